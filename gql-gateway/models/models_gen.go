@@ -2,6 +2,28 @@
 
 package models
 
+type Address struct {
+	FullName   string  `json:"fullName"`
+	Line1      string  `json:"line1"`
+	Line2      *string `json:"line2,omitempty"`
+	City       string  `json:"city"`
+	Region     *string `json:"region,omitempty"`
+	PostalCode *string `json:"postalCode,omitempty"`
+	Country    string  `json:"country"`
+	Phone      *string `json:"phone,omitempty"`
+}
+
+type AddressInput struct {
+	FullName   string  `json:"fullName"`
+	Line1      string  `json:"line1"`
+	Line2      *string `json:"line2,omitempty"`
+	City       string  `json:"city"`
+	Region     *string `json:"region,omitempty"`
+	PostalCode *string `json:"postalCode,omitempty"`
+	Country    string  `json:"country"`
+	Phone      *string `json:"phone,omitempty"`
+}
+
 type AuthToken struct {
 	AccessToken string  `json:"accessToken"`
 	IDToken     *string `json:"idToken,omitempty"`
@@ -21,50 +43,122 @@ type CategoryInput struct {
 }
 
 type Customer struct {
-	ID        string `json:"id"`
-	AuthID    string `json:"authID"`
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-	Email     string `json:"email"`
-	Phone     string `json:"phone"`
-	CreatedAt string `json:"createdAt"`
+	ID        string      `json:"id"`
+	AuthID    string      `json:"authID"`
+	FirstName string      `json:"firstName"`
+	LastName  string      `json:"lastName"`
+	Email     string      `json:"email"`
+	Phone     string      `json:"phone"`
+	CreatedAt string      `json:"createdAt"`
+	Orders    *OrderPage  `json:"orders"`
+	Wishlists []*Wishlist `json:"wishlists"`
+}
+
+type CustomerAddress struct {
+	ID                string   `json:"id"`
+	Label             *string  `json:"label,omitempty"`
+	Address           *Address `json:"address"`
+	IsDefaultShipping bool     `json:"isDefaultShipping"`
+	IsDefaultBilling  bool     `json:"isDefaultBilling"`
+	CreatedAt         string   `json:"createdAt"`
+}
+
+type CustomerAddressInput struct {
+	Label             *string       `json:"label,omitempty"`
+	Address           *AddressInput `json:"address"`
+	IsDefaultShipping *bool         `json:"isDefaultShipping,omitempty"`
+	IsDefaultBilling  *bool         `json:"isDefaultBilling,omitempty"`
 }
 
 type Mutation struct {
 }
 
+type OptionType struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
 type Order struct {
-	ID        string       `json:"id"`
-	Customer  *Customer    `json:"customer"`
-	OrderDate string       `json:"orderDate"`
-	Status    string       `json:"status"`
-	Items     []*OrderItem `json:"items"`
+	ID              string               `json:"id"`
+	OrderNumber     string               `json:"orderNumber"`
+	Customer        *Customer            `json:"customer"`
+	OrderDate       string               `json:"orderDate"`
+	Status          string               `json:"status"`
+	Items           []*OrderItem         `json:"items"`
+	Subtotal        float64              `json:"subtotal"`
+	DiscountTotal   float64              `json:"discountTotal"`
+	Total           float64              `json:"total"`
+	Discounts       []*OrderDiscount     `json:"discounts"`
+	ShippingAddress *Address             `json:"shippingAddress,omitempty"`
+	ShippingMethod  *string              `json:"shippingMethod,omitempty"`
+	ShippingCost    float64              `json:"shippingCost"`
+	Shipments       []*Shipment          `json:"shipments"`
+	Returns         []*Return            `json:"returns"`
+	Refunds         []*Refund            `json:"refunds"`
+	History         []*OrderStatusChange `json:"history"`
+	InvoiceURL      *string              `json:"invoiceUrl,omitempty"`
+}
+
+type OrderDiscount struct {
+	ID          string  `json:"id"`
+	Code        *string `json:"code,omitempty"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
 }
 
 type OrderInput struct {
-	CustomerID string            `json:"customerID"`
-	Items      []*OrderItemInput `json:"items"`
+	CustomerID       string            `json:"customerID"`
+	Items            []*OrderItemInput `json:"items"`
+	CouponCode       *string           `json:"couponCode,omitempty"`
+	AddressID        *string           `json:"addressID,omitempty"`
+	ShippingAddress  *AddressInput     `json:"shippingAddress,omitempty"`
+	ShippingMethodID *string           `json:"shippingMethodID,omitempty"`
 }
 
 type OrderItem struct {
-	ID       string   `json:"id"`
-	Product  *Product `json:"product"`
-	Quantity int      `json:"quantity"`
-	Price    float64  `json:"price"`
+	ID       string          `json:"id"`
+	Product  *Product        `json:"product"`
+	Variant  *ProductVariant `json:"variant,omitempty"`
+	Quantity int             `json:"quantity"`
+	Price    float64         `json:"price"`
 }
 
 type OrderItemInput struct {
-	ProductID string  `json:"productID"`
-	Quantity  int     `json:"quantity"`
-	Price     float64 `json:"price"`
+	ProductID string   `json:"productID"`
+	VariantID *string  `json:"variantID,omitempty"`
+	Quantity  int      `json:"quantity"`
+	Price     *float64 `json:"price,omitempty"`
+}
+
+type OrderPage struct {
+	Items       []*Order `json:"items"`
+	TotalCount  int      `json:"totalCount"`
+	HasNextPage bool     `json:"hasNextPage"`
+	EndCursor   *string  `json:"endCursor,omitempty"`
+}
+
+type OrderStatusChange struct {
+	Status    string  `json:"status"`
+	Note      *string `json:"note,omitempty"`
+	CreatedAt string  `json:"createdAt"`
 }
 
 type Product struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Description *string   `json:"description,omitempty"`
-	Price       float64   `json:"price"`
-	Category    *Category `json:"category,omitempty"`
+	ID            string            `json:"id"`
+	Sku           *string           `json:"sku,omitempty"`
+	Name          string            `json:"name"`
+	Description   *string           `json:"description,omitempty"`
+	Price         float64           `json:"price"`
+	Category      *Category         `json:"category,omitempty"`
+	WeightKg      *float64          `json:"weightKg,omitempty"`
+	LengthCm      *float64          `json:"lengthCm,omitempty"`
+	WidthCm       *float64          `json:"widthCm,omitempty"`
+	HeightCm      *float64          `json:"heightCm,omitempty"`
+	Variants      []*ProductVariant `json:"variants"`
+	Images        []*ProductImage   `json:"images"`
+	AverageRating *float64          `json:"averageRating,omitempty"`
+	RatingCount   int               `json:"ratingCount"`
+	Reviews       *ReviewPage       `json:"reviews"`
 }
 
 type ProductCatalog struct {
@@ -72,11 +166,28 @@ type ProductCatalog struct {
 	SubCategories   []*ProductSubCategory `json:"subCategories"`
 }
 
+type ProductImage struct {
+	ID           string  `json:"id"`
+	URL          string  `json:"url"`
+	MediumURL    string  `json:"mediumUrl"`
+	ThumbnailURL string  `json:"thumbnailUrl"`
+	AltText      *string `json:"altText,omitempty"`
+	Width        int     `json:"width"`
+	Height       int     `json:"height"`
+	Position     int     `json:"position"`
+	IsPrimary    bool    `json:"isPrimary"`
+}
+
 type ProductInput struct {
-	Name        string  `json:"name"`
-	Description *string `json:"description,omitempty"`
-	Price       float64 `json:"price"`
-	CategoryID  *string `json:"categoryID,omitempty"`
+	Sku         *string  `json:"sku,omitempty"`
+	Name        string   `json:"name"`
+	Description *string  `json:"description,omitempty"`
+	Price       float64  `json:"price"`
+	CategoryID  *string  `json:"categoryID,omitempty"`
+	WeightKg    *float64 `json:"weightKg,omitempty"`
+	LengthCm    *float64 `json:"lengthCm,omitempty"`
+	WidthCm     *float64 `json:"widthCm,omitempty"`
+	HeightCm    *float64 `json:"heightCm,omitempty"`
 }
 
 type ProductSubCategory struct {
@@ -84,9 +195,68 @@ type ProductSubCategory struct {
 	Products []*Product `json:"products"`
 }
 
+type ProductVariant struct {
+	ID            string           `json:"id"`
+	Sku           string           `json:"sku"`
+	Price         float64          `json:"price"`
+	PriceOverride *float64         `json:"priceOverride,omitempty"`
+	Stock         int              `json:"stock"`
+	Options       []*VariantOption `json:"options"`
+}
+
+type ProductVariantInput struct {
+	ProductID string                `json:"productID"`
+	Sku       string                `json:"sku"`
+	Price     *float64              `json:"price,omitempty"`
+	Stock     int                   `json:"stock"`
+	Options   []*VariantOptionInput `json:"options"`
+}
+
+type Promotion struct {
+	ID                    string    `json:"id"`
+	Name                  string    `json:"name"`
+	Code                  *string   `json:"code,omitempty"`
+	Kind                  string    `json:"kind"`
+	Value                 float64   `json:"value"`
+	Category              *Category `json:"category,omitempty"`
+	BuyQuantity           *int      `json:"buyQuantity,omitempty"`
+	GetQuantity           *int      `json:"getQuantity,omitempty"`
+	MinSubtotal           *float64  `json:"minSubtotal,omitempty"`
+	UsageLimit            *int      `json:"usageLimit,omitempty"`
+	UsageLimitPerCustomer *int      `json:"usageLimitPerCustomer,omitempty"`
+	StartsAt              *string   `json:"startsAt,omitempty"`
+	EndsAt                *string   `json:"endsAt,omitempty"`
+	Active                bool      `json:"active"`
+	CreatedAt             string    `json:"createdAt"`
+}
+
+type PromotionInput struct {
+	Name                  string   `json:"name"`
+	Code                  *string  `json:"code,omitempty"`
+	Kind                  string   `json:"kind"`
+	Value                 float64  `json:"value"`
+	CategoryID            *string  `json:"categoryID,omitempty"`
+	BuyQuantity           *int     `json:"buyQuantity,omitempty"`
+	GetQuantity           *int     `json:"getQuantity,omitempty"`
+	MinSubtotal           *float64 `json:"minSubtotal,omitempty"`
+	UsageLimit            *int     `json:"usageLimit,omitempty"`
+	UsageLimitPerCustomer *int     `json:"usageLimitPerCustomer,omitempty"`
+	StartsAt              *string  `json:"startsAt,omitempty"`
+	EndsAt                *string  `json:"endsAt,omitempty"`
+}
+
 type Query struct {
 }
 
+type Refund struct {
+	ID          string  `json:"id"`
+	Amount      float64 `json:"amount"`
+	Reason      *string `json:"reason,omitempty"`
+	Status      string  `json:"status"`
+	CreatedAt   string  `json:"createdAt"`
+	ProcessedAt *string `json:"processedAt,omitempty"`
+}
+
 type RegisterInput struct {
 	FirstName string `json:"firstName"`
 	LastName  string `json:"lastName"`
@@ -94,3 +264,208 @@ type RegisterInput struct {
 	Phone     string `json:"phone"`
 	Password  string `json:"password"`
 }
+
+type Return struct {
+	ID        string                `json:"id"`
+	OrderID   string                `json:"orderID"`
+	Reason    string                `json:"reason"`
+	Status    string                `json:"status"`
+	Items     []*ReturnItem         `json:"items"`
+	History   []*ReturnStatusChange `json:"history"`
+	Refund    *Refund               `json:"refund,omitempty"`
+	CreatedAt string                `json:"createdAt"`
+}
+
+type ReturnInput struct {
+	OrderID string             `json:"orderID"`
+	Items   []*ReturnItemInput `json:"items"`
+	Reason  string             `json:"reason"`
+}
+
+type ReturnItem struct {
+	OrderItem *OrderItem `json:"orderItem"`
+	Quantity  int        `json:"quantity"`
+}
+
+type ReturnItemInput struct {
+	OrderItemID string `json:"orderItemID"`
+	Quantity    int    `json:"quantity"`
+}
+
+type ReturnStatusChange struct {
+	Status    string  `json:"status"`
+	Note      *string `json:"note,omitempty"`
+	CreatedAt string  `json:"createdAt"`
+}
+
+type Review struct {
+	ID           string `json:"id"`
+	ProductID    string `json:"productID"`
+	CustomerName string `json:"customerName"`
+	Rating       int    `json:"rating"`
+	Body         string `json:"body"`
+	Status       string `json:"status"`
+	CreatedAt    string `json:"createdAt"`
+}
+
+type ReviewInput struct {
+	ProductID string `json:"productID"`
+	Rating    int    `json:"rating"`
+	Body      string `json:"body"`
+}
+
+type ReviewPage struct {
+	Items       []*Review `json:"items"`
+	TotalCount  int       `json:"totalCount"`
+	HasNextPage bool      `json:"hasNextPage"`
+	EndCursor   *string   `json:"endCursor,omitempty"`
+}
+
+type Shipment struct {
+	ID             string          `json:"id"`
+	Carrier        string          `json:"carrier"`
+	TrackingNumber *string         `json:"trackingNumber,omitempty"`
+	ShippedAt      string          `json:"shippedAt"`
+	DeliveredAt    *string         `json:"deliveredAt,omitempty"`
+	Items          []*ShipmentItem `json:"items"`
+}
+
+type ShipmentInput struct {
+	OrderID        string               `json:"orderID"`
+	Carrier        string               `json:"carrier"`
+	TrackingNumber *string              `json:"trackingNumber,omitempty"`
+	Items          []*ShipmentItemInput `json:"items"`
+}
+
+type ShipmentItem struct {
+	OrderItem *OrderItem `json:"orderItem"`
+	Quantity  int        `json:"quantity"`
+}
+
+type ShipmentItemInput struct {
+	OrderItemID string `json:"orderItemID"`
+	Quantity    int    `json:"quantity"`
+}
+
+type ShippingMethod struct {
+	ID          string  `json:"id"`
+	Code        string  `json:"code"`
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	Active      bool    `json:"active"`
+}
+
+type ShippingMethodInput struct {
+	Code        string  `json:"code"`
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	Active      *bool   `json:"active,omitempty"`
+}
+
+type ShippingQuote struct {
+	Method   *ShippingMethod `json:"method"`
+	Zone     string          `json:"zone"`
+	WeightKg float64         `json:"weightKg"`
+	Cost     float64         `json:"cost"`
+}
+
+type ShippingQuoteInput struct {
+	OrderID         *string           `json:"orderID,omitempty"`
+	Items           []*OrderItemInput `json:"items,omitempty"`
+	AddressID       *string           `json:"addressID,omitempty"`
+	ShippingAddress *AddressInput     `json:"shippingAddress,omitempty"`
+}
+
+type ShippingRate struct {
+	ID          string          `json:"id"`
+	Method      *ShippingMethod `json:"method"`
+	Zone        *ShippingZone   `json:"zone"`
+	MinWeightKg float64         `json:"minWeightKg"`
+	MaxWeightKg *float64        `json:"maxWeightKg,omitempty"`
+	Price       float64         `json:"price"`
+}
+
+type ShippingRateInput struct {
+	MethodID    string   `json:"methodID"`
+	ZoneID      string   `json:"zoneID"`
+	MinWeightKg *float64 `json:"minWeightKg,omitempty"`
+	MaxWeightKg *float64 `json:"maxWeightKg,omitempty"`
+	Price       float64  `json:"price"`
+}
+
+type ShippingZone struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Countries []string `json:"countries"`
+	Regions   []string `json:"regions"`
+}
+
+type ShippingZoneInput struct {
+	Name      string   `json:"name"`
+	Countries []string `json:"countries"`
+	Regions   []string `json:"regions,omitempty"`
+}
+
+type Subscription struct {
+}
+
+type VariantOption struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type VariantOptionInput struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type WebhookDelivery struct {
+	ID             string  `json:"id"`
+	SubscriptionID string  `json:"subscriptionID"`
+	EventType      string  `json:"eventType"`
+	Payload        string  `json:"payload"`
+	Status         string  `json:"status"`
+	Attempts       int     `json:"attempts"`
+	LastStatusCode *int    `json:"lastStatusCode,omitempty"`
+	LastError      *string `json:"lastError,omitempty"`
+	NextAttemptAt  *string `json:"nextAttemptAt,omitempty"`
+	CreatedAt      string  `json:"createdAt"`
+	DeliveredAt    *string `json:"deliveredAt,omitempty"`
+}
+
+type WebhookSubscription struct {
+	ID         string   `json:"id"`
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"eventTypes"`
+	Active     bool     `json:"active"`
+	CreatedAt  string   `json:"createdAt"`
+}
+
+type WebhookSubscriptionInput struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"eventTypes"`
+	Secret     *string  `json:"secret,omitempty"`
+}
+
+type Wishlist struct {
+	ID         string          `json:"id"`
+	Name       string          `json:"name"`
+	ShareToken *string         `json:"shareToken,omitempty"`
+	Items      []*WishlistItem `json:"items"`
+	CreatedAt  string          `json:"createdAt"`
+}
+
+type WishlistItem struct {
+	Product *Product        `json:"product"`
+	Variant *ProductVariant `json:"variant,omitempty"`
+	Notify  bool            `json:"notify"`
+	AddedAt string          `json:"addedAt"`
+}
+
+type WishlistItemInput struct {
+	WishlistID *string `json:"wishlistID,omitempty"`
+	ProductID  string  `json:"productID"`
+	VariantID  *string `json:"variantID,omitempty"`
+	Notify     *bool   `json:"notify,omitempty"`
+}
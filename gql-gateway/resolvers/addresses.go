@@ -0,0 +1,123 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/models"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	rootModels "github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+// addressFromInput trims and validates a postal address. Country must be a
+// two-letter ISO 3166 code and is stored upper case.
+func addressFromInput(input *models.AddressInput) (*rootModels.Address, error) {
+	if input == nil {
+		return nil, repo.Validation("", "address is required")
+	}
+
+	a := &rootModels.Address{
+		FullName:   strings.TrimSpace(input.FullName),
+		Line1:      strings.TrimSpace(input.Line1),
+		Line2:      trimOptional(input.Line2),
+		City:       strings.TrimSpace(input.City),
+		Region:     trimOptional(input.Region),
+		PostalCode: trimOptional(input.PostalCode),
+		Country:    strings.ToUpper(strings.TrimSpace(input.Country)),
+		Phone:      trimOptional(input.Phone),
+	}
+
+	switch {
+	case a.FullName == "":
+		return nil, repo.Validation("", "address full name is required")
+	case a.Line1 == "":
+		return nil, repo.Validation("", "address line 1 is required")
+	case a.City == "":
+		return nil, repo.Validation("", "address city is required")
+	case len(a.Country) != 2:
+		return nil, repo.Validation("", "address country must be a two-letter ISO 3166 code, got %q", input.Country)
+	}
+	return a, nil
+}
+
+// customerAddressFromInput builds an address book entry for the customer
+func customerAddressFromInput(customerID int, input models.CustomerAddressInput) (*rootModels.CustomerAddress, error) {
+	address, err := addressFromInput(input.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rootModels.CustomerAddress{
+		CustomerID:        customerID,
+		Label:             trimOptional(input.Label),
+		Address:           *address,
+		IsDefaultShipping: input.IsDefaultShipping != nil && *input.IsDefaultShipping,
+		IsDefaultBilling:  input.IsDefaultBilling != nil && *input.IsDefaultBilling,
+	}, nil
+}
+
+// orderShippingAddress picks the address an order ships to: one of the
+// customer's saved addresses or an address given inline, but not both
+func (r *Resolver) orderShippingAddress(ctx context.Context, customerID int, input models.OrderInput) (*rootModels.Address, error) {
+	switch {
+	case input.AddressID != nil && input.ShippingAddress != nil:
+		return nil, repo.Validation("input", "give either addressID or shippingAddress, not both")
+	case input.ShippingAddress != nil:
+		return addressFromInput(input.ShippingAddress)
+	case input.AddressID != nil:
+		id, err := strconv.Atoi(*input.AddressID)
+		if err != nil {
+			return nil, invalidID("address", *input.AddressID)
+		}
+		saved, err := r.AddressRepo.GetAddress(ctx, customerID, id)
+		if errors.Is(err, repo.ErrNotFound) {
+			return nil, repo.NotFound("address %d not found for this customer", id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &saved.Address, nil
+	}
+	return nil, repo.Validation("input", "a shipping address is required, give addressID or shippingAddress")
+}
+
+func trimOptional(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*s)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+func toGQLAddress(a *rootModels.Address) *models.Address {
+	if a == nil {
+		return nil
+	}
+	return &models.Address{
+		FullName:   a.FullName,
+		Line1:      a.Line1,
+		Line2:      a.Line2,
+		City:       a.City,
+		Region:     a.Region,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+		Phone:      a.Phone,
+	}
+}
+
+func toGQLCustomerAddress(a rootModels.CustomerAddress) *models.CustomerAddress {
+	return &models.CustomerAddress{
+		ID:                strconv.Itoa(a.ID),
+		Label:             a.Label,
+		Address:           toGQLAddress(&a.Address),
+		IsDefaultShipping: a.IsDefaultShipping,
+		IsDefaultBilling:  a.IsDefaultBilling,
+		CreatedAt:         a.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,39 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/pkg"
+)
+
+// currentCustomerID resolves the customer behind the request's access token
+func (r *Resolver) currentCustomerID(ctx context.Context) (int, error) {
+	user, ok := pkg.UserFromContext(ctx)
+	if !ok {
+		return 0, repo.Unauthorized("unauthorized: missing or invalid token")
+	}
+
+	customerID, err := r.CustomerRepo.FindCustomerIDByAuth0Sub(ctx, user.Sub)
+	if err != nil {
+		return 0, fmt.Errorf("could not resolve customer from token: %w", err)
+	}
+	return customerID, nil
+}
+
+// authorizeCustomer lets staff through and otherwise checks that the caller is the given customer
+func (r *Resolver) authorizeCustomer(ctx context.Context, customerID int) error {
+	if user, ok := pkg.UserFromContext(ctx); ok && user.IsStaff() {
+		return nil
+	}
+
+	callerID, err := r.currentCustomerID(ctx)
+	if err != nil {
+		return err
+	}
+	if callerID != customerID {
+		return repo.Unauthorized("forbidden: resource belongs to another customer")
+	}
+	return nil
+}
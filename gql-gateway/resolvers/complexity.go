@@ -0,0 +1,76 @@
+package resolvers
+
+import "github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/graph"
+
+// listCost is how many items an unpaginated list field is assumed to return
+// when working out how expensive a query is. Selections inside a list count
+// once per item, so nesting lists multiplies their cost.
+const listCost = 10
+
+// Complexity costs list fields by how many items they can return, for the
+// ComplexityLimit extension. Other fields cost 1 plus their selections.
+func Complexity() graph.ComplexityRoot {
+	var c graph.ComplexityRoot
+
+	c.Query.GetAllProducts = listComplexity
+	c.Query.GetAllCategories = listComplexity
+	c.Query.GetAllCustomers = listComplexity
+	c.Query.GetAllOrders = listComplexity
+	c.Query.ProductCatalog = listComplexity
+	c.Query.GetAllPromotions = listComplexity
+	c.Query.GetAllOptionTypes = listComplexity
+	c.Query.GetMyAddresses = listComplexity
+	c.Query.GetAllShippingMethods = listComplexity
+	c.Query.GetShippingZones = listComplexity
+	c.Query.GetShippingRates = listComplexity
+	c.Query.GetWebhookSubscriptions = listComplexity
+	c.Query.GetReturns = func(childComplexity int, _ *string) int {
+		return listComplexity(childComplexity)
+	}
+	c.Query.GetReviews = func(childComplexity int, _ *string) int {
+		return listComplexity(childComplexity)
+	}
+	c.Query.GetWebhookDeliveries = func(childComplexity int, _, _ *string, first *int) int {
+		return pageComplexity(childComplexity, first)
+	}
+
+	c.Category.Children = listComplexity
+	c.Product.Variants = listComplexity
+	c.Product.Images = listComplexity
+	c.Product.Reviews = func(childComplexity int, first *int, _ *string) int {
+		return pageComplexity(childComplexity, first)
+	}
+	c.ProductVariant.Options = listComplexity
+	c.ProductCatalog.SubCategories = listComplexity
+	c.ProductSubCategory.Products = listComplexity
+	c.Customer.Orders = func(childComplexity int, _, _, _ *string, first *int, _ *string) int {
+		return pageComplexity(childComplexity, first)
+	}
+	c.Customer.Wishlists = listComplexity
+	c.Wishlist.Items = listComplexity
+	c.Order.Items = listComplexity
+	c.Order.Discounts = listComplexity
+	c.Order.Shipments = listComplexity
+	c.Order.Returns = listComplexity
+	c.Order.Refunds = listComplexity
+	c.Order.History = listComplexity
+	c.Shipment.Items = listComplexity
+	c.Return.Items = listComplexity
+	c.Return.History = listComplexity
+
+	return c
+}
+
+func listComplexity(childComplexity int) int {
+	return listCost * max(childComplexity, 1)
+}
+
+// pageComplexity costs a paginated field by the page size it asks for
+func pageComplexity(childComplexity int, first *int) int {
+	size, err := pageSize(first)
+	if err != nil {
+		// the resolver rejects it before anything is loaded
+		return 1
+	}
+	return max(size, 1) * max(childComplexity, 1)
+}
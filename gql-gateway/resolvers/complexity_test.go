@@ -0,0 +1,35 @@
+package resolvers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/99designs/gqlgen/complexity"
+	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/graph"
+	"github.com/vektah/gqlparser/v2"
+)
+
+func TestComplexity(t *testing.T) {
+	es := graph.NewExecutableSchema(graph.Config{Resolvers: &Resolver{}, Complexity: Complexity()})
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{`{ getProduct(id: "1") { name price } }`, 3},
+		{`{ getAllProducts { name } }`, 10},
+		{`{ getAllProducts { variants { sku } } }`, 100},
+		{`{ getAllCategories { children { children { name } } } }`, 1000},
+		{`{ getProduct(id: "1") { reviews(first: 50) { items { rating } } } }`, 101},
+		{`{ getProduct(id: "1") { reviews(first: 500) { items { rating } } } }`, 201},
+	}
+	for _, tt := range tests {
+		doc, errs := gqlparser.LoadQuery(es.Schema(), tt.query)
+		if errs != nil {
+			t.Fatalf("parse %s: %v", tt.query, errs)
+		}
+		if got := complexity.Calculate(context.Background(), es, doc.Operations[0], nil); got != tt.want {
+			t.Errorf("complexity of %s = %d, want %d", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package resolvers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/models"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	rootModels "github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func toGQLCustomer(c rootModels.Customer) *models.Customer {
+	return &models.Customer{
+		ID:        strconv.Itoa(c.ID),
+		AuthID:    c.AuthID,
+		FirstName: c.FirstName,
+		LastName:  c.LastName,
+		Email:     c.Email,
+		Phone:     c.Phone,
+		CreatedAt: c.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// orderFilter turns the arguments of Customer.orders into a repo filter. A
+// date in `to` includes that whole day, so it becomes the start of the next.
+func orderFilter(customerID int, status, from, to *string, first *int, after *string) (rootModels.OrderFilter, error) {
+	f := rootModels.OrderFilter{CustomerID: customerID, Status: status}
+
+	var err error
+	if f.Limit, err = pageSize(first); err != nil {
+		return f, err
+	}
+	if from != nil {
+		if f.From, _, err = parseTimeBound(*from); err != nil {
+			return f, repo.Validation("from", "invalid from: %v", err)
+		}
+	}
+	if to != nil {
+		var isDate bool
+		if f.To, isDate, err = parseTimeBound(*to); err != nil {
+			return f, repo.Validation("to", "invalid to: %v", err)
+		}
+		if isDate {
+			next := f.To.AddDate(0, 0, 1)
+			f.To = &next
+		}
+	}
+
+	if after != nil {
+		id, err := decodeCursor("order", *after)
+		if err != nil {
+			return f, err
+		}
+		f.AfterID = &id
+	}
+	return f, nil
+}
+
+// parseTimeBound accepts a date (2006-01-02) or an RFC 3339 time and reports
+// which one it got
+func parseTimeBound(s string) (*time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return &t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, false, fmt.Errorf("expected YYYY-MM-DD or an RFC 3339 time, got %q", s)
+	}
+	// order dates are stored without a zone, in UTC
+	t = t.UTC()
+	return &t, false, nil
+}
@@ -0,0 +1,108 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/validate"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// CodeInternal is sent in extensions.code, besides the repo.ErrorKind ones,
+// for failures that aren't the caller's fault. Their details are logged
+// rather than sent.
+const CodeInternal = "INTERNAL"
+
+// ErrorPresenter turns resolver errors into GraphQL errors with a stable
+// extensions.code. repo.Errors keep their message and, for validation errors,
+// add the input field at fault as extensions.field. validate.Errors list every
+// problem in extensions.violations. Database errors are never
+// shown as they are: constraint violations get a generic message and code.
+// Any other error is logged and reported as INTERNAL, so errors meant for
+// the caller must be a repo.Error or validate.Error.
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	var gqlErr *gqlerror.Error
+	if errors.As(err, &gqlErr) && gqlErr.Extensions["code"] != nil {
+		// gqlgen's own parse and validation errors already have a code
+		return gqlErr
+	}
+
+	presented := graphql.DefaultErrorPresenter(ctx, err)
+	code, message, field := classifyError(err)
+	if code == CodeInternal {
+		log.Printf("graphql: %s at %v: %v", code, presented.Path, err)
+	}
+
+	presented.Message = message
+	if presented.Extensions == nil {
+		presented.Extensions = map[string]any{}
+	}
+	presented.Extensions["code"] = code
+	if field != "" {
+		presented.Extensions["field"] = field
+	}
+	var invalid *validate.Error
+	if errors.As(err, &invalid) {
+		presented.Extensions["violations"] = invalid.Violations
+	}
+	return presented
+}
+
+// classifyError picks the code, client facing message and field of an error
+func classifyError(err error) (code, message, field string) {
+	var domainErr *repo.Error
+	if errors.As(err, &domainErr) {
+		return string(domainErr.Kind), domainErr.Message, domainErr.Field
+	}
+	var invalid *validate.Error
+	if errors.As(err, &invalid) {
+		if len(invalid.Violations) == 1 {
+			field = invalid.Violations[0].Field
+		}
+		return string(repo.KindValidation), invalid.Error(), field
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505": // unique_violation
+			return string(repo.KindConflict), "a record with these details already exists", ""
+		case "23503": // foreign_key_violation
+			return string(repo.KindNotFound), missingReference(pgErr), ""
+		case "23502", "23514", "22001", "22003", "22P02": // not null, check, too long, out of range, bad text
+			return string(repo.KindValidation), "the request breaks a data constraint", ""
+		}
+		return CodeInternal, "internal server error", ""
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return string(repo.KindNotFound), repo.ErrNotFound.Message, ""
+	}
+	return CodeInternal, "internal server error", ""
+}
+
+// foreignKeyDetail matches Postgres' detail for a missing reference, e.g.
+// `Key (product_id)=(999) is not present in table "products".`
+var foreignKeyDetail = regexp.MustCompile(`^Key \(([a-z_]+)\)=\(([^)]*)\) is not present`)
+
+// missingReference says which referenced record doesn't exist, without
+// naming tables
+func missingReference(pgErr *pgconn.PgError) string {
+	m := foreignKeyDetail.FindStringSubmatch(pgErr.Detail)
+	if m == nil {
+		return "the request refers to a record that doesn't exist"
+	}
+	return fmt.Sprintf("%s %s does not exist", strings.ReplaceAll(m[1], "_", " "), m[2])
+}
+
+// invalidID reports an ID argument that isn't a number
+func invalidID(kind, id string) error {
+	return repo.Validation("", "invalid %s ID %q", kind, id)
+}
@@ -0,0 +1,127 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/models"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/validate"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestErrorPresenterListsViolations(t *testing.T) {
+	ctx := graphql.WithResponseContext(context.Background(), graphql.DefaultErrorPresenter, nil)
+
+	err := validateProductInput(models.ProductInput{Name: " ", Price: -1})
+	got := ErrorPresenter(ctx, err)
+
+	if got.Extensions["code"] != "VALIDATION" || got.Message != "name is required; price must be at least 0" {
+		t.Errorf("unexpected error %q with code %v", got.Message, got.Extensions["code"])
+	}
+	violations, _ := got.Extensions["violations"].([]validate.Violation)
+	if len(violations) != 2 || violations[0].Field != "input.name" || violations[1].Field != "input.price" {
+		t.Errorf("expected both fields to be listed, got %+v", got.Extensions["violations"])
+	}
+	if _, ok := got.Extensions["field"]; ok {
+		t.Error("expected no single field for several violations")
+	}
+}
+
+func TestErrorPresenter(t *testing.T) {
+	ctx := graphql.WithResponseContext(context.Background(), graphql.DefaultErrorPresenter, nil)
+
+	cases := []struct {
+		name    string
+		err     error
+		code    string
+		message string
+		field   string
+	}{
+		{
+			name:    "domain error keeps its message",
+			err:     fmt.Errorf("failed to create order: %w", repo.OutOfStock("variant 3 is out of stock, 0 left")),
+			code:    "OUT_OF_STOCK",
+			message: "variant 3 is out of stock, 0 left",
+		},
+		{
+			name:    "validation error names the field",
+			err:     repo.Validation("input.rating", "rating must be between 1 and 5"),
+			code:    "VALIDATION",
+			message: "rating must be between 1 and 5",
+			field:   "input.rating",
+		},
+		{
+			name:    "unique violation is a conflict",
+			err:     fmt.Errorf("create category: %w", &pgconn.PgError{Code: "23505", Message: `duplicate key value violates unique constraint "categories_name_key"`}),
+			code:    "CONFLICT",
+			message: "a record with these details already exists",
+		},
+		{
+			name: "foreign key violation names the missing record",
+			err: fmt.Errorf("create product: %w", &pgconn.PgError{
+				Code:   "23503",
+				Detail: `Key (category_id)=(999) is not present in table "categories".`,
+			}),
+			code:    "NOT_FOUND",
+			message: "category id 999 does not exist",
+		},
+		{
+			name:    "missing row is not found",
+			err:     fmt.Errorf("get product: %w", pgx.ErrNoRows),
+			code:    "NOT_FOUND",
+			message: "not found",
+		},
+		{
+			name:    "other database errors are hidden",
+			err:     fmt.Errorf("list orders: %w", &pgconn.PgError{Code: "42P01", Message: `relation "orders" does not exist`}),
+			code:    CodeInternal,
+			message: "internal server error",
+		},
+		{
+			name:    "malformed IDs are validation errors",
+			err:     invalidID("order", "abc"),
+			code:    "VALIDATION",
+			message: `invalid order ID "abc"`,
+		},
+		{
+			name:    "broken connections are hidden",
+			err:     fmt.Errorf("list orders: %w", io.ErrUnexpectedEOF),
+			code:    CodeInternal,
+			message: "internal server error",
+		},
+		{
+			name:    "cancelled requests are hidden",
+			err:     fmt.Errorf("failed to create order: %w", context.Canceled),
+			code:    CodeInternal,
+			message: "internal server error",
+		},
+		{
+			name:    "other errors are hidden",
+			err:     errors.New("failed to store image: s3: access denied for bucket media-prod"),
+			code:    CodeInternal,
+			message: "internal server error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ErrorPresenter(ctx, tc.err)
+			if got.Message != tc.message {
+				t.Errorf("message = %q, want %q", got.Message, tc.message)
+			}
+			if got.Extensions["code"] != tc.code {
+				t.Errorf("code = %v, want %q", got.Extensions["code"], tc.code)
+			}
+			field, _ := got.Extensions["field"].(string)
+			if field != tc.field {
+				t.Errorf("field = %q, want %q", field, tc.field)
+			}
+		})
+	}
+}
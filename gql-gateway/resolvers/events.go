@@ -0,0 +1,81 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/models"
+	rootModels "github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+// topics orders are published on
+const newOrdersTopic = "orders:new"
+
+func orderTopic(orderID int) string {
+	return "order:" + strconv.Itoa(orderID)
+}
+
+// orderEvent only names the order, subscribers load its current state
+type orderEvent struct {
+	OrderID int `json:"order_id"`
+}
+
+// publishOrder tells subscribers of the topics that an order changed.
+// Failures are only logged, the change has already been saved.
+func (r *Resolver) publishOrder(ctx context.Context, orderID int, topics ...string) {
+	if r.Events == nil {
+		return
+	}
+	payload, err := json.Marshal(orderEvent{OrderID: orderID})
+	if err != nil {
+		return
+	}
+	for _, topic := range topics {
+		if err := r.Events.Publish(ctx, topic, payload); err != nil {
+			log.Printf("failed to publish order %d on %s: %v", orderID, topic, err)
+		}
+	}
+}
+
+// orderFeed streams the orders published on a topic until ctx is done,
+// skipping those keep turns down when it is given
+func (r *Resolver) orderFeed(ctx context.Context, topic string, keep func(rootModels.Order) bool) (<-chan *models.Order, error) {
+	if r.Events == nil {
+		return nil, errors.New("subscriptions are not available")
+	}
+
+	events := r.Events.Subscribe(ctx, topic)
+	orders := make(chan *models.Order, 1)
+	go func() {
+		defer close(orders)
+		for payload := range events {
+			var e orderEvent
+			if err := json.Unmarshal(payload, &e); err != nil {
+				continue
+			}
+			order, err := r.OrderRepo.GetOrder(ctx, e.OrderID)
+			if err != nil {
+				log.Printf("failed to load order %d for subscription: %v", e.OrderID, err)
+				continue
+			}
+			if keep != nil && !keep(*order) {
+				continue
+			}
+			gqlOrder, err := r.loadOrder(ctx, *order)
+			if err != nil {
+				log.Printf("failed to load order %d for subscription: %v", e.OrderID, err)
+				continue
+			}
+
+			select {
+			case orders <- gqlOrder:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return orders, nil
+}
@@ -0,0 +1,93 @@
+package resolvers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	rootModels "github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/godfreyowidi/simple-ecomm-demo/pkg"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// idempotent runs a mutation at most once per idempotency key. The key comes
+// from the mutation's argument or else the Idempotency-Key header; without one
+// the mutation just runs. A retry with the same key and payload gets the first
+// result back, and the same key with a different payload is rejected. Keys
+// belong to the signed-in caller. Only createOrder uses it: the API has no
+// separate checkout or payment mutations yet, and they should go through this
+// too when added.
+func idempotent[T any](ctx context.Context, r *Resolver, operation string, argKey *string, request any, run func() (T, error)) (T, error) {
+	var zero T
+
+	key, ok := idempotencyKey(ctx, argKey)
+	if !ok {
+		return run()
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return zero, repo.Validation("idempotencyKey", "idempotency key must be at most %d characters", maxIdempotencyKeyLength)
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return zero, fmt.Errorf("hash request: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+
+	// keys are scoped to the caller, anonymous clients would share them
+	user, ok := pkg.UserFromContext(ctx)
+	if !ok {
+		return zero, repo.Unauthorized("unauthorized: idempotency keys need a signed-in caller")
+	}
+	claim := rootModels.IdempotencyKey{
+		Owner:       user.Sub,
+		Operation:   operation,
+		Key:         key,
+		RequestHash: hex.EncodeToString(sum[:]),
+	}
+
+	stored, err := r.IdempotencyRepo.Claim(ctx, claim)
+	if err != nil {
+		return zero, err
+	}
+	if stored != nil {
+		var result T
+		if err := json.Unmarshal(stored, &result); err != nil {
+			return zero, fmt.Errorf("replay %s: %w", operation, err)
+		}
+		return result, nil
+	}
+
+	result, err := run()
+	if err != nil {
+		// let the client retry with the same key, even if it hung up meanwhile
+		if releaseErr := r.IdempotencyRepo.Release(context.WithoutCancel(ctx), claim); releaseErr != nil {
+			log.Printf("failed to release idempotency key for %s: %v", operation, releaseErr)
+		}
+		return zero, err
+	}
+
+	response, err := json.Marshal(result)
+	if err == nil {
+		err = r.IdempotencyRepo.Complete(context.WithoutCancel(ctx), claim, response)
+	}
+	if err != nil {
+		// the mutation went through, so answer it, retries will see the key as in progress
+		log.Printf("failed to store idempotent response for %s: %v", operation, err)
+	}
+	return result, nil
+}
+
+// idempotencyKey picks the mutation's argument over the request header
+func idempotencyKey(ctx context.Context, argKey *string) (string, bool) {
+	if argKey != nil && strings.TrimSpace(*argKey) != "" {
+		return strings.TrimSpace(*argKey), true
+	}
+	return pkg.IdempotencyKeyFromContext(ctx)
+}
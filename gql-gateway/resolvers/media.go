@@ -0,0 +1,22 @@
+package resolvers
+
+import (
+	"strconv"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/models"
+	rootModels "github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func (r *Resolver) toGQLProductImage(m rootModels.ProductMedia) *models.ProductImage {
+	return &models.ProductImage{
+		ID:           strconv.Itoa(m.ID),
+		URL:          r.Storage.URL(m.OriginalKey),
+		MediumURL:    r.Storage.URL(m.MediumKey),
+		ThumbnailURL: r.Storage.URL(m.ThumbnailKey),
+		AltText:      m.AltText,
+		Width:        m.Width,
+		Height:       m.Height,
+		Position:     m.Position,
+		IsPrimary:    m.IsPrimary,
+	}
+}
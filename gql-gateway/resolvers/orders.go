@@ -0,0 +1,275 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/models"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/promotions"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	rootModels "github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/godfreyowidi/simple-ecomm-demo/pkg"
+)
+
+// loadOrder fetches the items, discount lines, shipments, returns, refunds and status history of an order and maps it to the GraphQL model
+func (r *Resolver) loadOrder(ctx context.Context, o rootModels.Order) (*models.Order, error) {
+	items, err := r.OrderItemRepo.GetItemsByOrder(ctx, o.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	discounts, err := r.OrderRepo.ListDiscountsByOrder(ctx, o.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var variantIDs []int
+	for _, item := range items {
+		if item.VariantID != nil {
+			variantIDs = append(variantIDs, *item.VariantID)
+		}
+	}
+	variants := map[int]rootModels.ProductVariant{}
+	if len(variantIDs) > 0 {
+		found, err := r.VariantRepo.GetVariantsByIDs(ctx, variantIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range found {
+			variants[v.ID] = v
+		}
+	}
+
+	shipments, err := r.ShipmentRepo.ListShipmentsByOrder(ctx, o.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	returns, err := r.ReturnRepo.ListReturnsByOrder(ctx, o.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	refunds, err := r.RefundRepo.ListRefundsByOrder(ctx, o.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := r.OrderRepo.ListStatusHistory(ctx, o.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	gqlOrder := toGQLOrder(o, items, variants, discounts)
+	for _, s := range shipments {
+		gqlOrder.Shipments = append(gqlOrder.Shipments, toGQLShipment(s, gqlOrder.Items))
+	}
+	for _, ret := range returns {
+		gqlOrder.Returns = append(gqlOrder.Returns, toGQLReturn(ret, gqlOrder.Items, refunds))
+	}
+	for _, refund := range refunds {
+		gqlOrder.Refunds = append(gqlOrder.Refunds, toGQLRefund(refund))
+	}
+	for _, change := range history {
+		gqlOrder.History = append(gqlOrder.History, &models.OrderStatusChange{
+			Status:    change.Status,
+			Note:      change.Note,
+			CreatedAt: change.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	return gqlOrder, nil
+}
+
+func toGQLOrder(o rootModels.Order, items []rootModels.OrderItem, variants map[int]rootModels.ProductVariant, discounts []rootModels.OrderDiscount) *models.Order {
+	gqlItems := []*models.OrderItem{}
+	for _, item := range items {
+		gqlItem := &models.OrderItem{
+			ID:       strconv.Itoa(item.ID),
+			Product:  &models.Product{ID: strconv.Itoa(item.ProductID)},
+			Quantity: item.Quantity,
+			Price:    item.Price,
+		}
+		if item.VariantID != nil {
+			if v, ok := variants[*item.VariantID]; ok {
+				gqlItem.Variant = toGQLVariant(v)
+			}
+		}
+		gqlItems = append(gqlItems, gqlItem)
+	}
+
+	gqlDiscounts := []*models.OrderDiscount{}
+	for _, d := range discounts {
+		gqlDiscounts = append(gqlDiscounts, &models.OrderDiscount{
+			ID:          strconv.Itoa(d.ID),
+			Code:        d.Code,
+			Description: d.Description,
+			Amount:      d.Amount,
+		})
+	}
+
+	// cancelled orders have no invoice, every other order can download one
+	var invoiceURL *string
+	if o.Status != rootModels.OrderStatusCancelled {
+		path := pkg.InvoicePath(o.ID)
+		invoiceURL = &path
+	}
+
+	return &models.Order{
+		ID:              strconv.Itoa(o.ID),
+		OrderNumber:     o.Number,
+		Customer:        &models.Customer{ID: strconv.Itoa(o.CustomerID)},
+		OrderDate:       o.OrderDate.Format(time.RFC3339),
+		Status:          o.Status,
+		Items:           gqlItems,
+		Subtotal:        o.Subtotal,
+		DiscountTotal:   o.DiscountTotal,
+		Total:           o.Total,
+		Discounts:       gqlDiscounts,
+		ShippingAddress: toGQLAddress(o.ShippingAddress),
+		ShippingMethod:  o.ShippingMethodName,
+		ShippingCost:    o.ShippingCost,
+		Shipments:       []*models.Shipment{},
+		Returns:         []*models.Return{},
+		Refunds:         []*models.Refund{},
+		History:         []*models.OrderStatusChange{},
+		InvoiceURL:      invoiceURL,
+	}
+}
+
+// createOrder places an order for the customer given in the input or else
+// the caller, and sends the confirmation SMS. Only staff may name another
+// customer, whose saved address and promotions would otherwise be usable.
+func (r *Resolver) createOrder(ctx context.Context, input models.OrderInput) (*models.Order, error) {
+	// Ensure at least one order item
+	if len(input.Items) == 0 {
+		return nil, repo.Validation("input.items", "at least one order item is required")
+	}
+
+	var customerID int
+	var err error
+
+	// Staff may order for any customer, everyone else only for themselves
+	if input.CustomerID != "" {
+		customerID, err = strconv.Atoi(input.CustomerID)
+		if err != nil {
+			return nil, invalidID("customer", input.CustomerID)
+		}
+		if err := r.authorizeCustomer(ctx, customerID); err != nil {
+			return nil, err
+		}
+	} else {
+		customerID, err = r.currentCustomerID(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	shippingAddress, err := r.orderShippingAddress(ctx, customerID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build order items for repository, priced from the catalogue rather
+	// than by the client
+	repoOrderItemsInput, err := orderItemsFromInput(input.Items)
+	if err != nil {
+		return nil, err
+	}
+	repoOrderItemsInput, err = r.OrderRepo.PriceItems(ctx, repoOrderItemsInput)
+	if err != nil {
+		return nil, err
+	}
+
+	shippingQuote, err := r.orderShipping(ctx, input.ShippingMethodID, *shippingAddress, repoOrderItemsInput)
+	if err != nil {
+		return nil, err
+	}
+
+	// Work out automatic and coupon discounts
+	lines, err := r.promotionLines(ctx, repoOrderItemsInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load order products: %w", err)
+	}
+	discounts, err := r.automaticDiscounts(ctx, customerID, lines)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate promotions: %w", err)
+	}
+	if input.CouponCode != nil && strings.TrimSpace(*input.CouponCode) != "" {
+		coupon, err := r.couponDiscount(ctx, *input.CouponCode, customerID, lines, promotions.Total(discounts))
+		if err != nil {
+			return nil, err
+		}
+		discounts = append(discounts, *coupon)
+	}
+
+	// Create order in repo
+	orderID, err := r.OrderRepo.PlaceOrder(ctx, rootModels.OrderDraft{
+		CustomerID:      customerID,
+		Items:           repoOrderItemsInput,
+		Discounts:       discounts,
+		ShippingAddress: shippingAddress,
+		Shipping:        shippingQuote,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create order: %w", err)
+	}
+
+	// Fetch complete order
+	order, err := r.OrderRepo.GetOrder(ctx, orderID.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve created order: %w", err)
+	}
+
+	// Fetch customer
+	customer, err := r.CustomerRepo.GetCustomerById(ctx, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve customer: %w", err)
+	}
+
+	// Try to send SMS
+	fullName := fmt.Sprintf("%s %s", customer.FirstName, customer.LastName)
+	if err := r.SMS.SendOrderConfirmationSMS(customer.Phone, fullName, order.Number); err != nil {
+		log.Printf("failed to send SMS: %v", err)
+	}
+
+	r.publishOrder(ctx, order.ID, newOrdersTopic)
+
+	// Build GraphQL response
+	gqlOrder, err := r.loadOrder(ctx, *order)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve created order: %w", err)
+	}
+	gqlOrder.Customer = &models.Customer{
+		ID:        strconv.Itoa(customer.ID),
+		FirstName: customer.FirstName,
+		LastName:  customer.LastName,
+		Email:     customer.Email,
+		Phone:     customer.Phone,
+		CreatedAt: customer.CreatedAt.Format(time.RFC3339),
+	}
+
+	return gqlOrder, nil
+}
+
+// notifyOrderCancelled texts the customer that their order was cancelled.
+// Failures are only logged, the cancellation has already been recorded.
+func (r *Resolver) notifyOrderCancelled(ctx context.Context, o rootModels.Order, refund *rootModels.Refund) {
+	customer, err := r.CustomerRepo.GetCustomerById(ctx, o.CustomerID)
+	if err != nil {
+		log.Printf("failed to load customer %d for cancellation SMS: %v", o.CustomerID, err)
+		return
+	}
+
+	var refundAmount *float64
+	if refund != nil {
+		refundAmount = &refund.Amount
+	}
+	fullName := fmt.Sprintf("%s %s", customer.FirstName, customer.LastName)
+	if err := r.SMS.SendOrderCancelledSMS(customer.Phone, fullName, o.Number, refundAmount); err != nil {
+		log.Printf("failed to send SMS: %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package resolvers
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+)
+
+// page sizes for paginated lists such as Customer.orders and Product.reviews
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageSize turns a `first` argument into a page size, capped at maxPageSize
+func pageSize(first *int) (int, error) {
+	if first == nil {
+		return defaultPageSize, nil
+	}
+	if *first < 0 {
+		return 0, repo.Validation("first", "first must not be negative")
+	}
+	return min(*first, maxPageSize), nil
+}
+
+// cursors are opaque to clients but only wrap the kind and ID of the last
+// item on a page, e.g. "order:42"
+func encodeCursor(kind string, id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(kind + ":" + strconv.Itoa(id)))
+}
+
+func decodeCursor(kind, cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		if id, ok := strings.CutPrefix(string(raw), kind+":"); ok {
+			if n, err := strconv.Atoi(id); err == nil {
+				return n, nil
+			}
+		}
+	}
+	return 0, repo.Validation("after", "invalid cursor %q", cursor)
+}
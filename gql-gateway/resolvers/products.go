@@ -0,0 +1,28 @@
+package resolvers
+
+import (
+	"strconv"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/models"
+	rootModels "github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func toGQLProduct(p rootModels.Product) *models.Product {
+	product := &models.Product{
+		ID:            strconv.Itoa(p.ID),
+		Sku:           p.SKU,
+		Name:          p.Name,
+		Description:   p.Description,
+		Price:         p.Price,
+		WeightKg:      p.WeightKg,
+		LengthCm:      p.LengthCm,
+		WidthCm:       p.WidthCm,
+		HeightCm:      p.HeightCm,
+		AverageRating: p.AverageRating(),
+		RatingCount:   p.RatingCount,
+	}
+	if p.CategoryID != nil {
+		product.Category = &models.Category{ID: strconv.Itoa(*p.CategoryID)}
+	}
+	return product
+}
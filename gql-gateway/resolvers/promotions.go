@@ -0,0 +1,264 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/models"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/promotions"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	rootModels "github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+// normalizeCouponCode makes coupon lookups case and whitespace insensitive
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// promotionLines loads the products behind the order items so category-wide
+// promotions can tell which lines they cover
+func (r *Resolver) promotionLines(ctx context.Context, items []rootModels.OrderItemInput) ([]promotions.Line, error) {
+	ids := make([]int, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ProductID)
+	}
+
+	products, err := r.ProductRepo.GetProductsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	categories := make(map[int]*int, len(products))
+	for _, p := range products {
+		categories[p.ID] = p.CategoryID
+	}
+
+	lines := make([]promotions.Line, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, promotions.Line{
+			ProductID:  item.ProductID,
+			CategoryID: categories[item.ProductID],
+			Quantity:   item.Quantity,
+			UnitPrice:  item.Price,
+		})
+	}
+	return lines, nil
+}
+
+// promotionCandidate resolves the category tree a promotion covers
+func (r *Resolver) promotionCandidate(ctx context.Context, p rootModels.Promotion) (promotions.Candidate, error) {
+	c := promotions.Candidate{Promotion: p}
+	if p.CategoryID == nil {
+		return c, nil
+	}
+
+	ids, err := r.CategoryRepo.ListDescendantIDs(ctx, *p.CategoryID)
+	if err != nil {
+		return c, err
+	}
+	c.Scope = promotions.Scope{}
+	for _, id := range ids {
+		c.Scope[id] = true
+	}
+	return c, nil
+}
+
+// withinUsageLimits reports whether the customer can redeem the promotion once more
+func (r *Resolver) withinUsageLimits(ctx context.Context, p rootModels.Promotion, customerID int) (bool, error) {
+	if p.UsageLimit == nil && p.UsageLimitPerCustomer == nil {
+		return true, nil
+	}
+
+	total, byCustomer, err := r.PromotionRepo.CountRedemptions(ctx, p.ID, customerID)
+	if err != nil {
+		return false, err
+	}
+	if p.UsageLimit != nil && total >= *p.UsageLimit {
+		return false, nil
+	}
+	if p.UsageLimitPerCustomer != nil && byCustomer >= *p.UsageLimitPerCustomer {
+		return false, nil
+	}
+	return true, nil
+}
+
+// automaticDiscounts works out the code-less promotions that apply to the lines
+func (r *Resolver) automaticDiscounts(ctx context.Context, customerID int, lines []promotions.Line) ([]rootModels.OrderDiscount, error) {
+	now := time.Now()
+
+	promos, err := r.PromotionRepo.ListAutomaticPromotions(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []promotions.Candidate
+	for _, p := range promos {
+		ok, err := r.withinUsageLimits(ctx, p, customerID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		c, err := r.promotionCandidate(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+
+	return promotions.Apply(candidates, lines, now), nil
+}
+
+// couponDiscount validates a coupon code against the lines and returns its discount line.
+// alreadyDiscounted is the amount other promotions take off, the coupon never pushes the
+// order below zero.
+func (r *Resolver) couponDiscount(ctx context.Context, code string, customerID int, lines []promotions.Line, alreadyDiscounted float64) (*rootModels.OrderDiscount, error) {
+	p, err := r.PromotionRepo.GetPromotionByCode(ctx, normalizeCouponCode(code))
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, repo.Validation("couponCode", "invalid coupon code %q", code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := r.promotionCandidate(ctx, *p)
+	if err != nil {
+		return nil, err
+	}
+	if err := promotions.Check(c, lines, time.Now()); err != nil {
+		return nil, repo.Validation("couponCode", "coupon %q cannot be applied: %v", code, err)
+	}
+
+	ok, err := r.withinUsageLimits(ctx, *p, customerID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, repo.Conflict("coupon %q has reached its usage limit", code)
+	}
+
+	amount := math.Min(promotions.Discount(c, lines), promotions.Subtotal(lines)-alreadyDiscounted)
+	if amount <= 0 {
+		return nil, repo.Validation("couponCode", "coupon %q cannot be applied: %v", code, promotions.ErrNotApplicable)
+	}
+
+	return &rootModels.OrderDiscount{
+		PromotionID: &p.ID,
+		Code:        p.Code,
+		Description: p.Name,
+		Amount:      math.Round(amount*100) / 100,
+	}, nil
+}
+
+// promotionFromInput validates a PromotionInput and converts it to the repo model
+func promotionFromInput(input models.PromotionInput) (*rootModels.Promotion, error) {
+	p := &rootModels.Promotion{
+		Name:                  strings.TrimSpace(input.Name),
+		Kind:                  input.Kind,
+		Value:                 input.Value,
+		BuyQuantity:           input.BuyQuantity,
+		GetQuantity:           input.GetQuantity,
+		MinSubtotal:           input.MinSubtotal,
+		UsageLimit:            input.UsageLimit,
+		UsageLimitPerCustomer: input.UsageLimitPerCustomer,
+		Active:                true,
+	}
+	if p.Name == "" {
+		return nil, repo.Validation("input.name", "promotion name is required")
+	}
+
+	switch p.Kind {
+	case rootModels.PromotionPercentage:
+		if p.Value <= 0 || p.Value > 100 {
+			return nil, repo.Validation("input.value", "percentage must be between 0 and 100")
+		}
+	case rootModels.PromotionFixedAmount:
+		if p.Value <= 0 {
+			return nil, repo.Validation("input.value", "fixed amount must be greater than 0")
+		}
+	case rootModels.PromotionBuyXGetY:
+		if p.BuyQuantity == nil || p.GetQuantity == nil || *p.BuyQuantity < 1 || *p.GetQuantity < 1 {
+			return nil, repo.Validation("input", "buy_x_get_y promotions need buyQuantity and getQuantity of at least 1")
+		}
+		if p.Value <= 0 || p.Value > 100 {
+			return nil, repo.Validation("input.value", "percentage off the free items must be between 0 and 100")
+		}
+	default:
+		return nil, repo.Validation("input.kind", "unknown promotion kind %q", p.Kind)
+	}
+
+	if input.Code != nil {
+		code := normalizeCouponCode(*input.Code)
+		if code == "" {
+			return nil, repo.Validation("input.code", "coupon code cannot be blank")
+		}
+		p.Code = &code
+	}
+
+	if input.CategoryID != nil {
+		id, err := strconv.Atoi(*input.CategoryID)
+		if err != nil {
+			return nil, invalidID("category", *input.CategoryID)
+		}
+		p.CategoryID = &id
+	}
+
+	var err error
+	if p.StartsAt, err = parseOptionalTime(input.StartsAt); err != nil {
+		return nil, err
+	}
+	if p.EndsAt, err = parseOptionalTime(input.EndsAt); err != nil {
+		return nil, err
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return nil, repo.Validation("input.endsAt", "endsAt must be after startsAt")
+	}
+
+	return p, nil
+}
+
+// parseOptionalTime parses an optional RFC3339 timestamp argument
+func parseOptionalTime(value *string) (*time.Time, error) {
+	if value == nil {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return nil, repo.Validation("", "invalid date %q, expected RFC3339", *value)
+	}
+	return &t, nil
+}
+
+func toGQLPromotion(p rootModels.Promotion) *models.Promotion {
+	gql := &models.Promotion{
+		ID:                    strconv.Itoa(p.ID),
+		Name:                  p.Name,
+		Code:                  p.Code,
+		Kind:                  p.Kind,
+		Value:                 p.Value,
+		BuyQuantity:           p.BuyQuantity,
+		GetQuantity:           p.GetQuantity,
+		MinSubtotal:           p.MinSubtotal,
+		UsageLimit:            p.UsageLimit,
+		UsageLimitPerCustomer: p.UsageLimitPerCustomer,
+		Active:                p.Active,
+		CreatedAt:             p.CreatedAt.Format(time.RFC3339),
+	}
+	if p.CategoryID != nil {
+		gql.Category = &models.Category{ID: strconv.Itoa(*p.CategoryID)}
+	}
+	if p.StartsAt != nil {
+		startsAt := p.StartsAt.Format(time.RFC3339)
+		gql.StartsAt = &startsAt
+	}
+	if p.EndsAt != nil {
+		endsAt := p.EndsAt.Format(time.RFC3339)
+		gql.EndsAt = &endsAt
+	}
+	return gql
+}
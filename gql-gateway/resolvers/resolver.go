@@ -1,8 +1,10 @@
 package resolvers
 
 import (
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/pubsub"
 	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
 	"github.com/godfreyowidi/simple-ecomm-demo/pkg"
+	"github.com/godfreyowidi/simple-ecomm-demo/pkg/storage"
 )
 
 type Resolver struct {
@@ -12,5 +14,30 @@ type Resolver struct {
 	OrderItemRepo   *repo.OrderItemRepo
 	CategoryRepo    *repo.CategoryRepo
 	RegisterHandler *pkg.RegisterHandler
+	Auth0           *pkg.Auth0Client
 	CatalogRepo     *repo.CatalogRepo
+	PromotionRepo   *repo.PromotionRepo
+	VariantRepo     *repo.VariantRepo
+	MediaRepo       *repo.MediaRepo
+	AddressRepo     *repo.AddressRepo
+	ShippingRepo    *repo.ShippingRepo
+	ShipmentRepo    *repo.ShipmentRepo
+	ReturnRepo      *repo.ReturnRepo
+	RefundRepo      *repo.RefundRepo
+	IdempotencyRepo *repo.IdempotencyRepo
+	ReviewRepo      *repo.ReviewRepo
+	WishlistRepo    *repo.WishlistRepo
+	WebhookRepo     *repo.WebhookRepo
+	Storage         storage.Storage
+
+	// SMS texts customers about their orders, nothing is sent when it is nil
+	SMS *pkg.SMSService
+
+	// Events carries order updates to GraphQL subscriptions, which are
+	// unavailable when it is nil
+	Events pubsub.Broker
+
+	// ReturnWindowDays is how many days after delivery items can be returned,
+	// repo.DefaultReturnWindowDays when zero
+	ReturnWindowDays int
 }
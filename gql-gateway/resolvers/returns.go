@@ -0,0 +1,125 @@
+package resolvers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/models"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	rootModels "github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func returnFromInput(input models.ReturnInput) (*rootModels.Return, error) {
+	orderID, err := strconv.Atoi(input.OrderID)
+	if err != nil {
+		return nil, invalidID("order", input.OrderID)
+	}
+
+	ret := &rootModels.Return{
+		OrderID: orderID,
+		Reason:  strings.TrimSpace(input.Reason),
+	}
+	if ret.Reason == "" {
+		return nil, repo.Validation("input.reason", "reason is required")
+	}
+
+	for _, item := range input.Items {
+		orderItemID, err := strconv.Atoi(item.OrderItemID)
+		if err != nil {
+			return nil, invalidID("order item", item.OrderItemID)
+		}
+		if item.Quantity <= 0 {
+			return nil, repo.Validation("input.items", "quantity must be positive for order item %d", orderItemID)
+		}
+		ret.Items = append(ret.Items, rootModels.ReturnItem{OrderItemID: orderItemID, Quantity: item.Quantity})
+	}
+	return ret, nil
+}
+
+// returnWindowDays is the configured return window or the default one
+func (r *Resolver) returnWindowDays() int {
+	if r.ReturnWindowDays > 0 {
+		return r.ReturnWindowDays
+	}
+	return repo.DefaultReturnWindowDays
+}
+
+// toGQLReturn maps a return, pointing its lines at the order's items and
+// attaching the refund issued for it, if any
+func toGQLReturn(ret rootModels.Return, orderItems []*models.OrderItem, refunds []rootModels.Refund) *models.Return {
+	byID := make(map[string]*models.OrderItem, len(orderItems))
+	for _, item := range orderItems {
+		byID[item.ID] = item
+	}
+
+	items := []*models.ReturnItem{}
+	for _, item := range ret.Items {
+		orderItem, ok := byID[strconv.Itoa(item.OrderItemID)]
+		if !ok {
+			orderItem = &models.OrderItem{ID: strconv.Itoa(item.OrderItemID)}
+		}
+		items = append(items, &models.ReturnItem{OrderItem: orderItem, Quantity: item.Quantity})
+	}
+
+	history := []*models.ReturnStatusChange{}
+	for _, change := range ret.History {
+		history = append(history, &models.ReturnStatusChange{
+			Status:    change.Status,
+			Note:      change.Note,
+			CreatedAt: change.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	gqlReturn := &models.Return{
+		ID:        strconv.Itoa(ret.ID),
+		OrderID:   strconv.Itoa(ret.OrderID),
+		Reason:    ret.Reason,
+		Status:    ret.Status,
+		Items:     items,
+		History:   history,
+		CreatedAt: ret.CreatedAt.Format(time.RFC3339),
+	}
+	for _, refund := range refunds {
+		if refund.ReturnID != nil && *refund.ReturnID == ret.ID {
+			gqlReturn.Refund = toGQLRefund(refund)
+		}
+	}
+	return gqlReturn
+}
+
+func toGQLRefund(refund rootModels.Refund) *models.Refund {
+	gqlRefund := &models.Refund{
+		ID:        strconv.Itoa(refund.ID),
+		Amount:    refund.Amount,
+		Reason:    refund.Reason,
+		Status:    refund.Status,
+		CreatedAt: refund.CreatedAt.Format(time.RFC3339),
+	}
+	if refund.ProcessedAt != nil {
+		processedAt := refund.ProcessedAt.Format(time.RFC3339)
+		gqlRefund.ProcessedAt = &processedAt
+	}
+	return gqlRefund
+}
+
+// returnFromOrder loads the order and picks the return out of it, so the
+// return's lines come with their order items
+func (r *Resolver) returnFromOrder(ctx context.Context, orderID, returnID int) (*models.Return, error) {
+	order, err := r.OrderRepo.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	gqlOrder, err := r.loadOrder(ctx, *order)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ret := range gqlOrder.Returns {
+		if ret.ID == strconv.Itoa(returnID) {
+			return ret, nil
+		}
+	}
+	return nil, repo.NotFound("return %d not found on order %d", returnID, orderID)
+}
@@ -0,0 +1,44 @@
+package resolvers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/models"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	rootModels "github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func reviewFromInput(input models.ReviewInput, customerID int) (*rootModels.Review, error) {
+	productID, err := strconv.Atoi(input.ProductID)
+	if err != nil {
+		return nil, invalidID("product", input.ProductID)
+	}
+	if input.Rating < 1 || input.Rating > 5 {
+		return nil, repo.Validation("input.rating", "rating must be between 1 and 5")
+	}
+
+	review := &rootModels.Review{
+		ProductID:  productID,
+		CustomerID: customerID,
+		Rating:     input.Rating,
+		Body:       strings.TrimSpace(input.Body),
+	}
+	if review.Body == "" {
+		return nil, repo.Validation("input.body", "review text is required")
+	}
+	return review, nil
+}
+
+func toGQLReview(r rootModels.Review) *models.Review {
+	return &models.Review{
+		ID:           strconv.Itoa(r.ID),
+		ProductID:    strconv.Itoa(r.ProductID),
+		CustomerName: r.CustomerName,
+		Rating:       r.Rating,
+		Body:         r.Body,
+		Status:       r.Status,
+		CreatedAt:    r.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -6,29 +6,103 @@ package resolvers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/graph"
 	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/models"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/ordernumber"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
 	rootModels "github.com/godfreyowidi/simple-ecomm-demo/models"
 	"github.com/godfreyowidi/simple-ecomm-demo/pkg"
 )
 
+// Orders is the resolver for the orders field.
+func (r *customerResolver) Orders(ctx context.Context, obj *models.Customer, status *string, from *string, to *string, first *int, after *string) (*models.OrderPage, error) {
+	customerID, err := strconv.Atoi(obj.ID)
+	if err != nil {
+		return nil, invalidID("customer", obj.ID)
+	}
+	if err := r.Resolver.authorizeCustomer(ctx, customerID); err != nil {
+		return nil, err
+	}
+
+	filter, err := orderFilter(customerID, status, from, to, first, after)
+	if err != nil {
+		return nil, err
+	}
+	pageSize := filter.Limit
+	// one extra row tells whether another page follows
+	filter.Limit++
+
+	orders, total, err := r.Resolver.OrderRepo.ListCustomerOrders(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.OrderPage{Items: []*models.Order{}, TotalCount: total}
+	if len(orders) > pageSize {
+		orders = orders[:pageSize]
+		page.HasNextPage = true
+	}
+	for _, o := range orders {
+		gqlOrder, err := r.Resolver.loadOrder(ctx, o)
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, gqlOrder)
+	}
+	if len(orders) > 0 {
+		cursor := encodeCursor("order", orders[len(orders)-1].ID)
+		page.EndCursor = &cursor
+	}
+
+	return page, nil
+}
+
+// Wishlists is the resolver for the wishlists field.
+func (r *customerResolver) Wishlists(ctx context.Context, obj *models.Customer) ([]*models.Wishlist, error) {
+	customerID, err := strconv.Atoi(obj.ID)
+	if err != nil {
+		return nil, invalidID("customer", obj.ID)
+	}
+	if err := r.Resolver.authorizeCustomer(ctx, customerID); err != nil {
+		return nil, err
+	}
+
+	wishlists, err := r.Resolver.WishlistRepo.ListWishlistsByCustomer(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	gqlWishlists := []*models.Wishlist{}
+	for _, w := range wishlists {
+		gqlWishlist, err := r.Resolver.loadWishlist(ctx, w)
+		if err != nil {
+			return nil, err
+		}
+		gqlWishlists = append(gqlWishlists, gqlWishlist)
+	}
+	return gqlWishlists, nil
+}
+
 // CustomerLogin is the resolver for the customerLogin field.
 func (r *mutationResolver) CustomerLogin(ctx context.Context, identifier string, password string) (*models.AuthToken, error) {
 	// Find customer by email or phone
 	customer, err := r.CustomerRepo.FindByEmailOrPhone(ctx, identifier)
 	if err != nil {
-		return nil, fmt.Errorf("invalid credentials")
+		return nil, repo.Unauthorized("invalid credentials")
 	}
 
 	// Login with email + password (required by Auth0) -> this is still buggy though
-	tokenResp, err := pkg.CustomerLogin(ctx, customer.Email, password)
+	tokenResp, err := r.Auth0.CustomerLogin(ctx, customer.Email, password)
 	if err != nil {
-		return nil, fmt.Errorf("login failed: %w", err)
+		log.Printf("login for customer %d failed: %v", customer.ID, err)
+		return nil, repo.Unauthorized("invalid credentials")
 	}
 
 	// Return GraphQL AuthToken
@@ -41,6 +115,10 @@ func (r *mutationResolver) CustomerLogin(ctx context.Context, identifier string,
 
 // CreateCustomer is the resolver for the createCustomer field.
 func (r *mutationResolver) CreateCustomer(ctx context.Context, input models.RegisterInput) (*models.Customer, error) {
+	if err := validateRegisterInput(input); err != nil {
+		return nil, err
+	}
+
 	// Convert gql-gateway RegisterInput to pkg.RegisterInput
 	registerInput := pkg.RegisterInput{
 		FirstName: input.FirstName,
@@ -67,16 +145,17 @@ func (r *mutationResolver) CreateCustomer(ctx context.Context, input models.Regi
 
 // resolver for the createCategory field
 func (r *mutationResolver) CreateCategory(ctx context.Context, input models.CategoryInput) (*models.Category, error) {
+	if err := validateCategoryInput(input); err != nil {
+		return nil, err
+	}
+
 	var parentID *int
 	if input.ParentID != nil {
-		id, err := strconv.Atoi(*input.ParentID)
-		if err != nil {
-			return nil, err
-		}
+		id, _ := strconv.Atoi(*input.ParentID)
 		parentID = &id
 	}
 
-	category, err := r.Resolver.CategoryRepo.CreateCategory(ctx, input.Name, parentID)
+	category, err := r.Resolver.CategoryRepo.CreateCategory(ctx, strings.TrimSpace(input.Name), parentID)
 	if err != nil {
 		return nil, err
 	}
@@ -92,25 +171,41 @@ func (r *mutationResolver) CreateCategory(ctx context.Context, input models.Cate
 
 // CreateProduct is the resolver for the createProduct field.
 func (r *mutationResolver) CreateProduct(ctx context.Context, input models.ProductInput) (*models.Product, error) {
+	if err := validateProductInput(input); err != nil {
+		return nil, err
+	}
+
 	var categoryID *int
 	if input.CategoryID != nil {
-		id, err := strconv.Atoi(*input.CategoryID)
-		if err != nil {
-			return nil, err
-		}
+		id, _ := strconv.Atoi(*input.CategoryID)
 		categoryID = &id
 	}
 
-	product, err := r.Resolver.ProductRepo.CreateProduct(ctx, input.Name, input.Description, input.Price, categoryID)
+	product, err := r.Resolver.ProductRepo.InsertProduct(ctx, &rootModels.Product{
+		SKU:         input.Sku,
+		Name:        input.Name,
+		Description: input.Description,
+		Price:       input.Price,
+		CategoryID:  categoryID,
+		WeightKg:    input.WeightKg,
+		LengthCm:    input.LengthCm,
+		WidthCm:     input.WidthCm,
+		HeightCm:    input.HeightCm,
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.Product{
 		ID:          strconv.Itoa(product.ID),
+		Sku:         product.SKU,
 		Name:        input.Name,
 		Description: input.Description,
 		Price:       input.Price,
+		WeightKg:    product.WeightKg,
+		LengthCm:    product.LengthCm,
+		WidthCm:     product.WidthCm,
+		HeightCm:    product.HeightCm,
 		Category: func() *models.Category {
 			if input.CategoryID != nil {
 				return &models.Category{ID: *input.CategoryID}
@@ -120,118 +215,1012 @@ func (r *mutationResolver) CreateProduct(ctx context.Context, input models.Produ
 	}, nil
 }
 
-// CreateOrder is the resolver for the createOrder field.
-func (r *mutationResolver) CreateOrder(ctx context.Context, input models.OrderInput) (*models.Order, error) {
-	// Ensure at least one order item
-	if len(input.Items) == 0 {
-		return nil, fmt.Errorf("at least one order item is required")
+// CreateOrder is the resolver for the createOrder field.
+func (r *mutationResolver) CreateOrder(ctx context.Context, input models.OrderInput, idempotencyKey *string) (*models.Order, error) {
+	return idempotent(ctx, r.Resolver, "createOrder", idempotencyKey, input, func() (*models.Order, error) {
+		return r.Resolver.createOrder(ctx, input)
+	})
+}
+
+// UpdateOrderStatus is the resolver for the updateOrderStatus field.
+func (r *mutationResolver) UpdateOrderStatus(ctx context.Context, orderID string, status string) (bool, error) {
+	user, err := pkg.RequireStaff(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	id, err := strconv.Atoi(orderID)
+	if err != nil {
+		return false, invalidID("order", orderID)
+	}
+
+	// Update the order status in the repository
+	err = r.Resolver.OrderRepo.UpdateOrderStatus(ctx, id, status, &user.Sub)
+	if err != nil {
+		return false, fmt.Errorf("failed to update order status: %w", err)
+	}
+	r.Resolver.publishOrder(ctx, id, orderTopic(id))
+
+	return true, nil
+}
+
+// CancelOrder is the resolver for the cancelOrder field.
+func (r *mutationResolver) CancelOrder(ctx context.Context, orderID string, reason string) (*models.Order, error) {
+	id, err := strconv.Atoi(orderID)
+	if err != nil {
+		return nil, invalidID("order", orderID)
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, repo.Validation("reason", "a reason is required to cancel an order")
+	}
+
+	order, err := r.Resolver.OrderRepo.GetOrder(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	if err := r.Resolver.authorizeCustomer(ctx, order.CustomerID); err != nil {
+		return nil, err
+	}
+
+	var changedBy *string
+	if user, ok := pkg.UserFromContext(ctx); ok {
+		changedBy = &user.Sub
+	}
+
+	cancelled, refund, err := r.Resolver.OrderRepo.CancelOrder(ctx, id, reason, changedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel order: %w", err)
+	}
+
+	r.Resolver.notifyOrderCancelled(ctx, *cancelled, refund)
+	r.Resolver.publishOrder(ctx, id, orderTopic(id))
+	r.Resolver.notifyWishlists(ctx, r.Resolver.orderProductIDs(ctx, id))
+
+	return r.Resolver.loadOrder(ctx, *cancelled)
+}
+
+// CreatePromotion is the resolver for the createPromotion field.
+func (r *mutationResolver) CreatePromotion(ctx context.Context, input models.PromotionInput) (*models.Promotion, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
+	promotion, err := promotionFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := r.Resolver.PromotionRepo.CreatePromotion(ctx, promotion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create promotion: %w", err)
+	}
+
+	return toGQLPromotion(*created), nil
+}
+
+// SetPromotionActive is the resolver for the setPromotionActive field.
+func (r *mutationResolver) SetPromotionActive(ctx context.Context, id string, active bool) (bool, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return false, err
+	}
+
+	promotionID, err := strconv.Atoi(id)
+	if err != nil {
+		return false, invalidID("promotion", id)
+	}
+
+	if err := r.Resolver.PromotionRepo.SetPromotionActive(ctx, promotionID, active); err != nil {
+		return false, fmt.Errorf("failed to update promotion: %w", err)
+	}
+
+	return true, nil
+}
+
+// ApplyCoupon is the resolver for the applyCoupon field.
+func (r *mutationResolver) ApplyCoupon(ctx context.Context, orderID string, code string) (*models.Order, error) {
+	id, err := strconv.Atoi(orderID)
+	if err != nil {
+		return nil, invalidID("order", orderID)
+	}
+
+	order, err := r.Resolver.OrderRepo.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Resolver.authorizeCustomer(ctx, order.CustomerID); err != nil {
+		return nil, err
+	}
+	if order.Status != "pending" {
+		return nil, repo.Conflict("coupons can only be applied to pending orders")
+	}
+
+	items, err := r.Resolver.OrderItemRepo.GetItemsByOrder(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	var itemInputs []rootModels.OrderItemInput
+	for _, item := range items {
+		itemInputs = append(itemInputs, rootModels.OrderItemInput{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			Price:     item.Price,
+		})
+	}
+	lines, err := r.Resolver.promotionLines(ctx, itemInputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load order products: %w", err)
+	}
+
+	// coupons replace each other, automatic discounts stay in place
+	existing, err := r.Resolver.OrderRepo.ListDiscountsByOrder(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	var automatic float64
+	for _, d := range existing {
+		if d.Code == nil {
+			automatic += d.Amount
+		}
+	}
+
+	coupon, err := r.Resolver.couponDiscount(ctx, code, order.CustomerID, lines, automatic)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := r.Resolver.OrderRepo.ReplaceCouponDiscounts(ctx, order.ID, []rootModels.OrderDiscount{*coupon})
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply coupon: %w", err)
+	}
+
+	return r.Resolver.loadOrder(ctx, *updated)
+}
+
+// CreateOptionType is the resolver for the createOptionType field.
+func (r *mutationResolver) CreateOptionType(ctx context.Context, name string) (*models.OptionType, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil, repo.Validation("name", "option type name is required")
+	}
+
+	optionType, err := r.Resolver.VariantRepo.CreateOptionType(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create option type: %w", err)
+	}
+
+	return &models.OptionType{
+		ID:   strconv.Itoa(optionType.ID),
+		Name: optionType.Name,
+	}, nil
+}
+
+// CreateProductVariant is the resolver for the createProductVariant field.
+func (r *mutationResolver) CreateProductVariant(ctx context.Context, input models.ProductVariantInput) (*models.ProductVariant, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
+	productID, err := strconv.Atoi(input.ProductID)
+	if err != nil {
+		return nil, invalidID("product", input.ProductID)
+	}
+
+	sku := strings.TrimSpace(input.Sku)
+	if sku == "" {
+		return nil, repo.Validation("input.sku", "sku is required")
+	}
+	if input.Stock < 0 {
+		return nil, repo.Validation("input.stock", "stock cannot be negative")
+	}
+	if input.Price != nil && *input.Price < 0 {
+		return nil, repo.Validation("input.price", "price cannot be negative")
+	}
+
+	var options []rootModels.VariantOption
+	for _, opt := range input.Options {
+		options = append(options, rootModels.VariantOption{
+			Name:  strings.ToLower(strings.TrimSpace(opt.Name)),
+			Value: strings.TrimSpace(opt.Value),
+		})
+	}
+
+	variant, err := r.Resolver.VariantRepo.CreateVariant(ctx, &rootModels.ProductVariant{
+		ProductID: productID,
+		SKU:       sku,
+		Price:     input.Price,
+		Stock:     input.Stock,
+		Options:   options,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create product variant: %w", err)
+	}
+
+	return toGQLVariant(*variant), nil
+}
+
+// UpdateVariantStock is the resolver for the updateVariantStock field.
+func (r *mutationResolver) UpdateVariantStock(ctx context.Context, variantID string, stock int) (bool, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return false, err
+	}
+
+	id, err := strconv.Atoi(variantID)
+	if err != nil {
+		return false, invalidID("variant", variantID)
+	}
+	if stock < 0 {
+		return false, repo.Validation("input.stock", "stock cannot be negative")
+	}
+
+	if err := r.Resolver.VariantRepo.UpdateVariantStock(ctx, id, stock); err != nil {
+		return false, fmt.Errorf("failed to update stock: %w", err)
+	}
+	if variants, err := r.Resolver.VariantRepo.GetVariantsByIDs(ctx, []int{id}); err == nil && len(variants) == 1 {
+		r.Resolver.notifyWishlists(ctx, []int{variants[0].ProductID})
+	}
+
+	return true, nil
+}
+
+// SetPrimaryProductImage is the resolver for the setPrimaryProductImage field.
+func (r *mutationResolver) SetPrimaryProductImage(ctx context.Context, imageID string) (bool, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return false, err
+	}
+
+	id, err := strconv.Atoi(imageID)
+	if err != nil {
+		return false, invalidID("image", imageID)
+	}
+
+	if err := r.Resolver.MediaRepo.SetPrimaryMedia(ctx, id); err != nil {
+		return false, fmt.Errorf("failed to set primary image: %w", err)
+	}
+
+	return true, nil
+}
+
+// ReorderProductImages is the resolver for the reorderProductImages field.
+func (r *mutationResolver) ReorderProductImages(ctx context.Context, productID string, imageIDs []string) ([]*models.ProductImage, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
+	pid, err := strconv.Atoi(productID)
+	if err != nil {
+		return nil, invalidID("product", productID)
+	}
+
+	ids := make([]int, 0, len(imageIDs))
+	for _, imageID := range imageIDs {
+		id, err := strconv.Atoi(imageID)
+		if err != nil {
+			return nil, invalidID("image", imageID)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := r.Resolver.MediaRepo.ReorderMedia(ctx, pid, ids); err != nil {
+		return nil, fmt.Errorf("failed to reorder images: %w", err)
+	}
+
+	media, err := r.Resolver.MediaRepo.ListMediaByProduct(ctx, pid)
+	if err != nil {
+		return nil, err
+	}
+
+	gqlImages := []*models.ProductImage{}
+	for _, m := range media {
+		gqlImages = append(gqlImages, r.Resolver.toGQLProductImage(m))
+	}
+
+	return gqlImages, nil
+}
+
+// DeleteProductImage is the resolver for the deleteProductImage field.
+func (r *mutationResolver) DeleteProductImage(ctx context.Context, imageID string) (bool, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return false, err
+	}
+
+	id, err := strconv.Atoi(imageID)
+	if err != nil {
+		return false, invalidID("image", imageID)
+	}
+
+	deleted, err := r.Resolver.MediaRepo.DeleteMedia(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete image: %w", err)
+	}
+
+	// the row is gone, leftover files are only logged
+	for _, key := range []string{deleted.OriginalKey, deleted.MediumKey, deleted.ThumbnailKey} {
+		if err := r.Resolver.Storage.Delete(ctx, key); err != nil {
+			log.Printf("failed to delete %s: %v", key, err)
+		}
+	}
+
+	return true, nil
+}
+
+// CreateAddress is the resolver for the createAddress field.
+func (r *mutationResolver) CreateAddress(ctx context.Context, input models.CustomerAddressInput) (*models.CustomerAddress, error) {
+	customerID, err := r.Resolver.currentCustomerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	address, err := customerAddressFromInput(customerID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := r.Resolver.AddressRepo.CreateAddress(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create address: %w", err)
+	}
+
+	return toGQLCustomerAddress(*created), nil
+}
+
+// UpdateAddress is the resolver for the updateAddress field.
+func (r *mutationResolver) UpdateAddress(ctx context.Context, id string, input models.CustomerAddressInput) (*models.CustomerAddress, error) {
+	addressID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, invalidID("address", id)
+	}
+
+	customerID, err := r.Resolver.currentCustomerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	address, err := customerAddressFromInput(customerID, input)
+	if err != nil {
+		return nil, err
+	}
+	address.ID = addressID
+
+	updated, err := r.Resolver.AddressRepo.UpdateAddress(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update address: %w", err)
+	}
+
+	return toGQLCustomerAddress(*updated), nil
+}
+
+// DeleteAddress is the resolver for the deleteAddress field.
+func (r *mutationResolver) DeleteAddress(ctx context.Context, id string) (bool, error) {
+	addressID, err := strconv.Atoi(id)
+	if err != nil {
+		return false, invalidID("address", id)
+	}
+
+	customerID, err := r.Resolver.currentCustomerID(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.Resolver.AddressRepo.DeleteAddress(ctx, customerID, addressID); err != nil {
+		return false, fmt.Errorf("failed to delete address: %w", err)
+	}
+
+	return true, nil
+}
+
+// CreateShippingMethod is the resolver for the createShippingMethod field.
+func (r *mutationResolver) CreateShippingMethod(ctx context.Context, input models.ShippingMethodInput) (*models.ShippingMethod, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
+	method, err := shippingMethodFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := r.Resolver.ShippingRepo.CreateMethod(ctx, method)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shipping method: %w", err)
+	}
+
+	return toGQLShippingMethod(*created), nil
+}
+
+// SetShippingMethodActive is the resolver for the setShippingMethodActive field.
+func (r *mutationResolver) SetShippingMethodActive(ctx context.Context, id string, active bool) (bool, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return false, err
+	}
+
+	methodID, err := strconv.Atoi(id)
+	if err != nil {
+		return false, invalidID("shipping method", id)
+	}
+
+	if err := r.Resolver.ShippingRepo.SetMethodActive(ctx, methodID, active); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// CreateShippingZone is the resolver for the createShippingZone field.
+func (r *mutationResolver) CreateShippingZone(ctx context.Context, input models.ShippingZoneInput) (*models.ShippingZone, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
+	zone, err := shippingZoneFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := r.Resolver.ShippingRepo.CreateZone(ctx, zone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shipping zone: %w", err)
+	}
+
+	return toGQLShippingZone(*created), nil
+}
+
+// CreateShippingRate is the resolver for the createShippingRate field.
+func (r *mutationResolver) CreateShippingRate(ctx context.Context, input models.ShippingRateInput) (*models.ShippingRate, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
+	rate, err := shippingRateFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := r.Resolver.ShippingRepo.CreateRate(ctx, rate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shipping rate: %w", err)
+	}
+
+	table, err := r.Resolver.ShippingRepo.LoadTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return toGQLShippingRate(*created, table), nil
+}
+
+// DeleteShippingRate is the resolver for the deleteShippingRate field.
+func (r *mutationResolver) DeleteShippingRate(ctx context.Context, id string) (bool, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return false, err
+	}
+
+	rateID, err := strconv.Atoi(id)
+	if err != nil {
+		return false, invalidID("shipping rate", id)
+	}
+
+	if err := r.Resolver.ShippingRepo.DeleteRate(ctx, rateID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// CreateShipment is the resolver for the createShipment field.
+func (r *mutationResolver) CreateShipment(ctx context.Context, input models.ShipmentInput) (*models.Shipment, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
+	shipment, err := shipmentFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := r.Resolver.ShipmentRepo.CreateShipment(ctx, shipment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shipment: %w", err)
+	}
+
+	r.Resolver.notifyShipmentDispatched(ctx, *created)
+	r.Resolver.publishOrder(ctx, created.OrderID, orderTopic(created.OrderID))
+
+	return r.Resolver.shipmentFromOrder(ctx, created.OrderID, created.ID)
+}
+
+// MarkShipmentDelivered is the resolver for the markShipmentDelivered field.
+func (r *mutationResolver) MarkShipmentDelivered(ctx context.Context, shipmentID string) (*models.Shipment, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.Atoi(shipmentID)
+	if err != nil {
+		return nil, invalidID("shipment", shipmentID)
+	}
+
+	delivered, err := r.Resolver.ShipmentRepo.MarkDelivered(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark shipment delivered: %w", err)
+	}
+	r.Resolver.publishOrder(ctx, delivered.OrderID, orderTopic(delivered.OrderID))
+
+	return r.Resolver.shipmentFromOrder(ctx, delivered.OrderID, delivered.ID)
+}
+
+// RequestReturn is the resolver for the requestReturn field.
+func (r *mutationResolver) RequestReturn(ctx context.Context, input models.ReturnInput) (*models.Return, error) {
+	ret, err := returnFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := r.Resolver.OrderRepo.GetOrder(ctx, ret.OrderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	if err := r.Resolver.authorizeCustomer(ctx, order.CustomerID); err != nil {
+		return nil, err
+	}
+
+	var changedBy *string
+	if user, ok := pkg.UserFromContext(ctx); ok {
+		changedBy = &user.Sub
+	}
+
+	created, err := r.Resolver.ReturnRepo.CreateReturn(ctx, ret, r.Resolver.returnWindowDays(), changedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request return: %w", err)
+	}
+
+	return r.Resolver.returnFromOrder(ctx, created.OrderID, created.ID)
+}
+
+// ApproveReturn is the resolver for the approveReturn field.
+func (r *mutationResolver) ApproveReturn(ctx context.Context, returnID string, note *string) (*models.Return, error) {
+	user, err := pkg.RequireStaff(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.Atoi(returnID)
+	if err != nil {
+		return nil, invalidID("return", returnID)
+	}
+
+	ret, err := r.Resolver.ReturnRepo.ApproveReturn(ctx, id, note, &user.Sub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to approve return: %w", err)
+	}
+
+	return r.Resolver.returnFromOrder(ctx, ret.OrderID, ret.ID)
+}
+
+// RejectReturn is the resolver for the rejectReturn field.
+func (r *mutationResolver) RejectReturn(ctx context.Context, returnID string, note *string) (*models.Return, error) {
+	user, err := pkg.RequireStaff(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.Atoi(returnID)
+	if err != nil {
+		return nil, invalidID("return", returnID)
+	}
+
+	ret, err := r.Resolver.ReturnRepo.RejectReturn(ctx, id, note, &user.Sub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reject return: %w", err)
+	}
+
+	return r.Resolver.returnFromOrder(ctx, ret.OrderID, ret.ID)
+}
+
+// ReceiveReturn is the resolver for the receiveReturn field.
+func (r *mutationResolver) ReceiveReturn(ctx context.Context, returnID string, restock *bool, note *string) (*models.Return, error) {
+	user, err := pkg.RequireStaff(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.Atoi(returnID)
+	if err != nil {
+		return nil, invalidID("return", returnID)
+	}
+
+	ret, err := r.Resolver.ReturnRepo.ReceiveReturn(ctx, id, restock == nil || *restock, note, &user.Sub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive return: %w", err)
+	}
+	if restock == nil || *restock {
+		r.Resolver.notifyWishlists(ctx, r.Resolver.orderProductIDs(ctx, ret.OrderID))
+	}
+
+	return r.Resolver.returnFromOrder(ctx, ret.OrderID, ret.ID)
+}
+
+// ProcessRefund is the resolver for the processRefund field.
+func (r *mutationResolver) ProcessRefund(ctx context.Context, refundID string) (*models.Refund, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.Atoi(refundID)
+	if err != nil {
+		return nil, invalidID("refund", refundID)
+	}
+
+	refund, err := r.Resolver.RefundRepo.ProcessRefund(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process refund: %w", err)
+	}
+
+	return toGQLRefund(*refund), nil
+}
+
+// CreateReview is the resolver for the createReview field.
+func (r *mutationResolver) CreateReview(ctx context.Context, input models.ReviewInput) (*models.Review, error) {
+	customerID, err := r.Resolver.currentCustomerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	review, err := reviewFromInput(input, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := r.Resolver.ReviewRepo.CreateReview(ctx, review)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create review: %w", err)
+	}
+
+	return toGQLReview(*created), nil
+}
+
+// ApproveReview is the resolver for the approveReview field.
+func (r *mutationResolver) ApproveReview(ctx context.Context, reviewID string) (*models.Review, error) {
+	user, err := pkg.RequireStaff(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.Atoi(reviewID)
+	if err != nil {
+		return nil, invalidID("review", reviewID)
+	}
+
+	review, err := r.Resolver.ReviewRepo.ModerateReview(ctx, id, rootModels.ReviewApproved, &user.Sub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to approve review: %w", err)
+	}
+
+	return toGQLReview(*review), nil
+}
+
+// RejectReview is the resolver for the rejectReview field.
+func (r *mutationResolver) RejectReview(ctx context.Context, reviewID string) (*models.Review, error) {
+	user, err := pkg.RequireStaff(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.Atoi(reviewID)
+	if err != nil {
+		return nil, invalidID("review", reviewID)
+	}
+
+	review, err := r.Resolver.ReviewRepo.ModerateReview(ctx, id, rootModels.ReviewRejected, &user.Sub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reject review: %w", err)
+	}
+
+	return toGQLReview(*review), nil
+}
+
+// CreateWishlist is the resolver for the createWishlist field.
+func (r *mutationResolver) CreateWishlist(ctx context.Context, name string) (*models.Wishlist, error) {
+	customerID, err := r.Resolver.currentCustomerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, repo.Validation("name", "wishlist name is required")
+	}
+
+	w, err := r.Resolver.WishlistRepo.CreateWishlist(ctx, customerID, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wishlist: %w", err)
+	}
+
+	return r.Resolver.loadWishlist(ctx, *w)
+}
+
+// DeleteWishlist is the resolver for the deleteWishlist field.
+func (r *mutationResolver) DeleteWishlist(ctx context.Context, id string) (bool, error) {
+	w, err := r.Resolver.ownWishlist(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.Resolver.WishlistRepo.DeleteWishlist(ctx, w.ID); err != nil {
+		return false, fmt.Errorf("failed to delete wishlist: %w", err)
+	}
+
+	return true, nil
+}
+
+// AddToWishlist is the resolver for the addToWishlist field.
+func (r *mutationResolver) AddToWishlist(ctx context.Context, input models.WishlistItemInput) (*models.Wishlist, error) {
+	productID, err := strconv.Atoi(input.ProductID)
+	if err != nil {
+		return nil, invalidID("product", input.ProductID)
+	}
+	item := rootModels.WishlistItem{ProductID: productID, Notify: input.Notify != nil && *input.Notify}
+	if input.VariantID != nil {
+		variantID, err := strconv.Atoi(*input.VariantID)
+		if err != nil {
+			return nil, invalidID("variant", *input.VariantID)
+		}
+		item.VariantID = &variantID
+	}
+
+	var w *rootModels.Wishlist
+	if input.WishlistID != nil {
+		w, err = r.Resolver.ownWishlist(ctx, *input.WishlistID)
+	} else {
+		var customerID int
+		if customerID, err = r.Resolver.currentCustomerID(ctx); err == nil {
+			w, err = r.Resolver.WishlistRepo.DefaultWishlist(ctx, customerID)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := r.Resolver.WishlistRepo.AddItem(ctx, w.ID, item)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add to wishlist: %w", err)
+	}
+
+	return r.Resolver.loadWishlist(ctx, *updated)
+}
+
+// RemoveFromWishlist is the resolver for the removeFromWishlist field.
+func (r *mutationResolver) RemoveFromWishlist(ctx context.Context, wishlistID string, productID string, variantID *string) (*models.Wishlist, error) {
+	w, err := r.Resolver.ownWishlist(ctx, wishlistID)
+	if err != nil {
+		return nil, err
+	}
+
+	pID, err := strconv.Atoi(productID)
+	if err != nil {
+		return nil, invalidID("product", productID)
+	}
+	var vID *int
+	if variantID != nil {
+		id, err := strconv.Atoi(*variantID)
+		if err != nil {
+			return nil, invalidID("variant", *variantID)
+		}
+		vID = &id
+	}
+
+	updated, err := r.Resolver.WishlistRepo.RemoveItem(ctx, w.ID, pID, vID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove from wishlist: %w", err)
+	}
+
+	return r.Resolver.loadWishlist(ctx, *updated)
+}
+
+// ShareWishlist is the resolver for the shareWishlist field.
+func (r *mutationResolver) ShareWishlist(ctx context.Context, id string) (*models.Wishlist, error) {
+	w, err := r.Resolver.ownWishlist(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := r.Resolver.WishlistRepo.Share(ctx, w.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to share wishlist: %w", err)
+	}
+
+	return r.Resolver.loadWishlist(ctx, *updated)
+}
+
+// UnshareWishlist is the resolver for the unshareWishlist field.
+func (r *mutationResolver) UnshareWishlist(ctx context.Context, id string) (*models.Wishlist, error) {
+	w, err := r.Resolver.ownWishlist(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := r.Resolver.WishlistRepo.Unshare(ctx, w.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unshare wishlist: %w", err)
+	}
+
+	return r.Resolver.loadWishlist(ctx, *updated)
+}
+
+// CreateWebhookSubscription is the resolver for the createWebhookSubscription field.
+func (r *mutationResolver) CreateWebhookSubscription(ctx context.Context, input models.WebhookSubscriptionInput) (*models.WebhookSubscription, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
+	subscription, err := webhookSubscriptionFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := r.Resolver.WebhookRepo.CreateSubscription(ctx, subscription)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+
+	return toGQLWebhookSubscription(*created), nil
+}
+
+// SetWebhookSubscriptionActive is the resolver for the setWebhookSubscriptionActive field.
+func (r *mutationResolver) SetWebhookSubscriptionActive(ctx context.Context, id string, active bool) (*models.WebhookSubscription, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
+	subscriptionID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, invalidID("webhook subscription", id)
+	}
+
+	subscription, err := r.Resolver.WebhookRepo.SetSubscriptionActive(ctx, subscriptionID, active)
+	if err != nil {
+		return nil, err
+	}
+
+	return toGQLWebhookSubscription(*subscription), nil
+}
+
+// DeleteWebhookSubscription is the resolver for the deleteWebhookSubscription field.
+func (r *mutationResolver) DeleteWebhookSubscription(ctx context.Context, id string) (bool, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return false, err
+	}
+
+	subscriptionID, err := strconv.Atoi(id)
+	if err != nil {
+		return false, invalidID("webhook subscription", id)
+	}
+
+	if err := r.Resolver.WebhookRepo.DeleteSubscription(ctx, subscriptionID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ReplayWebhookDelivery is the resolver for the replayWebhookDelivery field.
+func (r *mutationResolver) ReplayWebhookDelivery(ctx context.Context, deliveryID string) (*models.WebhookDelivery, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.Atoi(deliveryID)
+	if err != nil {
+		return nil, invalidID("webhook delivery", deliveryID)
 	}
 
-	var customerID int
-	var err error
+	delivery, err := r.Resolver.WebhookRepo.ReplayDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 
-	// Allow input.CustomerID if provided, else extract from JWT
-	if input.CustomerID != "" {
-		customerID, err = strconv.Atoi(input.CustomerID)
-		if err != nil {
-			return nil, fmt.Errorf("invalid customer ID: %w", err)
-		}
-	} else {
-		user, ok := pkg.UserFromContext(ctx)
-		if !ok {
-			return nil, fmt.Errorf("unauthorized: missing or invalid token")
-		}
+	return toGQLWebhookDelivery(*delivery), nil
+}
 
-		customerID, err = r.Resolver.CustomerRepo.FindCustomerIDByAuth0Sub(ctx, user.Sub)
-		if err != nil {
-			return nil, fmt.Errorf("could not resolve customer from token: %w", err)
-		}
+// Variants is the resolver for the variants field.
+func (r *productResolver) Variants(ctx context.Context, obj *models.Product) ([]*models.ProductVariant, error) {
+	productID, err := strconv.Atoi(obj.ID)
+	if err != nil {
+		return nil, invalidID("product", obj.ID)
 	}
 
-	// Build order items for repository
-	var repoOrderItemsInput []rootModels.OrderItemInput
-	for _, item := range input.Items {
-		productID, err := strconv.Atoi(item.ProductID)
-		if err != nil {
-			return nil, fmt.Errorf("invalid product ID: %w", err)
-		}
-		repoOrderItemsInput = append(repoOrderItemsInput, rootModels.OrderItemInput{
-			ProductID: productID,
-			Quantity:  item.Quantity,
-			Price:     item.Price,
-		})
+	variants, err := r.Resolver.VariantRepo.ListVariantsByProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	gqlVariants := []*models.ProductVariant{}
+	for _, v := range variants {
+		gqlVariants = append(gqlVariants, toGQLVariant(v))
 	}
 
-	// Create order in repo
-	orderID, err := r.Resolver.OrderRepo.CreateOrder(ctx, customerID, repoOrderItemsInput)
+	return gqlVariants, nil
+}
+
+// Images is the resolver for the images field.
+func (r *productResolver) Images(ctx context.Context, obj *models.Product) ([]*models.ProductImage, error) {
+	productID, err := strconv.Atoi(obj.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create order: %w", err)
+		return nil, invalidID("product", obj.ID)
 	}
 
-	// Fetch complete order
-	order, err := r.Resolver.OrderRepo.GetOrder(ctx, orderID.ID)
+	media, err := r.Resolver.MediaRepo.ListMediaByProduct(ctx, productID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve created order: %w", err)
+		return nil, err
 	}
 
-	// Fetch customer
-	customer, err := r.Resolver.CustomerRepo.GetCustomerById(ctx, customerID)
+	gqlImages := []*models.ProductImage{}
+	for _, m := range media {
+		gqlImages = append(gqlImages, r.Resolver.toGQLProductImage(m))
+	}
+
+	return gqlImages, nil
+}
+
+// Reviews is the resolver for the reviews field.
+func (r *productResolver) Reviews(ctx context.Context, obj *models.Product, first *int, after *string) (*models.ReviewPage, error) {
+	productID, err := strconv.Atoi(obj.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve customer: %w", err)
+		return nil, invalidID("product", obj.ID)
 	}
 
-	// Try to send SMS
-	smsService, err := pkg.NewSMSService()
+	limit, err := pageSize(first)
 	if err != nil {
-		log.Printf("failed to initialize SMS service: %v", err)
-	} else {
-		fullName := fmt.Sprintf("%s %s", customer.FirstName, customer.LastName)
-		if err := smsService.SendOrderConfirmationSMS(customer.Phone, fullName); err != nil {
-			log.Printf("failed to send SMS: %v", err)
+		return nil, err
+	}
+	var afterID *int
+	if after != nil {
+		id, err := decodeCursor("review", *after)
+		if err != nil {
+			return nil, err
 		}
+		afterID = &id
 	}
 
-	// Build GraphQL response
-	var gqlOrderItems []*models.OrderItem
-	for _, item := range repoOrderItemsInput {
-		gqlOrderItems = append(gqlOrderItems, &models.OrderItem{
-			ID:       "", // ID not returned from CreateOrder
-			Product:  &models.Product{ID: strconv.Itoa(item.ProductID)},
-			Quantity: item.Quantity,
-			Price:    item.Price,
-		})
+	// one extra row tells whether another page follows
+	reviews, total, err := r.Resolver.ReviewRepo.ListProductReviews(ctx, productID, afterID, limit+1)
+	if err != nil {
+		return nil, err
 	}
 
-	return &models.Order{
-		ID:        strconv.Itoa(order.ID),
-		Status:    order.Status,
-		OrderDate: order.OrderDate.Format(time.RFC3339),
-		Items:     gqlOrderItems,
-		Customer: &models.Customer{
-			ID:        strconv.Itoa(customer.ID),
-			FirstName: customer.FirstName,
-			LastName:  customer.LastName,
-			Email:     customer.Email,
-			Phone:     customer.Phone,
-			CreatedAt: customer.CreatedAt.Format(time.RFC3339),
-		},
-	}, nil
+	page := &models.ReviewPage{Items: []*models.Review{}, TotalCount: total}
+	if len(reviews) > limit {
+		reviews = reviews[:limit]
+		page.HasNextPage = true
+	}
+	for _, review := range reviews {
+		page.Items = append(page.Items, toGQLReview(review))
+	}
+	if len(reviews) > 0 {
+		cursor := encodeCursor("review", reviews[len(reviews)-1].ID)
+		page.EndCursor = &cursor
+	}
+
+	return page, nil
 }
 
-// UpdateOrderStatus is the resolver for the updateOrderStatus field.
-func (r *mutationResolver) UpdateOrderStatus(ctx context.Context, orderID string, status string) (bool, error) {
-	id, err := strconv.Atoi(orderID)
+// Me is the resolver for the me field.
+func (r *queryResolver) Me(ctx context.Context) (*models.Customer, error) {
+	customerID, err := r.Resolver.currentCustomerID(ctx)
 	if err != nil {
-		return false, fmt.Errorf("invalid order ID: %w", err)
+		return nil, err
 	}
 
-	// Update the order status in the repository
-	err = r.Resolver.OrderRepo.UpdateOrderStatus(ctx, id, status)
+	customer, err := r.Resolver.CustomerRepo.GetCustomerById(ctx, customerID)
 	if err != nil {
-		return false, fmt.Errorf("failed to update order status: %w", err)
+		return nil, err
 	}
-
-	return true, nil
+	return toGQLCustomer(*customer), nil
 }
 
 // Call ProductRepo.ListProducts to get all products.
@@ -243,48 +1232,30 @@ func (r *queryResolver) GetAllProducts(ctx context.Context) ([]*models.Product,
 
 	var result []*models.Product
 	for _, p := range products {
-		product := &models.Product{
-			ID:          strconv.Itoa(p.ID),
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       p.Price,
-		}
-		if p.CategoryID != nil {
-			categoryIDStr := strconv.Itoa(*p.CategoryID)
-			product.Category = &models.Category{ID: categoryIDStr}
-		}
-		result = append(result, product)
+		result = append(result, toGQLProduct(p))
 	}
 	return result, nil
 }
 
+// GetProduct is the resolver for the getProduct field.
 func (r *queryResolver) GetProduct(ctx context.Context, id string) (*models.Product, error) {
 	productID, err := strconv.Atoi(id)
 	if err != nil {
-		return nil, fmt.Errorf("invalid product ID: %w", err)
+		return nil, invalidID("product", id)
 	}
 
 	p, err := r.Resolver.ProductRepo.GetProduct(ctx, productID)
-	if err != nil {
-		return nil, err
-	}
-	if p == nil {
+	if errors.Is(err, repo.ErrNotFound) {
 		return nil, nil
 	}
-
-	product := &models.Product{
-		ID:          strconv.Itoa(p.ID),
-		Name:        p.Name,
-		Description: p.Description,
-		Price:       p.Price,
-	}
-	if p.CategoryID != nil {
-		product.Category = &models.Category{ID: strconv.Itoa(*p.CategoryID)}
+	if err != nil {
+		return nil, err
 	}
 
-	return product, nil
+	return toGQLProduct(*p), nil
 }
 
+// GetAllCategories is the resolver for the getAllCategories field.
 func (r *queryResolver) GetAllCategories(ctx context.Context) ([]*models.Category, error) {
 	categories, err := r.Resolver.CategoryRepo.ListCategories(ctx)
 	if err != nil {
@@ -306,16 +1277,16 @@ func (r *queryResolver) GetAllCategories(ctx context.Context) ([]*models.Categor
 func (r *queryResolver) GetCategory(ctx context.Context, id string) (*models.Category, error) {
 	catID, err := strconv.Atoi(id)
 	if err != nil {
-		return nil, fmt.Errorf("invalid category ID: %w", err)
+		return nil, invalidID("category", id)
 	}
 
 	c, err := r.Resolver.CategoryRepo.GetCategoryById(ctx, catID)
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	if c == nil {
-		return nil, nil
-	}
 
 	return &models.Category{
 		ID:   strconv.Itoa(c.ID),
@@ -349,16 +1320,16 @@ func (r *queryResolver) GetAllCustomers(ctx context.Context) ([]*models.Customer
 func (r *queryResolver) GetCustomer(ctx context.Context, id string) (*models.Customer, error) {
 	customerID, err := strconv.Atoi(id)
 	if err != nil {
-		return nil, fmt.Errorf("invalid customer ID: %w", err)
+		return nil, invalidID("customer", id)
 	}
 
 	c, err := r.Resolver.CustomerRepo.GetCustomerById(ctx, customerID)
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	if c == nil {
-		return nil, nil // or return a not found error if you prefer
-	}
 
 	customer := &models.Customer{
 		ID:        strconv.Itoa(c.ID),
@@ -372,6 +1343,11 @@ func (r *queryResolver) GetCustomer(ctx context.Context, id string) (*models.Cus
 
 // call OrderRepo.ListOrders to get all orders.
 func (r *queryResolver) GetAllOrders(ctx context.Context) ([]*models.Order, error) {
+	// every customer's orders, customers list their own through me
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
 	orders, err := r.Resolver.OrderRepo.ListOrders(ctx)
 	if err != nil {
 		return nil, err
@@ -379,30 +1355,12 @@ func (r *queryResolver) GetAllOrders(ctx context.Context) ([]*models.Order, erro
 
 	var gqlOrders []*models.Order
 	for _, o := range orders {
-		// Fetch items for each order
-		items, err := r.Resolver.OrderItemRepo.GetItemsByOrder(ctx, o.ID)
+		// Fetch items and discounts for each order
+		gqlOrder, err := r.Resolver.loadOrder(ctx, o)
 		if err != nil {
 			return nil, err
 		}
 
-		var gqlItems []*models.OrderItem
-		for _, item := range items {
-			gqlItems = append(gqlItems, &models.OrderItem{
-				ID:       strconv.Itoa(item.ID),
-				Product:  &models.Product{ID: strconv.Itoa(item.ProductID)},
-				Quantity: item.Quantity,
-				Price:    item.Price,
-			})
-		}
-
-		gqlOrder := &models.Order{
-			ID:        strconv.Itoa(o.ID),
-			Customer:  &models.Customer{ID: strconv.Itoa(o.CustomerID)},
-			OrderDate: o.OrderDate.Format(time.RFC3339),
-			Status:    o.Status,
-			Items:     gqlItems,
-		}
-
 		gqlOrders = append(gqlOrders, gqlOrder)
 	}
 
@@ -413,45 +1371,54 @@ func (r *queryResolver) GetAllOrders(ctx context.Context) ([]*models.Order, erro
 func (r *queryResolver) GetOrder(ctx context.Context, id string) (*models.Order, error) {
 	orderID, err := strconv.Atoi(id)
 	if err != nil {
-		return nil, fmt.Errorf("invalid order ID: %w", err)
+		return nil, invalidID("order", id)
 	}
 
 	o, err := r.Resolver.OrderRepo.GetOrder(ctx, orderID)
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
+	if err := r.Resolver.authorizeCustomer(ctx, o.CustomerID); err != nil {
+		return nil, err
+	}
 
-	items, err := r.Resolver.OrderItemRepo.GetItemsByOrder(ctx, o.ID)
+	gqlOrder, err := r.Resolver.loadOrder(ctx, *o)
 	if err != nil {
 		return nil, err
 	}
 
-	var gqlItems []*models.OrderItem
-	for _, item := range items {
-		gqlItems = append(gqlItems, &models.OrderItem{
-			ID:       strconv.Itoa(item.ID),
-			Product:  &models.Product{ID: strconv.Itoa(item.ProductID)},
-			Quantity: item.Quantity,
-			Price:    item.Price,
-		})
+	return gqlOrder, nil
+}
+
+// GetOrderByNumber is the resolver for the getOrderByNumber field.
+func (r *queryResolver) GetOrderByNumber(ctx context.Context, orderNumber string) (*models.Order, error) {
+	number, err := ordernumber.Normalize(orderNumber)
+	if err != nil {
+		return nil, repo.Validation("orderNumber", "%v %q, check it for typos", err, orderNumber)
 	}
 
-	gqlOrder := &models.Order{
-		ID:        strconv.Itoa(o.ID),
-		Customer:  &models.Customer{ID: strconv.Itoa(o.CustomerID)},
-		OrderDate: o.OrderDate.Format(time.RFC3339),
-		Status:    o.Status,
-		Items:     gqlItems,
+	o, err := r.Resolver.OrderRepo.GetOrderByNumber(ctx, number)
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Resolver.authorizeCustomer(ctx, o.CustomerID); err != nil {
+		return nil, err
 	}
 
-	return gqlOrder, nil
+	return r.Resolver.loadOrder(ctx, *o)
 }
 
 // returning the average product price for a category
 func (r *queryResolver) AveragePriceByCategory(ctx context.Context, categoryID string) (float64, error) {
 	catID, err := strconv.Atoi(categoryID)
 	if err != nil {
-		return 0, fmt.Errorf("invalid category ID: %w", err)
+		return 0, invalidID("category", categoryID)
 	}
 
 	avgPrice, err := r.Resolver.ProductRepo.GetAveragePriceByCategory(ctx, catID)
@@ -497,11 +1464,307 @@ func (r *queryResolver) ProductCatalog(ctx context.Context) ([]*models.ProductCa
 	return gqlCatalogs, nil
 }
 
+// GetAllPromotions is the resolver for the getAllPromotions field.
+func (r *queryResolver) GetAllPromotions(ctx context.Context) ([]*models.Promotion, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
+	promos, err := r.Resolver.PromotionRepo.ListPromotions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var gqlPromotions []*models.Promotion
+	for _, p := range promos {
+		gqlPromotions = append(gqlPromotions, toGQLPromotion(p))
+	}
+
+	return gqlPromotions, nil
+}
+
+// GetAllOptionTypes is the resolver for the getAllOptionTypes field.
+func (r *queryResolver) GetAllOptionTypes(ctx context.Context) ([]*models.OptionType, error) {
+	optionTypes, err := r.Resolver.VariantRepo.ListOptionTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var gqlOptionTypes []*models.OptionType
+	for _, o := range optionTypes {
+		gqlOptionTypes = append(gqlOptionTypes, &models.OptionType{
+			ID:   strconv.Itoa(o.ID),
+			Name: o.Name,
+		})
+	}
+
+	return gqlOptionTypes, nil
+}
+
+// GetVariantBySku is the resolver for the getVariantBySku field.
+func (r *queryResolver) GetVariantBySku(ctx context.Context, sku string) (*models.ProductVariant, error) {
+	variant, err := r.Resolver.VariantRepo.GetVariantBySKU(ctx, strings.TrimSpace(sku))
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return toGQLVariant(*variant), nil
+}
+
+// GetMyAddresses is the resolver for the getMyAddresses field.
+func (r *queryResolver) GetMyAddresses(ctx context.Context) ([]*models.CustomerAddress, error) {
+	customerID, err := r.Resolver.currentCustomerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses, err := r.Resolver.AddressRepo.ListAddressesByCustomer(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*models.CustomerAddress{}
+	for _, a := range addresses {
+		result = append(result, toGQLCustomerAddress(a))
+	}
+	return result, nil
+}
+
+// AvailableShippingMethods is the resolver for the availableShippingMethods field.
+func (r *queryResolver) AvailableShippingMethods(ctx context.Context, input models.ShippingQuoteInput) ([]*models.ShippingQuote, error) {
+	address, items, err := r.Resolver.quoteTarget(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes, err := r.Resolver.shippingQuotes(ctx, *address, items)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*models.ShippingQuote{}
+	for _, q := range quotes {
+		result = append(result, toGQLShippingQuote(q))
+	}
+	return result, nil
+}
+
+// GetAllShippingMethods is the resolver for the getAllShippingMethods field.
+func (r *queryResolver) GetAllShippingMethods(ctx context.Context) ([]*models.ShippingMethod, error) {
+	methods, err := r.Resolver.ShippingRepo.ListMethods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*models.ShippingMethod{}
+	for _, m := range methods {
+		result = append(result, toGQLShippingMethod(m))
+	}
+	return result, nil
+}
+
+// GetShippingZones is the resolver for the getShippingZones field.
+func (r *queryResolver) GetShippingZones(ctx context.Context) ([]*models.ShippingZone, error) {
+	zones, err := r.Resolver.ShippingRepo.ListZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*models.ShippingZone{}
+	for _, z := range zones {
+		result = append(result, toGQLShippingZone(z))
+	}
+	return result, nil
+}
+
+// GetShippingRates is the resolver for the getShippingRates field.
+func (r *queryResolver) GetShippingRates(ctx context.Context) ([]*models.ShippingRate, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
+	table, err := r.Resolver.ShippingRepo.LoadTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*models.ShippingRate{}
+	for _, rate := range table.Rates {
+		result = append(result, toGQLShippingRate(rate, table))
+	}
+	return result, nil
+}
+
+// GetReturns is the resolver for the getReturns field.
+func (r *queryResolver) GetReturns(ctx context.Context, status *string) ([]*models.Return, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
+	returns, err := r.Resolver.ReturnRepo.ListReturns(ctx, status)
+	if err != nil {
+		return nil, err
+	}
+
+	// load each order once, its items and refunds are shared by its returns
+	orders := map[int]*models.Order{}
+	gqlReturns := []*models.Return{}
+	for _, ret := range returns {
+		gqlOrder, ok := orders[ret.OrderID]
+		if !ok {
+			order, err := r.Resolver.OrderRepo.GetOrder(ctx, ret.OrderID)
+			if err != nil {
+				return nil, err
+			}
+			if gqlOrder, err = r.Resolver.loadOrder(ctx, *order); err != nil {
+				return nil, err
+			}
+			orders[ret.OrderID] = gqlOrder
+		}
+
+		for _, gqlReturn := range gqlOrder.Returns {
+			if gqlReturn.ID == strconv.Itoa(ret.ID) {
+				gqlReturns = append(gqlReturns, gqlReturn)
+			}
+		}
+	}
+	return gqlReturns, nil
+}
+
+// GetReviews is the resolver for the getReviews field.
+func (r *queryResolver) GetReviews(ctx context.Context, status *string) ([]*models.Review, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
+	reviews, err := r.Resolver.ReviewRepo.ListReviews(ctx, status)
+	if err != nil {
+		return nil, err
+	}
+
+	gqlReviews := []*models.Review{}
+	for _, review := range reviews {
+		gqlReviews = append(gqlReviews, toGQLReview(review))
+	}
+	return gqlReviews, nil
+}
+
+// SharedWishlist is the resolver for the sharedWishlist field.
+func (r *queryResolver) SharedWishlist(ctx context.Context, token string) (*models.Wishlist, error) {
+	w, err := r.Resolver.WishlistRepo.GetWishlistByShareToken(ctx, token)
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Resolver.loadWishlist(ctx, *w)
+}
+
+// GetWebhookSubscriptions is the resolver for the getWebhookSubscriptions field.
+func (r *queryResolver) GetWebhookSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
+	subscriptions, err := r.Resolver.WebhookRepo.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	gqlSubscriptions := []*models.WebhookSubscription{}
+	for _, s := range subscriptions {
+		gqlSubscriptions = append(gqlSubscriptions, toGQLWebhookSubscription(s))
+	}
+	return gqlSubscriptions, nil
+}
+
+// GetWebhookDeliveries is the resolver for the getWebhookDeliveries field.
+func (r *queryResolver) GetWebhookDeliveries(ctx context.Context, subscriptionID *string, status *string, first *int) ([]*models.WebhookDelivery, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
+	var subscription *int
+	if subscriptionID != nil {
+		id, err := strconv.Atoi(*subscriptionID)
+		if err != nil {
+			return nil, invalidID("webhook subscription", *subscriptionID)
+		}
+		subscription = &id
+	}
+
+	limit, err := pageSize(first)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := r.Resolver.WebhookRepo.ListDeliveries(ctx, subscription, status, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	gqlDeliveries := []*models.WebhookDelivery{}
+	for _, d := range deliveries {
+		gqlDeliveries = append(gqlDeliveries, toGQLWebhookDelivery(d))
+	}
+	return gqlDeliveries, nil
+}
+
+// OrderStatusChanged is the resolver for the orderStatusChanged field.
+func (r *subscriptionResolver) OrderStatusChanged(ctx context.Context, orderID string) (<-chan *models.Order, error) {
+	id, err := strconv.Atoi(orderID)
+	if err != nil {
+		return nil, invalidID("order", orderID)
+	}
+
+	order, err := r.Resolver.OrderRepo.GetOrder(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	if err := r.Resolver.authorizeCustomer(ctx, order.CustomerID); err != nil {
+		return nil, err
+	}
+
+	// shipments publish the order even when its status stays the same
+	lastStatus := order.Status
+	return r.Resolver.orderFeed(ctx, orderTopic(id), func(o rootModels.Order) bool {
+		changed := o.Status != lastStatus
+		lastStatus = o.Status
+		return changed
+	})
+}
+
+// NewOrders is the resolver for the newOrders field.
+func (r *subscriptionResolver) NewOrders(ctx context.Context) (<-chan *models.Order, error) {
+	if _, err := pkg.RequireStaff(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.Resolver.orderFeed(ctx, newOrdersTopic, nil)
+}
+
+// Customer returns graph.CustomerResolver implementation.
+func (r *Resolver) Customer() graph.CustomerResolver { return &customerResolver{r} }
+
 // Mutation returns graph.MutationResolver implementation.
 func (r *Resolver) Mutation() graph.MutationResolver { return &mutationResolver{r} }
 
+// Product returns graph.ProductResolver implementation.
+func (r *Resolver) Product() graph.ProductResolver { return &productResolver{r} }
+
 // Query returns graph.QueryResolver implementation.
 func (r *Resolver) Query() graph.QueryResolver { return &queryResolver{r} }
 
+// Subscription returns graph.SubscriptionResolver implementation.
+func (r *Resolver) Subscription() graph.SubscriptionResolver { return &subscriptionResolver{r} }
+
+type customerResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type productResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
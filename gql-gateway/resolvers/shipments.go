@@ -0,0 +1,112 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/models"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	rootModels "github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func shipmentFromInput(input models.ShipmentInput) (*rootModels.Shipment, error) {
+	orderID, err := strconv.Atoi(input.OrderID)
+	if err != nil {
+		return nil, invalidID("order", input.OrderID)
+	}
+
+	s := &rootModels.Shipment{
+		OrderID:        orderID,
+		Carrier:        strings.TrimSpace(input.Carrier),
+		TrackingNumber: trimOptional(input.TrackingNumber),
+	}
+	if s.Carrier == "" {
+		return nil, repo.Validation("input.carrier", "carrier is required")
+	}
+
+	for _, item := range input.Items {
+		orderItemID, err := strconv.Atoi(item.OrderItemID)
+		if err != nil {
+			return nil, invalidID("order item", item.OrderItemID)
+		}
+		if item.Quantity <= 0 {
+			return nil, repo.Validation("input.items", "quantity must be positive for order item %d", orderItemID)
+		}
+		s.Items = append(s.Items, rootModels.ShipmentItem{OrderItemID: orderItemID, Quantity: item.Quantity})
+	}
+	return s, nil
+}
+
+// toGQLShipment maps a shipment, pointing its lines at the order's items
+func toGQLShipment(s rootModels.Shipment, orderItems []*models.OrderItem) *models.Shipment {
+	byID := make(map[string]*models.OrderItem, len(orderItems))
+	for _, item := range orderItems {
+		byID[item.ID] = item
+	}
+
+	items := []*models.ShipmentItem{}
+	for _, item := range s.Items {
+		orderItem, ok := byID[strconv.Itoa(item.OrderItemID)]
+		if !ok {
+			orderItem = &models.OrderItem{ID: strconv.Itoa(item.OrderItemID)}
+		}
+		items = append(items, &models.ShipmentItem{OrderItem: orderItem, Quantity: item.Quantity})
+	}
+
+	gqlShipment := &models.Shipment{
+		ID:             strconv.Itoa(s.ID),
+		Carrier:        s.Carrier,
+		TrackingNumber: s.TrackingNumber,
+		ShippedAt:      s.ShippedAt.Format(time.RFC3339),
+		Items:          items,
+	}
+	if s.DeliveredAt != nil {
+		deliveredAt := s.DeliveredAt.Format(time.RFC3339)
+		gqlShipment.DeliveredAt = &deliveredAt
+	}
+	return gqlShipment
+}
+
+// shipmentFromOrder loads the order and picks the shipment out of it, so the
+// shipment's lines come with their order items
+func (r *Resolver) shipmentFromOrder(ctx context.Context, orderID, shipmentID int) (*models.Shipment, error) {
+	order, err := r.OrderRepo.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	gqlOrder, err := r.loadOrder(ctx, *order)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range gqlOrder.Shipments {
+		if s.ID == strconv.Itoa(shipmentID) {
+			return s, nil
+		}
+	}
+	return nil, repo.NotFound("shipment %d not found on order %d", shipmentID, orderID)
+}
+
+// notifyShipmentDispatched texts the customer that a shipment is on its way.
+// Failures are only logged, the shipment has already been recorded.
+func (r *Resolver) notifyShipmentDispatched(ctx context.Context, s rootModels.Shipment) {
+	order, err := r.OrderRepo.GetOrder(ctx, s.OrderID)
+	if err != nil {
+		log.Printf("failed to load order %d for dispatch SMS: %v", s.OrderID, err)
+		return
+	}
+	customer, err := r.CustomerRepo.GetCustomerById(ctx, order.CustomerID)
+	if err != nil {
+		log.Printf("failed to load customer %d for dispatch SMS: %v", order.CustomerID, err)
+		return
+	}
+
+	fullName := fmt.Sprintf("%s %s", customer.FirstName, customer.LastName)
+	if err := r.SMS.SendShipmentDispatchedSMS(customer.Phone, fullName, order.Number, s.Carrier, s.TrackingNumber); err != nil {
+		log.Printf("failed to send SMS: %v", err)
+	}
+}
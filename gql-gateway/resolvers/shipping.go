@@ -0,0 +1,285 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/models"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/shipping"
+	rootModels "github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+// orderItemsFromInput converts the GraphQL order lines for the repos
+func orderItemsFromInput(items []*models.OrderItemInput) ([]rootModels.OrderItemInput, error) {
+	if err := validateOrderItems(items); err != nil {
+		return nil, err
+	}
+
+	result := make([]rootModels.OrderItemInput, 0, len(items))
+	for _, item := range items {
+		// the IDs were validated above
+		productID, _ := strconv.Atoi(item.ProductID)
+		var variantID *int
+		if item.VariantID != nil {
+			id, _ := strconv.Atoi(*item.VariantID)
+			variantID = &id
+		}
+		result = append(result, rootModels.OrderItemInput{
+			ProductID: productID,
+			VariantID: variantID,
+			Quantity:  item.Quantity,
+		})
+	}
+	return result, nil
+}
+
+// shippingWeight is the chargeable weight of the order lines
+func (r *Resolver) shippingWeight(ctx context.Context, items []rootModels.OrderItemInput) (float64, error) {
+	ids := make([]int, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ProductID)
+	}
+
+	products, err := r.ProductRepo.GetProductsByIDs(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+	byID := make(map[int]rootModels.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+
+	parcels := make([]shipping.Parcel, 0, len(items))
+	for _, item := range items {
+		parcels = append(parcels, shipping.Parcel{Product: byID[item.ProductID], Quantity: item.Quantity})
+	}
+	return shipping.ChargeableWeight(parcels), nil
+}
+
+// shippingQuotes prices every method that can deliver the items to the address
+func (r *Resolver) shippingQuotes(ctx context.Context, address rootModels.Address, items []rootModels.OrderItemInput) ([]rootModels.ShippingQuote, error) {
+	weight, err := r.shippingWeight(ctx, items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load order products: %w", err)
+	}
+	table, err := r.ShippingRepo.LoadTable(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load shipping rates: %w", err)
+	}
+	return shipping.Quotes(*table, address, weight), nil
+}
+
+// orderShipping prices the shipping method chosen for a new order
+func (r *Resolver) orderShipping(ctx context.Context, methodID *string, address rootModels.Address, items []rootModels.OrderItemInput) (*rootModels.ShippingQuote, error) {
+	if methodID == nil {
+		return nil, repo.Validation("input.shippingMethodID", "a shipping method is required, see availableShippingMethods")
+	}
+	id, err := strconv.Atoi(*methodID)
+	if err != nil {
+		return nil, invalidID("shipping method", *methodID)
+	}
+
+	weight, err := r.shippingWeight(ctx, items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load order products: %w", err)
+	}
+	table, err := r.ShippingRepo.LoadTable(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load shipping rates: %w", err)
+	}
+
+	quote, err := shipping.Quote(*table, id, address, weight)
+	if errors.Is(err, shipping.ErrUnavailable) {
+		return nil, repo.Validation("input.shippingMethodID", "shipping method %d is not available for %s, %s", id, address.City, address.Country)
+	}
+	return quote, err
+}
+
+// quoteTarget works out the items and address a shipping quote is for, either
+// from an existing order or from a cart
+func (r *Resolver) quoteTarget(ctx context.Context, input models.ShippingQuoteInput) (*rootModels.Address, []rootModels.OrderItemInput, error) {
+	if input.OrderID != nil {
+		if input.Items != nil || input.AddressID != nil || input.ShippingAddress != nil {
+			return nil, nil, repo.Validation("input", "give either orderID or a cart, not both")
+		}
+
+		orderID, err := strconv.Atoi(*input.OrderID)
+		if err != nil {
+			return nil, nil, invalidID("order", *input.OrderID)
+		}
+		order, err := r.OrderRepo.GetOrder(ctx, orderID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := r.authorizeCustomer(ctx, order.CustomerID); err != nil {
+			return nil, nil, err
+		}
+		if order.ShippingAddress == nil {
+			return nil, nil, repo.Conflict("order %d has no shipping address", orderID)
+		}
+
+		orderItems, err := r.OrderItemRepo.GetItemsByOrder(ctx, orderID)
+		if err != nil {
+			return nil, nil, err
+		}
+		items := make([]rootModels.OrderItemInput, 0, len(orderItems))
+		for _, item := range orderItems {
+			items = append(items, rootModels.OrderItemInput{
+				ProductID: item.ProductID,
+				VariantID: item.VariantID,
+				Quantity:  item.Quantity,
+				Price:     item.Price,
+			})
+		}
+		return order.ShippingAddress, items, nil
+	}
+
+	if len(input.Items) == 0 {
+		return nil, nil, repo.Validation("input", "give an orderID or at least one cart item")
+	}
+	items, err := orderItemsFromInput(input.Items)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// saved addresses belong to the caller, inline ones need no account
+	customerID := 0
+	if input.AddressID != nil {
+		if customerID, err = r.currentCustomerID(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+	address, err := r.orderShippingAddress(ctx, customerID, models.OrderInput{
+		AddressID:       input.AddressID,
+		ShippingAddress: input.ShippingAddress,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return address, items, nil
+}
+
+func shippingMethodFromInput(input models.ShippingMethodInput) (*rootModels.ShippingMethod, error) {
+	m := &rootModels.ShippingMethod{
+		Code:        strings.ToLower(strings.TrimSpace(input.Code)),
+		Name:        strings.TrimSpace(input.Name),
+		Description: trimOptional(input.Description),
+		Active:      input.Active == nil || *input.Active,
+	}
+	if m.Code == "" || m.Name == "" {
+		return nil, repo.Validation("input", "shipping method code and name are required")
+	}
+	return m, nil
+}
+
+func shippingZoneFromInput(input models.ShippingZoneInput) (*rootModels.ShippingZone, error) {
+	z := &rootModels.ShippingZone{
+		Name:      strings.TrimSpace(input.Name),
+		Countries: []string{},
+		Regions:   []string{},
+	}
+	if z.Name == "" {
+		return nil, repo.Validation("input.name", "shipping zone name is required")
+	}
+	for _, c := range input.Countries {
+		c = strings.ToUpper(strings.TrimSpace(c))
+		if len(c) != 2 {
+			return nil, repo.Validation("input.countries", "countries must be two-letter ISO 3166 codes, got %q", c)
+		}
+		z.Countries = append(z.Countries, c)
+	}
+	if len(z.Countries) == 0 {
+		return nil, repo.Validation("input.countries", "a shipping zone needs at least one country")
+	}
+	for _, region := range input.Regions {
+		if region = strings.ToLower(strings.TrimSpace(region)); region != "" {
+			z.Regions = append(z.Regions, region)
+		}
+	}
+	return z, nil
+}
+
+func shippingRateFromInput(input models.ShippingRateInput) (*rootModels.ShippingRate, error) {
+	methodID, err := strconv.Atoi(input.MethodID)
+	if err != nil {
+		return nil, invalidID("shipping method", input.MethodID)
+	}
+	zoneID, err := strconv.Atoi(input.ZoneID)
+	if err != nil {
+		return nil, invalidID("shipping zone", input.ZoneID)
+	}
+
+	rate := &rootModels.ShippingRate{
+		MethodID:    methodID,
+		ZoneID:      zoneID,
+		MaxWeightKg: input.MaxWeightKg,
+		Price:       input.Price,
+	}
+	if input.MinWeightKg != nil {
+		rate.MinWeightKg = *input.MinWeightKg
+	}
+	switch {
+	case rate.Price < 0:
+		return nil, repo.Validation("input.price", "price cannot be negative")
+	case rate.MinWeightKg < 0:
+		return nil, repo.Validation("input.minWeightKg", "minWeightKg cannot be negative")
+	case rate.MaxWeightKg != nil && *rate.MaxWeightKg <= rate.MinWeightKg:
+		return nil, repo.Validation("input.maxWeightKg", "maxWeightKg must be greater than minWeightKg")
+	}
+	return rate, nil
+}
+
+func toGQLShippingMethod(m rootModels.ShippingMethod) *models.ShippingMethod {
+	return &models.ShippingMethod{
+		ID:          strconv.Itoa(m.ID),
+		Code:        m.Code,
+		Name:        m.Name,
+		Description: m.Description,
+		Active:      m.Active,
+	}
+}
+
+func toGQLShippingZone(z rootModels.ShippingZone) *models.ShippingZone {
+	return &models.ShippingZone{
+		ID:        strconv.Itoa(z.ID),
+		Name:      z.Name,
+		Countries: z.Countries,
+		Regions:   z.Regions,
+	}
+}
+
+func toGQLShippingQuote(q rootModels.ShippingQuote) *models.ShippingQuote {
+	return &models.ShippingQuote{
+		Method:   toGQLShippingMethod(q.Method),
+		Zone:     q.Zone.Name,
+		WeightKg: q.WeightKg,
+		Cost:     q.Cost,
+	}
+}
+
+// toGQLShippingRate maps a rate, looking its method and zone up in the table
+func toGQLShippingRate(rate rootModels.ShippingRate, table *shipping.Table) *models.ShippingRate {
+	gqlRate := &models.ShippingRate{
+		ID:          strconv.Itoa(rate.ID),
+		Method:      &models.ShippingMethod{ID: strconv.Itoa(rate.MethodID)},
+		Zone:        &models.ShippingZone{ID: strconv.Itoa(rate.ZoneID)},
+		MinWeightKg: rate.MinWeightKg,
+		MaxWeightKg: rate.MaxWeightKg,
+		Price:       rate.Price,
+	}
+	for _, m := range table.Methods {
+		if m.ID == rate.MethodID {
+			gqlRate.Method = toGQLShippingMethod(m)
+		}
+	}
+	for _, z := range table.Zones {
+		if z.ID == rate.ZoneID {
+			gqlRate.Zone = toGQLShippingZone(z)
+		}
+	}
+	return gqlRate
+}
@@ -0,0 +1,64 @@
+package resolvers
+
+import (
+	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/models"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/validate"
+)
+
+// length limits for names and free text, generous enough for real data
+const (
+	maxNameLength = 100
+	maxSKULength  = 64
+	// Auth0's default password policy
+	minPasswordLength = 8
+)
+
+func validateRegisterInput(input models.RegisterInput) error {
+	v := validate.New("input")
+	validate.Field(v, "firstName", input.FirstName, validate.Required(), validate.MaxLength(maxNameLength))
+	validate.Field(v, "lastName", input.LastName, validate.Required(), validate.MaxLength(maxNameLength))
+	validate.Field(v, "email", input.Email, validate.Required(), validate.Email())
+	validate.Field(v, "phone", input.Phone, validate.Required(), validate.Phone())
+	validate.Field(v, "password", input.Password, validate.Required(), validate.MinLength(minPasswordLength))
+	return v.Err()
+}
+
+func validateCategoryInput(input models.CategoryInput) error {
+	v := validate.New("input")
+	validate.Field(v, "name", input.Name, validate.Required(), validate.MaxLength(maxNameLength))
+	validate.Optional(v, "parentID", input.ParentID, validate.ID())
+	return v.Err()
+}
+
+func validateProductInput(input models.ProductInput) error {
+	v := validate.New("input")
+	validate.Optional(v, "sku", input.Sku, validate.Required(), validate.MaxLength(maxSKULength))
+	validate.Field(v, "name", input.Name, validate.Required(), validate.MaxLength(maxNameLength))
+	validate.Field(v, "price", input.Price, validate.Min(0.0))
+	validate.Optional(v, "categoryID", input.CategoryID, validate.ID())
+	// dimensions are optional, but a parcel can't weigh or measure nothing
+	validate.Optional(v, "weightKg", input.WeightKg, positive)
+	validate.Optional(v, "lengthCm", input.LengthCm, positive)
+	validate.Optional(v, "widthCm", input.WidthCm, positive)
+	validate.Optional(v, "heightCm", input.HeightCm, positive)
+	return v.Err()
+}
+
+// validateOrderItems checks the order lines of an input's items field
+func validateOrderItems(items []*models.OrderItemInput) error {
+	v := validate.New("input.items")
+	for i, item := range items {
+		line := v.Index(i)
+		validate.Field(line, "productID", item.ProductID, validate.ID())
+		validate.Optional(line, "variantID", item.VariantID, validate.ID())
+		validate.Field(line, "quantity", item.Quantity, validate.Min(1))
+	}
+	return v.Err()
+}
+
+func positive(value float64) string {
+	if value <= 0 {
+		return "must be greater than 0"
+	}
+	return ""
+}
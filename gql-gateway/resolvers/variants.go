@@ -0,0 +1,24 @@
+package resolvers
+
+import (
+	"strconv"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/models"
+	rootModels "github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func toGQLVariant(v rootModels.ProductVariant) *models.ProductVariant {
+	options := []*models.VariantOption{}
+	for _, opt := range v.Options {
+		options = append(options, &models.VariantOption{Name: opt.Name, Value: opt.Value})
+	}
+
+	return &models.ProductVariant{
+		ID:            strconv.Itoa(v.ID),
+		Sku:           v.SKU,
+		Price:         v.UnitPrice,
+		PriceOverride: v.Price,
+		Stock:         v.Stock,
+		Options:       options,
+	}
+}
@@ -0,0 +1,71 @@
+package resolvers
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/models"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	rootModels "github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func webhookSubscriptionFromInput(input models.WebhookSubscriptionInput) (*rootModels.WebhookSubscription, error) {
+	u, err := url.Parse(input.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, repo.Validation("input.url", "webhook URL must be an absolute http or https URL")
+	}
+	if len(input.EventTypes) == 0 {
+		return nil, repo.Validation("input.eventTypes", "at least one event type is required")
+	}
+	for _, event := range input.EventTypes {
+		if !slices.Contains(rootModels.WebhookEventTypes, event) {
+			return nil, repo.Validation("input.eventTypes", "unknown webhook event type %q", event)
+		}
+	}
+
+	s := &rootModels.WebhookSubscription{
+		URL:        input.URL,
+		EventTypes: slices.Compact(slices.Sorted(slices.Values(input.EventTypes))),
+	}
+	if input.Secret != nil {
+		s.Secret = *input.Secret
+	}
+	return s, nil
+}
+
+func toGQLWebhookSubscription(s rootModels.WebhookSubscription) *models.WebhookSubscription {
+	return &models.WebhookSubscription{
+		ID:         strconv.Itoa(s.ID),
+		URL:        s.URL,
+		Secret:     s.Secret,
+		EventTypes: s.EventTypes,
+		Active:     s.Active,
+		CreatedAt:  s.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func toGQLWebhookDelivery(d rootModels.WebhookDelivery) *models.WebhookDelivery {
+	gqlDelivery := &models.WebhookDelivery{
+		ID:             strconv.Itoa(d.ID),
+		SubscriptionID: strconv.Itoa(d.SubscriptionID),
+		EventType:      d.EventType,
+		Payload:        string(d.Payload),
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt.Format(time.RFC3339),
+	}
+	// only pending deliveries have another attempt coming
+	if d.Status == rootModels.WebhookDeliveryPending && d.NextAttemptAt != nil {
+		nextAttemptAt := d.NextAttemptAt.Format(time.RFC3339)
+		gqlDelivery.NextAttemptAt = &nextAttemptAt
+	}
+	if d.DeliveredAt != nil {
+		deliveredAt := d.DeliveredAt.Format(time.RFC3339)
+		gqlDelivery.DeliveredAt = &deliveredAt
+	}
+	return gqlDelivery
+}
@@ -0,0 +1,104 @@
+package resolvers
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/models"
+	rootModels "github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/godfreyowidi/simple-ecomm-demo/pkg"
+)
+
+// ownWishlist loads a wishlist the caller is allowed to change
+func (r *Resolver) ownWishlist(ctx context.Context, wishlistID string) (*rootModels.Wishlist, error) {
+	id, err := strconv.Atoi(wishlistID)
+	if err != nil {
+		return nil, invalidID("wishlist", wishlistID)
+	}
+
+	w, err := r.WishlistRepo.GetWishlist(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.authorizeCustomer(ctx, w.CustomerID); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// loadWishlist converts a wishlist, loading the products and variants on it
+func (r *Resolver) loadWishlist(ctx context.Context, w rootModels.Wishlist) (*models.Wishlist, error) {
+	var productIDs, variantIDs []int
+	for _, item := range w.Items {
+		productIDs = append(productIDs, item.ProductID)
+		if item.VariantID != nil {
+			variantIDs = append(variantIDs, *item.VariantID)
+		}
+	}
+
+	products := map[int]rootModels.Product{}
+	if len(productIDs) > 0 {
+		found, err := r.ProductRepo.GetProductsByIDs(ctx, productIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range found {
+			products[p.ID] = p
+		}
+	}
+	variants := map[int]rootModels.ProductVariant{}
+	if len(variantIDs) > 0 {
+		found, err := r.VariantRepo.GetVariantsByIDs(ctx, variantIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range found {
+			variants[v.ID] = v
+		}
+	}
+
+	gqlItems := []*models.WishlistItem{}
+	for _, item := range w.Items {
+		gqlItem := &models.WishlistItem{
+			Product: toGQLProduct(products[item.ProductID]),
+			Notify:  item.Notify,
+			AddedAt: item.AddedAt.Format(time.RFC3339),
+		}
+		if item.VariantID != nil {
+			gqlItem.Variant = toGQLVariant(variants[*item.VariantID])
+		}
+		gqlItems = append(gqlItems, gqlItem)
+	}
+
+	return &models.Wishlist{
+		ID:         strconv.Itoa(w.ID),
+		Name:       w.Name,
+		ShareToken: w.ShareToken,
+		Items:      gqlItems,
+		CreatedAt:  w.CreatedAt.Format(time.RFC3339),
+	}, nil
+}
+
+// notifyWishlists texts customers watching the given products that one is
+// back in stock or cheaper
+func (r *Resolver) notifyWishlists(ctx context.Context, productIDs []int) {
+	if r.WishlistRepo == nil || len(productIDs) == 0 {
+		return
+	}
+	pkg.SendWishlistAlerts(ctx, r.WishlistRepo, r.SMS, productIDs)
+}
+
+// orderProductIDs lists the products on an order, for wishlist alerts after
+// its stock is released
+func (r *Resolver) orderProductIDs(ctx context.Context, orderID int) []int {
+	items, err := r.OrderItemRepo.GetItemsByOrder(ctx, orderID)
+	if err != nil {
+		return nil
+	}
+	var ids []int
+	for _, item := range items {
+		ids = append(ids, item.ProductID)
+	}
+	return ids
+}
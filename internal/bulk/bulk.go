@@ -0,0 +1,370 @@
+// Package bulk imports and exports products as CSV or JSON Lines files.
+//
+// CSV files start with a header row naming the columns id, sku, name,
+// description, price, category, weight_kg, length_cm, width_cm and height_cm
+// (in any order). JSON Lines files hold one object per line with the same
+// keys. category is a path from the top of the category tree, e.g.
+// "Electronics/Phones".
+//
+// Rows are matched to products by sku. Products without a SKU are matched by
+// id instead, so an export can always be imported back. A blank weight or
+// dimension leaves the product's current value alone.
+package bulk
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+var csvColumns = []string{"id", "sku", "name", "description", "price", "category", "weight_kg", "length_cm", "width_cm", "height_cm"}
+
+// ParseFormat normalizes a format name, accepting a few common aliases
+func ParseFormat(name string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "csv", "text/csv":
+		return FormatCSV, nil
+	case "jsonl", "ndjson", "json", "application/x-ndjson", "application/jsonl":
+		return FormatJSONL, nil
+	}
+	return "", fmt.Errorf("unsupported format %q, use csv or jsonl", name)
+}
+
+// Row is one product read from an import file. Err is set when the row can't
+// be imported; reading carries on with the next row.
+type Row struct {
+	Line   int
+	Record models.ProductRecord
+	Err    error
+}
+
+// Reader streams rows out of an import file
+type Reader interface {
+	// Next returns io.EOF after the last row
+	Next() (Row, error)
+}
+
+// NewReader reads rows in the given format from r
+func NewReader(r io.Reader, format string) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatJSONL:
+		return &jsonlReader{scanner: newLineScanner(r)}, nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+type csvReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("empty file, expected a header row")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"name", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("header is missing the %q column", required)
+		}
+	}
+	_, hasSKU := columns["sku"]
+	_, hasID := columns["id"]
+	if !hasSKU && !hasID {
+		return nil, errors.New(`header is missing the "sku" column`)
+	}
+	return &csvReader{r: cr, columns: columns}, nil
+}
+
+func (c *csvReader) Next() (Row, error) {
+	fields, err := c.r.Read()
+	if err == io.EOF {
+		return Row{}, io.EOF
+	}
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return Row{Line: parseErr.StartLine, Err: parseErr.Err}, nil
+	}
+	if err != nil {
+		return Row{}, err
+	}
+
+	line, _ := c.r.FieldPos(0)
+	field := func(name string) string {
+		i, ok := c.columns[name]
+		if !ok || i >= len(fields) {
+			return ""
+		}
+		return strings.TrimSpace(fields[i])
+	}
+
+	row := Row{Line: line}
+	row.Record.SKU = field("sku")
+	row.Record.Name = field("name")
+	row.Record.CategoryPath = field("category")
+	if description := field("description"); description != "" {
+		row.Record.Description = &description
+	}
+
+	if id := field("id"); id != "" {
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			row.Err = fmt.Errorf("invalid id %q", id)
+			return row, nil
+		}
+		row.Record.ID = n
+	}
+
+	price, err := strconv.ParseFloat(field("price"), 64)
+	if err != nil {
+		row.Err = fmt.Errorf("invalid price %q", field("price"))
+		return row, nil
+	}
+	row.Record.Price = price
+
+	for _, measure := range []struct {
+		column string
+		dst    **float64
+	}{
+		{"weight_kg", &row.Record.WeightKg},
+		{"length_cm", &row.Record.LengthCm},
+		{"width_cm", &row.Record.WidthCm},
+		{"height_cm", &row.Record.HeightCm},
+	} {
+		value := field(measure.column)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			row.Err = fmt.Errorf("invalid %s %q", measure.column, value)
+			return row, nil
+		}
+		*measure.dst = &n
+	}
+
+	row.Err = validate(row.Record)
+	return row, nil
+}
+
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// newLineScanner allows lines well beyond bufio's 64KB default
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	return s
+}
+
+func (j *jsonlReader) Next() (Row, error) {
+	for j.scanner.Scan() {
+		j.line++
+		text := strings.TrimSpace(j.scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		row := Row{Line: j.line}
+		dec := json.NewDecoder(strings.NewReader(text))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&row.Record); err != nil {
+			row.Err = fmt.Errorf("invalid JSON: %w", err)
+			return row, nil
+		}
+
+		row.Record.SKU = strings.TrimSpace(row.Record.SKU)
+		row.Record.Name = strings.TrimSpace(row.Record.Name)
+		row.Err = validate(row.Record)
+		return row, nil
+	}
+	if err := j.scanner.Err(); err != nil {
+		return Row{}, err
+	}
+	return Row{}, io.EOF
+}
+
+func validate(rec models.ProductRecord) error {
+	switch {
+	case rec.SKU == "" && rec.ID == 0:
+		return errors.New("sku is required")
+	case rec.Name == "":
+		return errors.New("name is required")
+	case rec.Price < 0:
+		return errors.New("price cannot be negative")
+	}
+	for _, measure := range []*float64{rec.WeightKg, rec.LengthCm, rec.WidthCm, rec.HeightCm} {
+		if measure != nil && *measure < 0 {
+			return errors.New("weight and dimensions cannot be negative")
+		}
+	}
+	return nil
+}
+
+// RowError explains why a row of an import file was rejected
+type RowError struct {
+	Line    int    `json:"line"`
+	SKU     string `json:"sku,omitempty"`
+	Message string `json:"message"`
+}
+
+// Report summarizes an import. Nothing is written unless Committed is true.
+type Report struct {
+	DryRun    bool       `json:"dryRun"`
+	Committed bool       `json:"committed"`
+	Rows      int        `json:"rows"`
+	Created   int        `json:"created"`
+	Updated   int        `json:"updated"`
+	Failed    int        `json:"failed"`
+	Errors    []RowError `json:"errors"`
+}
+
+// Import upserts every row of the file in one transaction. The import is
+// all-or-nothing: it only commits when every row succeeded and dryRun is
+// false, otherwise it rolls back and the report lists the rows that failed.
+func Import(ctx context.Context, products *repo.ProductRepo, r io.Reader, format string, dryRun bool) (*Report, error) {
+	reader, err := NewReader(r, format)
+	if err != nil {
+		return nil, err
+	}
+
+	imp, err := products.BeginImport(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer imp.Rollback(ctx)
+
+	report := &Report{DryRun: dryRun, Errors: []RowError{}}
+	seen := map[string]int{}
+
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read import file: %w", err)
+		}
+		report.Rows++
+
+		if row.Err == nil {
+			key, what := "sku:"+row.Record.SKU, "sku"
+			if row.Record.SKU == "" {
+				key, what = "id:"+strconv.Itoa(row.Record.ID), "id"
+			}
+			if first, ok := seen[key]; ok {
+				row.Err = fmt.Errorf("duplicate %s, first seen on line %d", what, first)
+			} else {
+				seen[key] = row.Line
+			}
+		}
+		if row.Err == nil {
+			created, err := imp.Upsert(ctx, row.Record)
+			switch {
+			case err != nil:
+				row.Err = err
+			case created:
+				report.Created++
+			default:
+				report.Updated++
+			}
+		}
+
+		if row.Err != nil {
+			report.Failed++
+			report.Errors = append(report.Errors, RowError{
+				Line:    row.Line,
+				SKU:     row.Record.SKU,
+				Message: row.Err.Error(),
+			})
+		}
+	}
+
+	if dryRun || report.Failed > 0 {
+		return report, nil
+	}
+	if err := imp.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit import: %w", err)
+	}
+	report.Committed = true
+	return report, nil
+}
+
+// Export writes every product to w in the given format
+func Export(ctx context.Context, products *repo.ProductRepo, w io.Writer, format string) error {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvColumns); err != nil {
+			return err
+		}
+		err := products.ExportProducts(ctx, func(rec models.ProductRecord) error {
+			description := ""
+			if rec.Description != nil {
+				description = *rec.Description
+			}
+			return cw.Write([]string{
+				strconv.Itoa(rec.ID),
+				rec.SKU,
+				rec.Name,
+				description,
+				strconv.FormatFloat(rec.Price, 'f', 2, 64),
+				rec.CategoryPath,
+				formatMeasure(rec.WeightKg),
+				formatMeasure(rec.LengthCm),
+				formatMeasure(rec.WidthCm),
+				formatMeasure(rec.HeightCm),
+			})
+		})
+		if err != nil {
+			return err
+		}
+		cw.Flush()
+		return cw.Error()
+	case FormatJSONL:
+		enc := json.NewEncoder(w)
+		return products.ExportProducts(ctx, func(rec models.ProductRecord) error {
+			return enc.Encode(rec)
+		})
+	}
+	return fmt.Errorf("unsupported format %q", format)
+}
+
+// formatMeasure writes a weight or dimension, blank when it isn't set
+func formatMeasure(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
@@ -0,0 +1,141 @@
+package bulk_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/bulk"
+)
+
+func readAll(t *testing.T, input, format string) []bulk.Row {
+	t.Helper()
+	reader, err := bulk.NewReader(strings.NewReader(input), format)
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+
+	var rows []bulk.Row
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			return rows
+		}
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	input := "Name,SKU,Price,Category,Description\n" +
+		"Phone,PH-1,199.99,Electronics/Phones,A phone\n" +
+		"Case,CS-1,abc,,\n" +
+		",NO-NAME,5,,\n" +
+		"\"Cable, USB\",CB-1,4.5,,\n"
+
+	rows := readAll(t, input, bulk.FormatCSV)
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 rows, got %d", len(rows))
+	}
+
+	phone := rows[0]
+	if phone.Err != nil {
+		t.Fatalf("unexpected error on first row: %v", phone.Err)
+	}
+	if phone.Line != 2 || phone.Record.SKU != "PH-1" || phone.Record.Price != 199.99 ||
+		phone.Record.CategoryPath != "Electronics/Phones" || phone.Record.Description == nil {
+		t.Errorf("unexpected first row: %+v", phone)
+	}
+
+	if rows[1].Err == nil || rows[1].Line != 3 {
+		t.Errorf("expected an invalid price error on line 3, got %+v", rows[1])
+	}
+	if rows[2].Err == nil {
+		t.Error("expected a missing name error")
+	}
+	if rows[3].Err != nil || rows[3].Record.Name != "Cable, USB" || rows[3].Record.Description != nil {
+		t.Errorf("unexpected quoted row: %+v", rows[3])
+	}
+}
+
+func TestCSVReaderRequiresHeader(t *testing.T) {
+	if _, err := bulk.NewReader(strings.NewReader("name,price\nPhone,1\n"), bulk.FormatCSV); err == nil {
+		t.Error("expected an error for a header without sku")
+	}
+	if _, err := bulk.NewReader(strings.NewReader(""), bulk.FormatCSV); err == nil {
+		t.Error("expected an error for an empty file")
+	}
+}
+
+func TestCSVReaderMatchesByIDAndReadsDimensions(t *testing.T) {
+	input := "id,sku,name,price,weight_kg,length_cm,width_cm,height_cm\n" +
+		"7,,Desk,250,12.5,120,60,75\n" +
+		",,Chair,80,,,,\n" +
+		"8,,Lamp,20,heavy,,,\n"
+
+	rows := readAll(t, input, bulk.FormatCSV)
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(rows))
+	}
+
+	desk := rows[0].Record
+	if rows[0].Err != nil || desk.ID != 7 || desk.SKU != "" || desk.WeightKg == nil || *desk.WeightKg != 12.5 ||
+		desk.HeightCm == nil || *desk.HeightCm != 75 {
+		t.Errorf("unexpected first row: %+v, %v", desk, rows[0].Err)
+	}
+	if rows[1].Err == nil {
+		t.Error("expected a row without a sku or id to be rejected")
+	}
+	if rows[2].Err == nil {
+		t.Error("expected an invalid weight error")
+	}
+}
+
+func TestJSONLReader(t *testing.T) {
+	input := `{"sku":"PH-1","name":"Phone","price":199.99,"category":"Electronics/Phones"}
+
+{"sku":"PH-2","name":"Phone 2","price":-1}
+not json
+{"sku":"PH-3","name":"Phone 3","price":10,"colour":"red"}
+`
+	rows := readAll(t, input, bulk.FormatJSONL)
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 rows, got %d", len(rows))
+	}
+
+	if rows[0].Err != nil || rows[0].Record.CategoryPath != "Electronics/Phones" {
+		t.Errorf("unexpected first row: %+v", rows[0])
+	}
+	// blank lines are skipped but still counted
+	if rows[1].Line != 3 || rows[1].Err == nil {
+		t.Errorf("expected a negative price error on line 3, got %+v", rows[1])
+	}
+	if rows[2].Err == nil {
+		t.Error("expected an invalid JSON error")
+	}
+	if rows[3].Err == nil {
+		t.Error("expected unknown fields to be rejected")
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	cases := map[string]string{
+		"csv":                  bulk.FormatCSV,
+		"CSV":                  bulk.FormatCSV,
+		"text/csv":             bulk.FormatCSV,
+		"jsonl":                bulk.FormatJSONL,
+		"ndjson":               bulk.FormatJSONL,
+		"application/x-ndjson": bulk.FormatJSONL,
+	}
+	for in, want := range cases {
+		got, err := bulk.ParseFormat(in)
+		if err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := bulk.ParseFormat("xml"); err == nil {
+		t.Error("expected an error for xml")
+	}
+}
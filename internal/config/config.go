@@ -0,0 +1,350 @@
+// Package config loads the app's settings once at startup and checks them
+// before anything runs. Each setting is taken from, in rising priority:
+//
+//   - the defaults in Default
+//   - the profile for APP_ENV (development, staging or production), built
+//     into the binary from profiles/<env>.yaml
+//   - the YAML file named by CONFIG_FILE, or config.yaml if there is one
+//   - environment variables, including those in a .env file
+//
+// YAML keys follow the struct fields' yaml tags and environment variables
+// their env tags, so GRAPHQL_MAX_DEPTH=8 and `graphql: {max_depth: 8}` are
+// the same setting.
+package config
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/ratelimit"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Environments a profile exists for
+const (
+	Development = "development"
+	Staging     = "staging"
+	Production  = "production"
+)
+
+var environments = []string{Development, Staging, Production}
+
+// Config is every setting the app reads
+type Config struct {
+	// Env picks the profile, it can only be set with APP_ENV
+	Env              string `yaml:"-" env:"APP_ENV"`
+	Port             string `yaml:"port" env:"PORT"`
+	DatabaseURL      string `yaml:"database_url" env:"DATABASE_URL"`
+	ReturnWindowDays int    `yaml:"return_window_days" env:"RETURN_WINDOW_DAYS"`
+
+	Server    Server    `yaml:"server"`
+	Auth0     Auth0     `yaml:"auth0"`
+	SMS       SMS       `yaml:"sms"`
+	Storage   Storage   `yaml:"storage"`
+	Invoice   Invoice   `yaml:"invoice"`
+	GraphQL   GraphQL   `yaml:"graphql"`
+	RateLimit RateLimit `yaml:"rate_limit"`
+}
+
+// Server holds the HTTP server's limits and how long it gets to shut down
+type Server struct {
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	// MaxBodyBytes caps GraphQL request bodies, uploads have limits of their own
+	MaxBodyBytes int64 `yaml:"max_body_bytes" env:"SERVER_MAX_BODY_BYTES"`
+	// MaxImportBytes caps product import files
+	MaxImportBytes int64 `yaml:"max_import_bytes" env:"SERVER_MAX_IMPORT_BYTES"`
+	// ShutdownDelay is how long the server keeps taking requests after
+	// SIGTERM while reporting itself not ready, giving the load balancer time
+	// to stop sending it traffic
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" env:"SHUTDOWN_DELAY"`
+	// ShutdownTimeout is how long requests and background work then get to
+	// finish. With ShutdownDelay it should fit in Kubernetes'
+	// terminationGracePeriodSeconds, 30s by default.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+}
+
+// Auth0 holds the tenant that issues customer and staff tokens and the
+// applications the app signs users up and in with
+type Auth0 struct {
+	Domain string `yaml:"domain" env:"AUTH0_DOMAIN"`
+	// Audience is the API that access tokens must be issued for
+	Audience string `yaml:"audience" env:"AUTH0_AUDIENCE"`
+
+	// the machine to machine application that creates users
+	ClientID           string `yaml:"client_id" env:"AUTH0_CLIENT_ID"`
+	ClientSecret       string `yaml:"client_secret" env:"AUTH0_CLIENT_SECRET"`
+	ManagementAudience string `yaml:"management_audience" env:"AUTH0_MANAGEMENT_API_AUDIENCE"`
+
+	// the application customers log in through
+	LoginClientID     string `yaml:"login_client_id" env:"AUTH0_LOGIN_CLIENT_ID"`
+	LoginClientSecret string `yaml:"login_client_secret" env:"AUTH0_LOGIN_CLIENT_SECRET"`
+	LoginAudience     string `yaml:"login_audience" env:"AUTH0_API_AUDIENCE"`
+}
+
+// SMS holds the Africa's Talking account notifications are sent from. SMS
+// notifications are off until it is set.
+type SMS struct {
+	APIKey    string `yaml:"api_key" env:"AT_API_KEY"`
+	Username  string `yaml:"username" env:"AT_USERNAME"`
+	ShortCode string `yaml:"short_code" env:"AT_SHORTCODE"`
+	Sandbox   string `yaml:"sandbox" env:"AT_SANDBOX"`
+}
+
+// Enabled reports whether an account is set
+func (s SMS) Enabled() bool {
+	return s.APIKey != ""
+}
+
+// Storage picks where uploaded files are kept
+type Storage struct {
+	// Driver is "local" or "s3"
+	Driver       string `yaml:"driver" env:"STORAGE_DRIVER"`
+	MediaDir     string `yaml:"media_dir" env:"MEDIA_DIR"`
+	MediaBaseURL string `yaml:"media_base_url" env:"MEDIA_BASE_URL"`
+	S3           S3     `yaml:"s3"`
+}
+
+type S3 struct {
+	Endpoint  string `yaml:"endpoint" env:"S3_ENDPOINT"`
+	AccessKey string `yaml:"access_key" env:"S3_ACCESS_KEY"`
+	SecretKey string `yaml:"secret_key" env:"S3_SECRET_KEY"`
+	Bucket    string `yaml:"bucket" env:"S3_BUCKET"`
+	Region    string `yaml:"region" env:"S3_REGION"`
+	UseSSL    bool   `yaml:"use_ssl" env:"S3_USE_SSL"`
+	PublicURL string `yaml:"public_url" env:"S3_PUBLIC_URL"`
+}
+
+// Invoice holds what invoices say about the seller and how they charge tax
+type Invoice struct {
+	// TaxRate is included in prices, e.g. 0.16 for 16% VAT
+	TaxRate     float64 `yaml:"tax_rate" env:"INVOICE_TAX_RATE"`
+	Currency    string  `yaml:"currency" env:"INVOICE_CURRENCY"`
+	SellerName  string  `yaml:"seller_name" env:"INVOICE_SELLER_NAME"`
+	SellerTaxID string  `yaml:"seller_tax_id" env:"INVOICE_SELLER_TAX_ID"`
+	// SellerAddress is one entry per line, separated by ";" in the environment
+	SellerAddress []string `yaml:"seller_address" env:"INVOICE_SELLER_ADDRESS" sep:";"`
+}
+
+// GraphQL limits what queries clients may run
+type GraphQL struct {
+	MaxComplexity int  `yaml:"max_complexity" env:"GRAPHQL_MAX_COMPLEXITY"`
+	MaxDepth      int  `yaml:"max_depth" env:"GRAPHQL_MAX_DEPTH"`
+	Introspection bool `yaml:"introspection" env:"GRAPHQL_INTROSPECTION"`
+	// PersistedQueriesManifest is a JSON file of queries clients can run by hash
+	PersistedQueriesManifest string `yaml:"persisted_queries_manifest" env:"PERSISTED_QUERIES_MANIFEST"`
+	// PersistedQueriesStrict allows only the queries in the manifest
+	PersistedQueriesStrict bool `yaml:"persisted_queries_strict" env:"PERSISTED_QUERIES_STRICT"`
+}
+
+// RateLimit throttles clients of the GraphQL endpoints
+type RateLimit struct {
+	// Default is how many operations each client may run
+	Default ratelimit.Limit `yaml:"default" env:"RATE_LIMIT_DEFAULT"`
+	// Fields are stricter limits for single mutations. RATE_LIMITS adds to
+	// or changes these rather than replacing them all.
+	Fields ratelimit.Limits `yaml:"fields" env:"RATE_LIMITS"`
+	// Store is "memory", counting on each replica, or "postgres", counting
+	// across all of them
+	Store string `yaml:"store" env:"RATE_LIMIT_STORE"`
+	// TrustProxy takes client addresses from X-Forwarded-For
+	TrustProxy bool `yaml:"trust_proxy" env:"RATE_LIMIT_TRUST_PROXY"`
+}
+
+// Default is the configuration before any profile, file or variable applies
+func Default() Config {
+	return Config{
+		Env:              Development,
+		Port:             "8080",
+		ReturnWindowDays: repo.DefaultReturnWindowDays,
+		Server: Server{
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			MaxBodyBytes:      1 << 20,
+			MaxImportBytes:    50 << 20,
+			ShutdownTimeout:   25 * time.Second,
+		},
+		Storage: Storage{
+			Driver:       "local",
+			MediaDir:     "./media",
+			MediaBaseURL: "/media",
+			S3:           S3{UseSSL: true},
+		},
+		Invoice: Invoice{
+			TaxRate:    0.16,
+			Currency:   "KES",
+			SellerName: "Simple Ecomm",
+		},
+		GraphQL: GraphQL{
+			MaxComplexity: 1000,
+			MaxDepth:      10,
+			Introspection: true,
+		},
+		RateLimit: RateLimit{
+			Default: ratelimit.Limit{Requests: 300, Per: time.Minute},
+			Fields: ratelimit.Limits{
+				"customerLogin":  {Requests: 5, Per: time.Minute},
+				"createCustomer": {Requests: 5, Per: time.Hour},
+				"applyCoupon":    {Requests: 20, Per: time.Minute},
+			},
+			Store: "memory",
+		},
+	}
+}
+
+//go:embed profiles/*.yaml
+var profiles embed.FS
+
+// Load reads the configuration from every source and validates it. A .env
+// file in the working directory is loaded into the environment first,
+// without overriding variables that are already set.
+func Load() (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("load .env: %w", err)
+	}
+	return load(os.LookupEnv)
+}
+
+// load builds the configuration from lookup, which reads one environment variable
+func load(lookup func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+	if env, ok := lookup("APP_ENV"); ok && env != "" {
+		cfg.Env = env
+	}
+	if !slices.Contains(environments, cfg.Env) {
+		return nil, fmt.Errorf("config: APP_ENV must be one of %s, not %q", strings.Join(environments, ", "), cfg.Env)
+	}
+
+	profile, err := profiles.ReadFile("profiles/" + cfg.Env + ".yaml")
+	if err != nil {
+		return nil, fmt.Errorf("config: read %s profile: %w", cfg.Env, err)
+	}
+	if err := yaml.Unmarshal(profile, &cfg); err != nil {
+		return nil, fmt.Errorf("config: parse %s profile: %w", cfg.Env, err)
+	}
+
+	path, required := lookup("CONFIG_FILE")
+	if !required {
+		path = "config.yaml"
+	}
+	file, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(file, &cfg); err != nil {
+			return nil, fmt.Errorf("config: parse %s: %w", path, err)
+		}
+	case required || !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	if err := applyEnv(&cfg, lookup); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate checks that everything the app needs is set and makes sense,
+// reporting every problem at once
+func (c *Config) Validate() error {
+	var problems []string
+	require := func(value, name string) {
+		if strings.TrimSpace(value) == "" {
+			problems = append(problems, name+" is required")
+		}
+	}
+
+	require(c.DatabaseURL, "DATABASE_URL")
+	require(c.Auth0.Domain, "AUTH0_DOMAIN")
+	require(c.Auth0.Audience, "AUTH0_AUDIENCE")
+	if c.Env == Production {
+		// sign up and log in fail without these
+		require(c.Auth0.ClientID, "AUTH0_CLIENT_ID")
+		require(c.Auth0.ClientSecret, "AUTH0_CLIENT_SECRET")
+		require(c.Auth0.ManagementAudience, "AUTH0_MANAGEMENT_API_AUDIENCE")
+		require(c.Auth0.LoginClientID, "AUTH0_LOGIN_CLIENT_ID")
+		require(c.Auth0.LoginClientSecret, "AUTH0_LOGIN_CLIENT_SECRET")
+		require(c.Auth0.LoginAudience, "AUTH0_API_AUDIENCE")
+	}
+	if c.SMS.Enabled() {
+		require(c.SMS.Username, "AT_USERNAME")
+		require(c.SMS.ShortCode, "AT_SHORTCODE")
+		require(c.SMS.Sandbox, "AT_SANDBOX")
+	}
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port <= 0 || port > 65535 {
+		problems = append(problems, fmt.Sprintf("PORT must be a port number, not %q", c.Port))
+	}
+	for name, d := range map[string]time.Duration{
+		"SERVER_READ_HEADER_TIMEOUT": c.Server.ReadHeaderTimeout,
+		"SERVER_READ_TIMEOUT":        c.Server.ReadTimeout,
+		"SERVER_WRITE_TIMEOUT":       c.Server.WriteTimeout,
+		"SERVER_IDLE_TIMEOUT":        c.Server.IdleTimeout,
+		"SHUTDOWN_TIMEOUT":           c.Server.ShutdownTimeout,
+	} {
+		if d <= 0 {
+			problems = append(problems, name+" must be a positive duration like 30s")
+		}
+	}
+	if c.Server.ShutdownDelay < 0 {
+		problems = append(problems, "SHUTDOWN_DELAY can not be negative")
+	}
+	if c.Server.MaxBodyBytes <= 0 {
+		problems = append(problems, "SERVER_MAX_BODY_BYTES must be positive")
+	}
+	if c.Server.MaxImportBytes <= 0 {
+		problems = append(problems, "SERVER_MAX_IMPORT_BYTES must be positive")
+	}
+	if c.ReturnWindowDays <= 0 {
+		problems = append(problems, "RETURN_WINDOW_DAYS must be a positive number of days")
+	}
+
+	switch c.Storage.Driver {
+	case "local":
+	case "s3":
+		require(c.Storage.S3.Endpoint, "S3_ENDPOINT")
+		require(c.Storage.S3.Bucket, "S3_BUCKET")
+	default:
+		problems = append(problems, fmt.Sprintf("STORAGE_DRIVER must be local or s3, not %q", c.Storage.Driver))
+	}
+
+	if c.Invoice.TaxRate < 0 {
+		problems = append(problems, "INVOICE_TAX_RATE must not be negative")
+	}
+
+	if c.GraphQL.MaxComplexity <= 0 {
+		problems = append(problems, "GRAPHQL_MAX_COMPLEXITY must be positive")
+	}
+	if c.GraphQL.MaxDepth <= 0 {
+		problems = append(problems, "GRAPHQL_MAX_DEPTH must be positive")
+	}
+	if c.GraphQL.PersistedQueriesStrict && c.GraphQL.PersistedQueriesManifest == "" {
+		problems = append(problems, "PERSISTED_QUERIES_STRICT needs a PERSISTED_QUERIES_MANIFEST")
+	}
+
+	if c.RateLimit.Default.Requests <= 0 {
+		problems = append(problems, "RATE_LIMIT_DEFAULT is required")
+	}
+	if c.RateLimit.Store != "memory" && c.RateLimit.Store != "postgres" {
+		problems = append(problems, fmt.Sprintf("RATE_LIMIT_STORE must be memory or postgres, not %q", c.RateLimit.Store))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
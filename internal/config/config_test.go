@@ -0,0 +1,177 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/ratelimit"
+)
+
+// env is a lookup over a fixed set of variables, with the required ones set
+func env(vars map[string]string) func(string) (string, bool) {
+	all := map[string]string{
+		"DATABASE_URL":   "postgres://localhost/ecomm",
+		"AUTH0_DOMAIN":   "tenant.auth0.com",
+		"AUTH0_AUDIENCE": "https://api.example.com",
+	}
+	for k, v := range vars {
+		all[k] = v
+	}
+	return func(name string) (string, bool) {
+		v, ok := all[name]
+		return v, ok
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := load(env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Env != Development || cfg.Port != "8080" || !cfg.GraphQL.Introspection || cfg.RateLimit.Store != "memory" {
+		t.Errorf("got %+v", cfg)
+	}
+	if cfg.SMS.Enabled() {
+		t.Error("SMS is enabled without an account")
+	}
+}
+
+func TestLoadReportsEveryMissingValue(t *testing.T) {
+	_, err := load(func(string) (string, bool) { return "", false })
+	if err == nil {
+		t.Fatal("loaded without required values")
+	}
+	for _, name := range []string{"DATABASE_URL", "AUTH0_DOMAIN", "AUTH0_AUDIENCE"} {
+		if !strings.Contains(err.Error(), name+" is required") {
+			t.Errorf("error %q doesn't mention %s", err, name)
+		}
+	}
+}
+
+func TestLoadEnvironmentVariables(t *testing.T) {
+	cfg, err := load(env(map[string]string{
+		"PORT":                    "9090",
+		"GRAPHQL_MAX_DEPTH":       "8",
+		"GRAPHQL_INTROSPECTION":   "false",
+		"INVOICE_TAX_RATE":        "0.08",
+		"INVOICE_SELLER_ADDRESS":  "1 Main St;Nairobi",
+		"S3_USE_SSL":              "false",
+		"RATE_LIMIT_DEFAULT":      "100/1m",
+		"RATE_LIMITS":             "customerLogin=3/1m,createReview=10/1h",
+		"SHUTDOWN_TIMEOUT":        "20s",
+		"SHUTDOWN_DELAY":          "5s",
+		"SERVER_MAX_BODY_BYTES":   "2097152",
+		"SERVER_MAX_IMPORT_BYTES": "1048576",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != "9090" || cfg.GraphQL.MaxDepth != 8 || cfg.GraphQL.Introspection || cfg.Invoice.TaxRate != 0.08 || cfg.Storage.S3.UseSSL {
+		t.Errorf("got %+v", cfg)
+	}
+	if cfg.Server.ShutdownTimeout != 20*time.Second || cfg.Server.ShutdownDelay != 5*time.Second || cfg.Server.MaxBodyBytes != 2<<20 || cfg.Server.MaxImportBytes != 1<<20 {
+		t.Errorf("server = %+v", cfg.Server)
+	}
+	if len(cfg.Invoice.SellerAddress) != 2 || cfg.Invoice.SellerAddress[1] != "Nairobi" {
+		t.Errorf("seller address = %q", cfg.Invoice.SellerAddress)
+	}
+	if cfg.RateLimit.Default != (ratelimit.Limit{Requests: 100, Per: time.Minute}) {
+		t.Errorf("default rate limit = %v", cfg.RateLimit.Default)
+	}
+	// RATE_LIMITS adds to the defaults rather than replacing them
+	fields := cfg.RateLimit.Fields
+	if fields["customerLogin"].Requests != 3 || fields["createReview"].Per != time.Hour || fields["applyCoupon"].Requests != 20 {
+		t.Errorf("field rate limits = %v", fields)
+	}
+}
+
+func TestLoadReportsInvalidValues(t *testing.T) {
+	_, err := load(env(map[string]string{
+		"GRAPHQL_MAX_DEPTH": "deep",
+		"SHUTDOWN_TIMEOUT":  "30",
+		"RATE_LIMIT_STORE":  "redis",
+		"STORAGE_DRIVER":    "s3",
+	}))
+	if err == nil {
+		t.Fatal("loaded invalid values")
+	}
+	for _, want := range []string{`invalid GRAPHQL_MAX_DEPTH "deep"`, `invalid SHUTDOWN_TIMEOUT "30"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't say %q", err, want)
+		}
+	}
+
+	_, err = load(env(map[string]string{"RATE_LIMIT_STORE": "redis", "STORAGE_DRIVER": "s3"}))
+	for _, want := range []string{"RATE_LIMIT_STORE must be memory or postgres", "S3_BUCKET is required"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error %v doesn't say %q", err, want)
+		}
+	}
+}
+
+func TestLoadProductionProfile(t *testing.T) {
+	_, err := load(env(map[string]string{"APP_ENV": Production}))
+	if err == nil || !strings.Contains(err.Error(), "AUTH0_LOGIN_CLIENT_ID is required") {
+		t.Errorf("production started without login credentials: %v", err)
+	}
+
+	cfg, err := load(env(map[string]string{
+		"APP_ENV":                       Production,
+		"AUTH0_CLIENT_ID":               "id",
+		"AUTH0_CLIENT_SECRET":           "secret",
+		"AUTH0_MANAGEMENT_API_AUDIENCE": "https://tenant.auth0.com/api/v2/",
+		"AUTH0_LOGIN_CLIENT_ID":         "login-id",
+		"AUTH0_LOGIN_CLIENT_SECRET":     "login-secret",
+		"AUTH0_API_AUDIENCE":            "https://api.example.com",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.GraphQL.Introspection || cfg.RateLimit.Store != "postgres" || !cfg.RateLimit.TrustProxy {
+		t.Errorf("production profile not applied: %+v", cfg)
+	}
+
+	if _, err := load(env(map[string]string{"APP_ENV": "prod"})); err == nil {
+		t.Error("loaded an unknown environment")
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	file := `
+port: "7000"
+graphql:
+  max_depth: 6
+  max_complexity: 500
+rate_limit:
+  fields:
+    createOrder: 10/1m
+invoice:
+  seller_address: ["1 Main St", "Nairobi"]
+`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := load(env(map[string]string{"CONFIG_FILE": path, "GRAPHQL_MAX_DEPTH": "7"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// environment variables win over the file
+	if cfg.Port != "7000" || cfg.GraphQL.MaxDepth != 7 || cfg.GraphQL.MaxComplexity != 500 {
+		t.Errorf("got %+v", cfg.GraphQL)
+	}
+	if cfg.RateLimit.Fields["createOrder"].Requests != 10 || cfg.RateLimit.Fields["customerLogin"].Requests != 5 {
+		t.Errorf("field rate limits = %v", cfg.RateLimit.Fields)
+	}
+	if len(cfg.Invoice.SellerAddress) != 2 {
+		t.Errorf("seller address = %q", cfg.Invoice.SellerAddress)
+	}
+
+	if _, err := load(env(map[string]string{"CONFIG_FILE": filepath.Join(t.TempDir(), "missing.yaml")})); err == nil {
+		t.Error("loaded without the CONFIG_FILE")
+	}
+}
@@ -0,0 +1,98 @@
+// Package health answers Kubernetes' probes. /healthz says the process is
+// up, /readyz whether it can serve traffic right now: its dependencies answer
+// and it isn't shutting down.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check reports why a dependency isn't usable, or nil when it is
+type Check func(ctx context.Context) error
+
+// Checker runs the readiness checks
+type Checker struct {
+	// Timeout bounds each check, so a hanging dependency fails the probe
+	// rather than the probe timing out
+	Timeout time.Duration
+
+	checks       []namedCheck
+	shuttingDown atomic.Bool
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+func New() *Checker {
+	return &Checker{Timeout: 2 * time.Second}
+}
+
+// Add registers a readiness check. Checks are added before serving starts.
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// ShutDown makes readiness fail from now on, so the load balancer stops
+// sending new requests while the ones in flight finish
+func (c *Checker) ShutDown() {
+	c.shuttingDown.Store(true)
+}
+
+type report struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Live answers /healthz. It doesn't look at dependencies: restarting the
+// process wouldn't bring a database back.
+func (c *Checker) Live(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, http.StatusOK, report{Status: "ok"})
+}
+
+// Ready answers /readyz with every check's result, and 503 when any failed
+func (c *Checker) Ready(w http.ResponseWriter, r *http.Request) {
+	if c.shuttingDown.Load() {
+		writeReport(w, http.StatusServiceUnavailable, report{Status: "shutting down"})
+		return
+	}
+
+	results := make([]error, len(c.checks))
+	var wg sync.WaitGroup
+	for i, nc := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(r.Context(), c.Timeout)
+			defer cancel()
+			results[i] = nc.check(ctx)
+		}()
+	}
+	wg.Wait()
+
+	rep := report{Status: "ready", Checks: map[string]string{}}
+	status := http.StatusOK
+	for i, nc := range c.checks {
+		if results[i] != nil {
+			rep.Checks[nc.name] = results[i].Error()
+			rep.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		rep.Checks[nc.name] = "ok"
+	}
+	writeReport(w, status, rep)
+}
+
+func writeReport(w http.ResponseWriter, status int, rep any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(rep)
+}
@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func get(t *testing.T, h http.HandlerFunc) (int, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	var body map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return rec.Code, body
+}
+
+func TestReadyWhenEveryCheckPasses(t *testing.T) {
+	c := New()
+	c.Add("database", func(context.Context) error { return nil })
+	c.Add("auth0", func(context.Context) error { return nil })
+
+	code, body := get(t, c.Ready)
+	if code != http.StatusOK || body["status"] != "ready" {
+		t.Fatalf("got %d %v", code, body)
+	}
+	checks := body["checks"].(map[string]any)
+	if checks["database"] != "ok" || checks["auth0"] != "ok" {
+		t.Errorf("checks = %v", checks)
+	}
+}
+
+func TestNotReadyWhenACheckFails(t *testing.T) {
+	c := New()
+	c.Add("database", func(context.Context) error { return nil })
+	c.Add("migrations", func(context.Context) error { return errors.New("database is at migration 16, want 17") })
+
+	code, body := get(t, c.Ready)
+	if code != http.StatusServiceUnavailable || body["status"] != "unavailable" {
+		t.Fatalf("got %d %v", code, body)
+	}
+	checks := body["checks"].(map[string]any)
+	if checks["database"] != "ok" || checks["migrations"] != "database is at migration 16, want 17" {
+		t.Errorf("checks = %v", checks)
+	}
+}
+
+func TestChecksThatHangTimeOut(t *testing.T) {
+	c := New()
+	c.Timeout = 50 * time.Millisecond
+	c.Add("database", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	begin := time.Now()
+	code, _ := get(t, c.Ready)
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("got %d", code)
+	}
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("took %s", elapsed)
+	}
+}
+
+func TestNotReadyOnceShuttingDownButStillLive(t *testing.T) {
+	c := New()
+	c.Add("database", func(context.Context) error { return nil })
+	c.ShutDown()
+
+	if code, body := get(t, c.Ready); code != http.StatusServiceUnavailable || body["status"] != "shutting down" {
+		t.Errorf("ready got %d %v", code, body)
+	}
+	if code, body := get(t, c.Live); code != http.StatusOK || body["status"] != "ok" {
+		t.Errorf("live got %d %v", code, body)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	code, body := get(t, VersionHandler)
+	if code != http.StatusOK || body["version"] != Version || body["goVersion"] == "" {
+		t.Errorf("got %d %v", code, body)
+	}
+}
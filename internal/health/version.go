@@ -0,0 +1,54 @@
+package health
+
+import (
+	"net/http"
+	"runtime/debug"
+)
+
+// Version and Commit are set at build time, for example
+//
+//	go build -ldflags "-X github.com/godfreyowidi/simple-ecomm-demo/internal/health.Version=v1.2.0"
+//
+// Commit falls back to what the Go toolchain recorded from git, when the
+// binary was built from a checkout.
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+// BuildInfo describes the running binary
+type BuildInfo struct {
+	Version    string `json:"version"`
+	Commit     string `json:"commit,omitempty"`
+	CommitTime string `json:"commitTime,omitempty"`
+	Modified   bool   `json:"modified,omitempty"`
+	GoVersion  string `json:"goVersion"`
+}
+
+// Build reads the running binary's build info
+func Build() BuildInfo {
+	info := BuildInfo{Version: Version, Commit: Commit}
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.GoVersion = build.GoVersion
+	for _, s := range build.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = s.Value
+			}
+		case "vcs.time":
+			info.CommitTime = s.Value
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+	return info
+}
+
+// VersionHandler answers /version with Build
+func VersionHandler(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, http.StatusOK, Build())
+}
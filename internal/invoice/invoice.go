@@ -0,0 +1,233 @@
+// Package invoice renders order invoices and receipts as PDF. It holds no
+// state; callers gather the order, customer and line data and store the
+// result wherever they like.
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+// Seller is the shop as named at the top of every invoice
+type Seller struct {
+	Name    string
+	Address []string
+	TaxID   string
+}
+
+// Line is one row of the invoice table
+type Line struct {
+	Description string
+	Quantity    int
+	UnitPrice   float64
+}
+
+func (l Line) Total() float64 {
+	return math.Round(float64(l.Quantity)*l.UnitPrice*100) / 100
+}
+
+// IncludedTax is the tax contained in a tax-inclusive amount at the given rate
+func IncludedTax(amount, rate float64) float64 {
+	if rate <= 0 {
+		return 0
+	}
+	return math.Round(amount*rate/(1+rate)*100) / 100
+}
+
+// Data is everything printed on an invoice
+type Data struct {
+	Seller   Seller
+	Invoice  models.Invoice
+	Order    models.Order
+	Customer models.Customer
+	Lines    []Line
+	// Currency is printed before every amount, e.g. "KES"
+	Currency string
+}
+
+// Title is "RECEIPT" for orders that have been paid and "INVOICE" otherwise
+func Title(orderStatus string) string {
+	switch orderStatus {
+	case models.OrderStatusPending, models.OrderStatusCancelled:
+		return "INVOICE"
+	}
+	return "RECEIPT"
+}
+
+// Title is the document's title for the order as it stands
+func (d Data) Title() string {
+	return Title(d.Order.Status)
+}
+
+// layout, in points
+const (
+	margin     = 50.0
+	lineHeight = 16.0
+	// how far above the bottom margin the table stops to leave room for totals
+	footerSpace = 140.0
+
+	colQuantity  = 360.0
+	colUnitPrice = 450.0
+	colTotal     = pageWidth - margin
+)
+
+// Render lays the invoice out over as many A4 pages as its lines need
+func Render(d Data) []byte {
+	doc := &pdf{}
+	page := doc.page()
+	y := header(page, d)
+
+	for _, l := range d.Lines {
+		if y < margin+footerSpace {
+			page = doc.page()
+			y = tableHeader(page, pageHeight-margin)
+		}
+		text(page, fontRegular, 10, margin, y, truncate(l.Description, colQuantity-margin-40))
+		textRight(page, fontRegular, 10, colQuantity, y, fmt.Sprint(l.Quantity))
+		textRight(page, fontRegular, 10, colUnitPrice, y, d.money(l.UnitPrice))
+		textRight(page, fontRegular, 10, colTotal, y, d.money(l.Total()))
+		y -= lineHeight
+	}
+
+	totals(page, d, y)
+
+	for i, page := range doc.pages {
+		footer := fmt.Sprintf("%s %s - page %d of %d", d.Title(), d.Invoice.Number, i+1, len(doc.pages))
+		textRight(page, fontRegular, 8, pageWidth-margin, margin/2, footer)
+	}
+	return doc.bytes()
+}
+
+// header prints the seller, the invoice details and the bill-to block, and
+// returns where the table's first row goes
+func header(page *bytes.Buffer, d Data) float64 {
+	y := pageHeight - margin
+	text(page, fontBold, 20, margin, y, d.Title())
+	textRight(page, fontBold, 12, pageWidth-margin, y, d.Seller.Name)
+
+	sellerY := y - lineHeight
+	for _, l := range d.Seller.Address {
+		textRight(page, fontRegular, 9, pageWidth-margin, sellerY, l)
+		sellerY -= 12
+	}
+	if d.Seller.TaxID != "" {
+		textRight(page, fontRegular, 9, pageWidth-margin, sellerY, "Tax ID: "+d.Seller.TaxID)
+	}
+
+	y -= 2 * lineHeight
+	details := [][2]string{
+		{"Invoice number", d.Invoice.Number},
+		{"Issued", d.Invoice.IssuedAt.Format("2 January 2006")},
+		{"Order number", d.Order.Number},
+		{"Order date", d.Order.OrderDate.Format("2 January 2006")},
+	}
+	for _, row := range details {
+		text(page, fontBold, 10, margin, y, row[0])
+		text(page, fontRegular, 10, margin+100, y, row[1])
+		y -= lineHeight
+	}
+
+	y -= lineHeight
+	text(page, fontBold, 10, margin, y, "Bill to")
+	y -= lineHeight
+	for _, l := range billTo(d) {
+		text(page, fontRegular, 10, margin, y, l)
+		y -= lineHeight - 3
+	}
+
+	return tableHeader(page, y-lineHeight)
+}
+
+func tableHeader(page *bytes.Buffer, y float64) float64 {
+	text(page, fontBold, 10, margin, y, "Description")
+	textRight(page, fontBold, 10, colQuantity, y, "Qty")
+	textRight(page, fontBold, 10, colUnitPrice, y, "Unit price")
+	textRight(page, fontBold, 10, colTotal, y, "Amount")
+	line(page, margin, y-5, pageWidth-margin, y-5)
+	return y - lineHeight - 4
+}
+
+// billTo is the customer's name and contact details, with the order's
+// shipping address when it has one
+func billTo(d Data) []string {
+	lines := []string{strings.TrimSpace(d.Customer.FirstName + " " + d.Customer.LastName), d.Customer.Email}
+	if a := d.Order.ShippingAddress; a != nil {
+		lines = append(lines, a.Line1)
+		if a.Line2 != nil {
+			lines = append(lines, *a.Line2)
+		}
+		city := a.City
+		if a.PostalCode != nil {
+			city += " " + *a.PostalCode
+		}
+		lines = append(lines, city+", "+a.Country)
+	}
+	return lines
+}
+
+// totals prints the subtotal, discounts, shipping, tax and total below the
+// table, and what is left of the total once refunds are taken off
+func totals(page *bytes.Buffer, d Data, y float64) {
+	line(page, colQuantity, y+lineHeight-6, pageWidth-margin, y+lineHeight-6)
+	y -= 4
+
+	rows := [][2]string{{"Subtotal", d.money(d.Order.Subtotal)}}
+	if d.Order.DiscountTotal > 0 {
+		rows = append(rows, [2]string{"Discounts", "-" + d.money(d.Order.DiscountTotal)})
+	}
+	if d.Order.ShippingMethodName != nil || d.Order.ShippingCost > 0 {
+		rows = append(rows, [2]string{"Shipping", d.money(d.Order.ShippingCost)})
+	}
+	for _, row := range rows {
+		textRight(page, fontRegular, 10, colUnitPrice, y, row[0])
+		textRight(page, fontRegular, 10, colTotal, y, row[1])
+		y -= lineHeight
+	}
+
+	textRight(page, fontBold, 11, colUnitPrice, y, "Total")
+	textRight(page, fontBold, 11, colTotal, y, d.money(d.Order.Total))
+	y -= lineHeight
+
+	taxLabel := fmt.Sprintf("Includes %s%% tax", formatRate(d.Invoice.TaxRate))
+	textRight(page, fontRegular, 9, colUnitPrice, y, taxLabel)
+	textRight(page, fontRegular, 9, colTotal, y, d.money(d.Invoice.TaxAmount))
+
+	if d.Invoice.RefundedTotal > 0 {
+		y -= lineHeight
+		textRight(page, fontRegular, 10, colUnitPrice, y, "Refunded")
+		textRight(page, fontRegular, 10, colTotal, y, "-"+d.money(d.Invoice.RefundedTotal))
+		y -= lineHeight
+		net := math.Round((d.Order.Total-d.Invoice.RefundedTotal)*100) / 100
+		textRight(page, fontBold, 11, colUnitPrice, y, "Net total")
+		textRight(page, fontBold, 11, colTotal, y, d.money(net))
+	}
+}
+
+func (d Data) money(amount float64) string {
+	s := fmt.Sprintf("%.2f", amount)
+	if d.Currency != "" {
+		s = d.Currency + " " + s
+	}
+	return s
+}
+
+// formatRate prints a rate like 0.16 as "16"
+func formatRate(rate float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", rate*100), "0"), ".")
+}
+
+// truncate shortens s with an ellipsis so it fits in width points
+func truncate(s string, width float64) string {
+	if textWidth(fontRegular, 10, s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && textWidth(fontRegular, 10, string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
@@ -0,0 +1,99 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func testData(lines int) Data {
+	d := Data{
+		Seller:   Seller{Name: "Simple Ecomm", Address: []string{"1 Moi Avenue", "Nairobi"}, TaxID: "P051234567X"},
+		Invoice:  models.Invoice{Number: "INV-2024-000042", TaxRate: 0.16, TaxAmount: IncludedTax(1160, 0.16), IssuedAt: time.Date(2024, 10, 19, 0, 0, 0, 0, time.UTC)},
+		Order:    models.Order{Number: "ORD-241019-7K3QZ9MX", Status: models.OrderStatusPaid, Subtotal: 1160, Total: 1160, OrderDate: time.Date(2024, 10, 18, 0, 0, 0, 0, time.UTC)},
+		Customer: models.Customer{FirstName: "Wanjiru", LastName: "Kamau (Ltd)", Email: "wanjiru@example.com"},
+		Currency: "KES",
+	}
+	for i := range lines {
+		d.Lines = append(d.Lines, Line{Description: fmt.Sprintf("Item %d", i+1), Quantity: 1, UnitPrice: 10})
+	}
+	return d
+}
+
+func TestRenderWritesAValidPDF(t *testing.T) {
+	out := Render(testData(3))
+
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatal("expected a PDF header and trailer")
+	}
+	for _, want := range []string{"RECEIPT", "INV-2024-000042", "ORD-241019-7K3QZ9MX", `Kamau \(Ltd\)`, "KES 160.00", "Includes 16% tax"} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("expected the PDF to contain %q", want)
+		}
+	}
+
+	// every xref entry must point at the start of its object
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if startxref == nil {
+		t.Fatal("missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(startxref[1]))
+	entries := strings.Split(string(out[xref:]), "\n")[3:]
+	for i, entry := range entries {
+		if !strings.HasSuffix(entry, " n ") {
+			break
+		}
+		offset, _ := strconv.Atoi(entry[:10])
+		if want := fmt.Sprintf("%d 0 obj", i+1); !bytes.HasPrefix(out[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", i+1, out[offset:offset+10])
+		}
+	}
+}
+
+func TestRenderSpillsOntoMorePages(t *testing.T) {
+	out := Render(testData(120))
+	if pages := bytes.Count(out, []byte("/Type /Page ")); pages < 3 {
+		t.Errorf("expected 120 lines to need at least 3 pages, got %d", pages)
+	}
+	if !bytes.Contains(out, []byte("page 1 of ")) {
+		t.Error("expected page numbers in the footer")
+	}
+}
+
+func TestTitleFollowsPayment(t *testing.T) {
+	d := testData(1)
+	d.Order.Status = models.OrderStatusPending
+	if got := d.Title(); got != "INVOICE" {
+		t.Errorf("expected INVOICE for an unpaid order, got %s", got)
+	}
+}
+
+func TestRenderShowsRefunds(t *testing.T) {
+	d := testData(1)
+	if bytes.Contains(Render(d), []byte("Refunded")) {
+		t.Error("expected no refund rows without refunds")
+	}
+
+	d.Invoice.RefundedTotal = 160
+	out := Render(d)
+	for _, want := range []string{"Refunded", "-KES 160.00", "Net total", "KES 1000.00"} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("expected the PDF to contain %q", want)
+		}
+	}
+}
+
+func TestIncludedTax(t *testing.T) {
+	if got := IncludedTax(1160, 0.16); got != 160 {
+		t.Errorf("expected 160, got %v", got)
+	}
+	if got := IncludedTax(100, 0); got != 0 {
+		t.Errorf("expected no tax at a zero rate, got %v", got)
+	}
+}
@@ -0,0 +1,129 @@
+// Package media validates uploaded product images and renders the resized
+// copies served next to the original.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported image format, use JPEG, PNG or GIF")
+
+// MaxPixels caps width×height of uploads. Decoding needs about 4 bytes per
+// pixel however well the file compresses, so this bounds memory to ~160MB.
+const MaxPixels = 40_000_000
+
+var ErrTooManyPixels = fmt.Errorf("image is too large, at most %d megapixels are allowed", MaxPixels/1_000_000)
+
+// Rendition is a resized copy of an image that fits inside a MaxSize square
+type Rendition struct {
+	Name    string
+	MaxSize int
+}
+
+var (
+	Thumbnail = Rendition{Name: "thumbnail", MaxSize: 150}
+	Medium    = Rendition{Name: "medium", MaxSize: 600}
+)
+
+// Renditions are generated for every uploaded image
+var Renditions = []Rendition{Thumbnail, Medium}
+
+// Encoded is an image ready to be stored
+type Encoded struct {
+	Data        []byte
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+}
+
+// Processed is an uploaded image with its renditions keyed by rendition name
+type Processed struct {
+	Original   Encoded
+	Renditions map[string]Encoded
+}
+
+// Process decodes an uploaded image and renders every rendition in the same
+// format. GIFs are rendered as PNG since only their first frame is kept.
+// The dimensions are checked against MaxPixels before the image is decoded.
+func Process(data []byte) (*Processed, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+
+	var original Encoded
+	switch format {
+	case "jpeg":
+		original = Encoded{ContentType: "image/jpeg", Ext: ".jpg"}
+	case "png":
+		original = Encoded{ContentType: "image/png", Ext: ".png"}
+	case "gif":
+		original = Encoded{ContentType: "image/gif", Ext: ".gif"}
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	original.Data = data
+	original.Width, original.Height = img.Bounds().Dx(), img.Bounds().Dy()
+
+	p := &Processed{Original: original, Renditions: map[string]Encoded{}}
+	for _, r := range Renditions {
+		resized := Resize(img, r.MaxSize)
+
+		var buf bytes.Buffer
+		out := Encoded{ContentType: original.ContentType, Ext: original.Ext}
+		switch format {
+		case "jpeg":
+			err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: 85})
+		default:
+			out = Encoded{ContentType: "image/png", Ext: ".png"}
+			err = png.Encode(&buf, resized)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("encode %s: %w", r.Name, err)
+		}
+
+		out.Data = buf.Bytes()
+		out.Width, out.Height = resized.Bounds().Dx(), resized.Bounds().Dy()
+		p.Renditions[r.Name] = out
+	}
+	return p, nil
+}
+
+// Resize scales img down to fit inside a maxSize square keeping its aspect
+// ratio. Images that already fit are returned unchanged.
+func Resize(img image.Image, maxSize int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxSize && h <= maxSize {
+		return img
+	}
+
+	if w >= h {
+		h = max(1, h*maxSize/w)
+		w = maxSize
+	} else {
+		w = max(1, w*maxSize/h)
+		h = maxSize
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+	return dst
+}
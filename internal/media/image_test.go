@@ -0,0 +1,68 @@
+package media_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/media"
+)
+
+func TestResizeKeepsAspectRatio(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1200, 600))
+
+	resized := media.Resize(img, 600)
+	if w, h := resized.Bounds().Dx(), resized.Bounds().Dy(); w != 600 || h != 300 {
+		t.Errorf("expected 600x300, got %dx%d", w, h)
+	}
+
+	small := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	if media.Resize(small, 600) != image.Image(small) {
+		t.Error("expected images smaller than the rendition to be returned unchanged")
+	}
+}
+
+func TestProcess(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 800, 1600))
+	img.Set(0, 0, color.White)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode fixture: %v", err)
+	}
+
+	p, err := media.Process(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if p.Original.ContentType != "image/png" || p.Original.Width != 800 || p.Original.Height != 1600 {
+		t.Errorf("unexpected original: %+v", p.Original)
+	}
+
+	thumb := p.Renditions[media.Thumbnail.Name]
+	if thumb.Width != 75 || thumb.Height != 150 {
+		t.Errorf("expected 75x150 thumbnail, got %dx%d", thumb.Width, thumb.Height)
+	}
+
+	if _, err := media.Process([]byte("not an image")); err != media.ErrUnsupportedFormat {
+		t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+	}
+}
+
+func TestProcessRejectsHugeDimensionsBeforeDecoding(t *testing.T) {
+	// a PNG header claiming 100000x100000 pixels, far more than the file holds
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	ihdr := []byte{0, 1, 0x86, 0xa0, 0, 1, 0x86, 0xa0, 8, 2, 0, 0, 0}
+	binary.Write(&buf, binary.BigEndian, uint32(len(ihdr)))
+	chunk := append([]byte("IHDR"), ihdr...)
+	buf.Write(chunk)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+
+	if _, err := media.Process(buf.Bytes()); err != media.ErrTooManyPixels {
+		t.Errorf("expected ErrTooManyPixels, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+// Package ordernumber makes the order numbers customers see, like
+// ORD-241019-7K3QZ9MX. The middle part is the order date and the last part is
+// random Crockford base32 ending in a Luhn mod 32 check character, so numbers
+// reveal nothing about order volume and typos read over the phone are caught
+// before they reach the database.
+package ordernumber
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Prefix starts every order number
+const Prefix = "ORD"
+
+// RandomLength is how many random characters follow the date
+const RandomLength = 7
+
+// alphabet is Crockford's base32, which leaves out I, L, O and U
+const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var ErrInvalid = errors.New("invalid order number")
+
+// New returns a fresh order number for an order placed at t
+func New(t time.Time) (string, error) {
+	random := make([]byte, RandomLength)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("generate order number: %w", err)
+	}
+
+	var body strings.Builder
+	for _, b := range random {
+		body.WriteByte(alphabet[int(b)%len(alphabet)])
+	}
+
+	date := t.UTC().Format("060102")
+	return fmt.Sprintf("%s-%s-%s%c", Prefix, date, body.String(), checkChar(date+body.String())), nil
+}
+
+// Normalize cleans up an order number as a person might type it: any case,
+// spaces or dashes anywhere, and the letters Crockford base32 reads as digits.
+// It returns ErrInvalid when the result is malformed or the check fails.
+func Normalize(s string) (string, error) {
+	s = strings.ToUpper(s)
+	s = strings.NewReplacer(" ", "", "-", "").Replace(s)
+	s = strings.TrimPrefix(s, Prefix)
+
+	const dateLength = 6
+	if len(s) != dateLength+RandomLength+1 {
+		return "", ErrInvalid
+	}
+
+	s = strings.NewReplacer("O", "0", "I", "1", "L", "1").Replace(s)
+	for _, r := range s {
+		if !strings.ContainsRune(alphabet, r) {
+			return "", ErrInvalid
+		}
+	}
+	if !valid(s) {
+		return "", ErrInvalid
+	}
+	return fmt.Sprintf("%s-%s-%s", Prefix, s[:dateLength], s[dateLength:]), nil
+}
+
+// checkChar is the Luhn mod 32 check character of s
+func checkChar(s string) byte {
+	factor, sum := 2, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		sum += luhnAddend(strings.IndexByte(alphabet, s[i]), factor)
+		factor = 3 - factor
+	}
+	return alphabet[(len(alphabet)-sum%len(alphabet))%len(alphabet)]
+}
+
+// valid reports whether s ends in its Luhn mod 32 check character
+func valid(s string) bool {
+	factor, sum := 1, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		sum += luhnAddend(strings.IndexByte(alphabet, s[i]), factor)
+		factor = 3 - factor
+	}
+	return sum%len(alphabet) == 0
+}
+
+func luhnAddend(code, factor int) int {
+	addend := code * factor
+	return addend/len(alphabet) + addend%len(alphabet)
+}
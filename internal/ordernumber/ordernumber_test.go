@@ -0,0 +1,74 @@
+package ordernumber
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewIsWellFormedAndValid(t *testing.T) {
+	placed := time.Date(2024, 10, 19, 15, 4, 5, 0, time.UTC)
+	format := regexp.MustCompile(`^ORD-241019-[0-9A-HJKMNP-TV-Z]{8}$`)
+
+	seen := map[string]bool{}
+	for range 200 {
+		number, err := New(placed)
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		if !format.MatchString(number) {
+			t.Fatalf("unexpected format %q", number)
+		}
+		if got, err := Normalize(number); err != nil || got != number {
+			t.Fatalf("expected %q to normalize to itself, got %q, %v", number, got, err)
+		}
+		seen[number] = true
+	}
+	if len(seen) < 200 {
+		t.Errorf("expected 200 distinct numbers, got %d", len(seen))
+	}
+}
+
+func TestNormalizeForgivesHowPeopleType(t *testing.T) {
+	number, err := New(time.Date(2024, 10, 19, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	typed := strings.ToLower(strings.ReplaceAll(number, "-", " "))
+	typed = strings.ReplaceAll(typed, "0", "o")
+	if got, err := Normalize(typed); err != nil || got != number {
+		t.Errorf("expected %q to normalize to %q, got %q, %v", typed, number, got, err)
+	}
+	if got, err := Normalize(strings.TrimPrefix(number, "ORD-")); err != nil || got != number {
+		t.Errorf("expected the prefix to be optional, got %q, %v", got, err)
+	}
+}
+
+func TestNormalizeCatchesTypos(t *testing.T) {
+	number, err := New(time.Now())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	// change each character of the random part in turn
+	for i := len(number) - RandomLength - 1; i < len(number); i++ {
+		for _, r := range alphabet {
+			if byte(r) == number[i] {
+				continue
+			}
+			typo := number[:i] + string(r) + number[i+1:]
+			if _, err := Normalize(typo); !errors.Is(err, ErrInvalid) {
+				t.Fatalf("expected typo %q of %q to be rejected", typo, number)
+			}
+		}
+	}
+
+	for _, bad := range []string{"", "ORD-", "ORD-241019-ABC", "ORD-241019-UUUUUUUU"} {
+		if _, err := Normalize(bad); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
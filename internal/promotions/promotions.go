@@ -0,0 +1,176 @@
+// Package promotions works out which discounts apply to a set of order lines.
+// It holds no state; callers load promotions and category scopes from the repos.
+package promotions
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+var (
+	ErrInactive      = errors.New("promotion is not active")
+	ErrNotStarted    = errors.New("promotion has not started yet")
+	ErrExpired       = errors.New("promotion has expired")
+	ErrMinSubtotal   = errors.New("order subtotal is below the promotion minimum")
+	ErrNotApplicable = errors.New("promotion does not apply to any item in the order")
+)
+
+// Line is a single order line as seen by the promotions engine
+type Line struct {
+	ProductID  int
+	CategoryID *int
+	Quantity   int
+	UnitPrice  float64
+}
+
+// Scope is the set of category IDs a category-wide promotion covers,
+// i.e. the promotion's category and all of its descendants
+type Scope map[int]bool
+
+// Candidate is a promotion together with its resolved category scope.
+// Scope is nil for promotions that apply to the whole order.
+type Candidate struct {
+	Promotion models.Promotion
+	Scope     Scope
+}
+
+// covers reports whether the line falls within the candidate's scope
+func (c Candidate) covers(l Line) bool {
+	if c.Scope == nil {
+		return true
+	}
+	return l.CategoryID != nil && c.Scope[*l.CategoryID]
+}
+
+// Subtotal is the undiscounted value of the lines
+func Subtotal(lines []Line) float64 {
+	var total float64
+	for _, l := range lines {
+		total += float64(l.Quantity) * l.UnitPrice
+	}
+	return round(total)
+}
+
+// Check explains why a candidate can't be used on the lines at time now, or returns nil
+func Check(c Candidate, lines []Line, now time.Time) error {
+	p := c.Promotion
+	if !p.Active {
+		return ErrInactive
+	}
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return ErrNotStarted
+	}
+	if p.EndsAt != nil && !now.Before(*p.EndsAt) {
+		return ErrExpired
+	}
+	if p.MinSubtotal != nil && Subtotal(lines) < *p.MinSubtotal {
+		return ErrMinSubtotal
+	}
+	if Discount(c, lines) <= 0 {
+		return ErrNotApplicable
+	}
+	return nil
+}
+
+// Discount is the amount the candidate takes off the lines, ignoring validity windows
+func Discount(c Candidate, lines []Line) float64 {
+	p := c.Promotion
+
+	var eligible float64
+	for _, l := range lines {
+		if c.covers(l) {
+			eligible += float64(l.Quantity) * l.UnitPrice
+		}
+	}
+	if eligible <= 0 {
+		return 0
+	}
+
+	switch p.Kind {
+	case models.PromotionPercentage:
+		return round(eligible * math.Min(p.Value, 100) / 100)
+	case models.PromotionFixedAmount:
+		return round(math.Min(p.Value, eligible))
+	case models.PromotionBuyXGetY:
+		return buyXGetY(c, lines)
+	}
+	return 0
+}
+
+// buyXGetY pools every eligible unit, groups them into buy+get sized bundles
+// and discounts the cheapest "get" units of each bundle
+func buyXGetY(c Candidate, lines []Line) float64 {
+	p := c.Promotion
+	if p.BuyQuantity == nil || p.GetQuantity == nil {
+		return 0
+	}
+	buy, get := *p.BuyQuantity, *p.GetQuantity
+
+	var units []float64
+	for _, l := range lines {
+		if !c.covers(l) {
+			continue
+		}
+		for i := 0; i < l.Quantity; i++ {
+			units = append(units, l.UnitPrice)
+		}
+	}
+
+	free := len(units) / (buy + get) * get
+	if free == 0 {
+		return 0
+	}
+
+	sort.Float64s(units)
+	var amount float64
+	for _, price := range units[:free] {
+		amount += price
+	}
+	return round(amount * math.Min(p.Value, 100) / 100)
+}
+
+// Apply evaluates the candidates against the lines and returns one discount
+// line per promotion that applies. Candidates that fail Check are skipped and
+// the combined discount never exceeds the order subtotal.
+func Apply(candidates []Candidate, lines []Line, now time.Time) []models.OrderDiscount {
+	remaining := Subtotal(lines)
+
+	var discounts []models.OrderDiscount
+	for _, c := range candidates {
+		if remaining <= 0 {
+			break
+		}
+		if Check(c, lines, now) != nil {
+			continue
+		}
+
+		amount := math.Min(Discount(c, lines), remaining)
+		remaining = round(remaining - amount)
+
+		promotionID := c.Promotion.ID
+		discounts = append(discounts, models.OrderDiscount{
+			PromotionID: &promotionID,
+			Code:        c.Promotion.Code,
+			Description: c.Promotion.Name,
+			Amount:      amount,
+		})
+	}
+	return discounts
+}
+
+// Total sums the discount lines
+func Total(discounts []models.OrderDiscount) float64 {
+	var total float64
+	for _, d := range discounts {
+		total += d.Amount
+	}
+	return round(total)
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,116 @@
+package promotions_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/promotions"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestDiscount(t *testing.T) {
+	phones, shoes := 2, 5
+	lines := []promotions.Line{
+		{ProductID: 1, CategoryID: &phones, Quantity: 1, UnitPrice: 500},
+		{ProductID: 2, CategoryID: &shoes, Quantity: 3, UnitPrice: 100},
+	}
+
+	tests := []struct {
+		name string
+		c    promotions.Candidate
+		want float64
+	}{
+		{
+			name: "percentage on whole order",
+			c:    promotions.Candidate{Promotion: models.Promotion{Kind: models.PromotionPercentage, Value: 10}},
+			want: 80,
+		},
+		{
+			name: "percentage on category",
+			c: promotions.Candidate{
+				Promotion: models.Promotion{Kind: models.PromotionPercentage, Value: 10},
+				Scope:     promotions.Scope{shoes: true},
+			},
+			want: 30,
+		},
+		{
+			name: "fixed amount capped at eligible value",
+			c: promotions.Candidate{
+				Promotion: models.Promotion{Kind: models.PromotionFixedAmount, Value: 1000},
+				Scope:     promotions.Scope{shoes: true},
+			},
+			want: 300,
+		},
+		{
+			name: "buy two get one free",
+			c: promotions.Candidate{
+				Promotion: models.Promotion{Kind: models.PromotionBuyXGetY, Value: 100, BuyQuantity: intPtr(2), GetQuantity: intPtr(1)},
+			},
+			want: 100,
+		},
+		{
+			name: "no item in scope",
+			c: promotions.Candidate{
+				Promotion: models.Promotion{Kind: models.PromotionPercentage, Value: 10},
+				Scope:     promotions.Scope{99: true},
+			},
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := promotions.Discount(tt.c, lines); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	min := 1000.0
+	lines := []promotions.Line{{ProductID: 1, Quantity: 2, UnitPrice: 100}}
+
+	base := models.Promotion{Kind: models.PromotionPercentage, Value: 10, Active: true}
+
+	inactive := base
+	inactive.Active = false
+	notStarted := base
+	notStarted.StartsAt = &future
+	expired := base
+	expired.EndsAt = &past
+	tooSmall := base
+	tooSmall.MinSubtotal = &min
+
+	for want, p := range map[error]models.Promotion{
+		nil:                       base,
+		promotions.ErrInactive:    inactive,
+		promotions.ErrNotStarted:  notStarted,
+		promotions.ErrExpired:     expired,
+		promotions.ErrMinSubtotal: tooSmall,
+	} {
+		if got := promotions.Check(promotions.Candidate{Promotion: p}, lines, now); got != want {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestApplyCapsAtSubtotal(t *testing.T) {
+	lines := []promotions.Line{{ProductID: 1, Quantity: 1, UnitPrice: 50}}
+	candidates := []promotions.Candidate{
+		{Promotion: models.Promotion{ID: 1, Name: "Forty off", Kind: models.PromotionFixedAmount, Value: 40, Active: true}},
+		{Promotion: models.Promotion{ID: 2, Name: "Twenty off", Kind: models.PromotionFixedAmount, Value: 20, Active: true}},
+	}
+
+	discounts := promotions.Apply(candidates, lines, time.Now())
+	if len(discounts) != 2 {
+		t.Fatalf("expected 2 discount lines, got %d", len(discounts))
+	}
+	if total := promotions.Total(discounts); total != 50 {
+		t.Errorf("expected total discount of 50, got %v", total)
+	}
+}
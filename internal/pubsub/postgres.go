@@ -0,0 +1,101 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultChannel is the Postgres notification channel events travel on
+const DefaultChannel = "ecomm_events"
+
+// Postgres is a Broker shared by every process connected to the same
+// database. Published events go out with NOTIFY, and each process LISTENs on
+// one connection and hands what arrives to its local subscribers, including
+// events it published itself.
+type Postgres struct {
+	pool    *pgxpool.Pool
+	channel string
+	local   *Memory
+}
+
+// envelope is what travels in a notification
+type envelope struct {
+	Topic   string          `json:"topic"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// NewPostgres starts listening on channel until ctx is done. Payloads must be JSON.
+func NewPostgres(ctx context.Context, pool *pgxpool.Pool, channel string) *Postgres {
+	p := &Postgres{pool: pool, channel: channel, local: NewMemory()}
+	go p.listen(ctx)
+	return p
+}
+
+func (p *Postgres) Publish(ctx context.Context, topic string, payload []byte) error {
+	msg, err := json.Marshal(envelope{Topic: topic, Payload: payload})
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+	if _, err := p.pool.Exec(ctx, `SELECT pg_notify($1, $2)`, p.channel, string(msg)); err != nil {
+		return fmt.Errorf("publish event: %w", err)
+	}
+	return nil
+}
+
+func (p *Postgres) Subscribe(ctx context.Context, topic string) <-chan []byte {
+	return p.local.Subscribe(ctx, topic)
+}
+
+// listen keeps a LISTEN connection open, reconnecting after failures.
+// Events sent while it is reconnecting are missed.
+func (p *Postgres) listen(ctx context.Context) {
+	backoff := time.Second
+	for {
+		err := p.receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("pubsub: listening on %s failed, retrying in %s: %v", p.channel, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 30*time.Second)
+	}
+}
+
+// receive delivers notifications until the connection fails or ctx is done
+func (p *Postgres) receive(ctx context.Context) error {
+	pooled, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// the session is left listening, so it must not go back into the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, `LISTEN `+pgx.Identifier{p.channel}.Sanitize()); err != nil {
+		return err
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var e envelope
+		if err := json.Unmarshal([]byte(n.Payload), &e); err != nil {
+			log.Printf("pubsub: ignoring malformed event on %s: %v", p.channel, err)
+			continue
+		}
+		_ = p.local.Publish(ctx, e.Topic, e.Payload)
+	}
+}
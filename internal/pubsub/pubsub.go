@@ -0,0 +1,73 @@
+// Package pubsub fans events out to subscribers, such as the GraphQL
+// subscriptions of every connected client. Memory delivers within one
+// process; Postgres passes events through LISTEN/NOTIFY so every replica
+// sees what any of them publishes.
+package pubsub
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// Broker publishes payloads on topics and hands them to the subscribers of
+// those topics. Payloads should stay small, Postgres caps them at 8000 bytes.
+type Broker interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// Subscribe delivers the topic's payloads until ctx is done, then closes
+	// the channel
+	Subscribe(ctx context.Context, topic string) <-chan []byte
+}
+
+// subscriberBuffer is how many payloads a slow subscriber can fall behind by
+// before further ones are dropped for it
+const subscriberBuffer = 16
+
+// Memory is a Broker for a single process
+type Memory struct {
+	mu     sync.Mutex
+	topics map[string]map[chan []byte]struct{}
+}
+
+func NewMemory() *Memory {
+	return &Memory{topics: map[string]map[chan []byte]struct{}{}}
+}
+
+// Publish hands the payload to every current subscriber of the topic without
+// waiting on any of them
+func (m *Memory) Publish(_ context.Context, topic string, payload []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for ch := range m.topics[topic] {
+		select {
+		case ch <- payload:
+		default:
+			log.Printf("pubsub: dropped an event on %s for a slow subscriber", topic)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Subscribe(ctx context.Context, topic string) <-chan []byte {
+	ch := make(chan []byte, subscriberBuffer)
+
+	m.mu.Lock()
+	if m.topics[topic] == nil {
+		m.topics[topic] = map[chan []byte]struct{}{}
+	}
+	m.topics[topic][ch] = struct{}{}
+	m.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		m.mu.Lock()
+		delete(m.topics[topic], ch)
+		if len(m.topics[topic]) == 0 {
+			delete(m.topics, topic)
+		}
+		m.mu.Unlock()
+		close(ch)
+	}()
+	return ch
+}
@@ -0,0 +1,75 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryDeliversToSubscribersOfTheTopic(t *testing.T) {
+	m := NewMemory()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := m.Subscribe(ctx, "order:1")
+	second := m.Subscribe(ctx, "order:1")
+	other := m.Subscribe(ctx, "order:2")
+
+	if err := m.Publish(ctx, "order:1", []byte(`{"status":"paid"}`)); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	for _, ch := range []<-chan []byte{first, second} {
+		select {
+		case got := <-ch:
+			if string(got) != `{"status":"paid"}` {
+				t.Errorf("unexpected payload %s", got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected the event to be delivered")
+		}
+	}
+	select {
+	case got := <-other:
+		t.Errorf("expected nothing on another topic, got %s", got)
+	default:
+	}
+}
+
+func TestMemoryClosesSubscriptionWhenContextEnds(t *testing.T) {
+	m := NewMemory()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ch := m.Subscribe(ctx, "orders:new")
+	cancel()
+
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("expected the channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the channel to be closed")
+	}
+
+	// publishing after the subscriber left must not block or panic
+	if err := m.Publish(context.Background(), "orders:new", []byte(`{}`)); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+}
+
+func TestMemoryDropsEventsForSlowSubscribers(t *testing.T) {
+	m := NewMemory()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := m.Subscribe(ctx, "orders:new")
+	for range subscriberBuffer + 5 {
+		if err := m.Publish(ctx, "orders:new", []byte(`{}`)); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	if len(ch) != subscriberBuffer {
+		t.Errorf("expected %d buffered events, got %d", subscriberBuffer, len(ch))
+	}
+}
@@ -0,0 +1,178 @@
+// Package querylimit keeps GraphQL clients from sending queries that are too
+// expensive to run. DepthLimit rejects deeply nested selections, and
+// PersistedQueries can restrict the server to queries registered ahead of
+// time in a manifest.
+package querylimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// error codes sent in extensions.code
+const (
+	CodeDepthLimit             = "DEPTH_LIMIT_EXCEEDED"
+	CodePersistedQueryOnly     = "PERSISTED_QUERY_NOT_ALLOWED"
+	CodePersistedQueryNotFound = "PERSISTED_QUERY_NOT_FOUND"
+)
+
+// DepthLimit rejects operations whose fields nest deeper than Max, counting
+// through fragments. Introspection fields aren't counted, the Introspection
+// extension decides whether those may run at all.
+type DepthLimit struct {
+	Max int
+}
+
+var _ interface {
+	graphql.OperationContextMutator
+	graphql.HandlerExtension
+} = DepthLimit{}
+
+func (d DepthLimit) ExtensionName() string {
+	return "DepthLimit"
+}
+
+func (d DepthLimit) Validate(schema graphql.ExecutableSchema) error {
+	if d.Max <= 0 {
+		return errors.New("DepthLimit.Max must be positive")
+	}
+	return nil
+}
+
+func (d DepthLimit) MutateOperationContext(ctx context.Context, opCtx *graphql.OperationContext) *gqlerror.Error {
+	if opCtx.Operation == nil {
+		return nil
+	}
+	if depth := Depth(opCtx.Operation.SelectionSet); depth > d.Max {
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d", depth, d.Max)
+		errcode.Set(err, CodeDepthLimit)
+		return err
+	}
+	return nil
+}
+
+// Depth is how deeply the fields in set nest, so `{ a { b } }` has depth 2.
+// Fragment cycles are rejected by validation before this runs.
+func Depth(set ast.SelectionSet) int {
+	deepest := 0
+	for _, sel := range set {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(sel.Name, "__") {
+				continue
+			}
+			deepest = max(deepest, 1+Depth(sel.SelectionSet))
+		case *ast.InlineFragment:
+			deepest = max(deepest, Depth(sel.SelectionSet))
+		case *ast.FragmentSpread:
+			if sel.Definition != nil {
+				deepest = max(deepest, Depth(sel.Definition.SelectionSet))
+			}
+		}
+	}
+	return deepest
+}
+
+// PersistedQueries runs queries registered in a manifest when clients send
+// just their hash, the same way as automatic persisted queries:
+//
+//	{"extensions": {"persistedQuery": {"version": 1, "sha256Hash": "..."}}}
+//
+// When Strict, registered queries are the only ones that run. Queries sent in
+// full are then accepted only if their hash is in the manifest, so the
+// AutomaticPersistedQuery extension, which registers any query it is sent,
+// must not be used alongside.
+type PersistedQueries struct {
+	// Queries maps the hex SHA-256 hash of each query to its text
+	Queries map[string]string
+	Strict  bool
+}
+
+var _ interface {
+	graphql.OperationParameterMutator
+	graphql.HandlerExtension
+} = PersistedQueries{}
+
+func (p PersistedQueries) ExtensionName() string {
+	return "PersistedQueries"
+}
+
+func (p PersistedQueries) Validate(schema graphql.ExecutableSchema) error {
+	if p.Strict && len(p.Queries) == 0 {
+		return errors.New("PersistedQueries is strict but has no queries")
+	}
+	return nil
+}
+
+func (p PersistedQueries) MutateOperationParameters(ctx context.Context, rawParams *graphql.RawParams) *gqlerror.Error {
+	hash := persistedQueryHash(rawParams)
+	if hash == "" {
+		if p.Strict && rawParams.Query != "" {
+			if _, ok := p.Queries[QueryHash(rawParams.Query)]; !ok {
+				err := gqlerror.Errorf("only persisted queries are allowed")
+				errcode.Set(err, CodePersistedQueryOnly)
+				return err
+			}
+		}
+		return nil
+	}
+
+	query, ok := p.Queries[hash]
+	switch {
+	case !ok && p.Strict:
+		err := gqlerror.Errorf("persisted query %s is not registered", hash)
+		errcode.Set(err, CodePersistedQueryNotFound)
+		return err
+	case !ok:
+		// left for automatic persisted queries
+		return nil
+	case rawParams.Query != "" && rawParams.Query != query:
+		return gqlerror.Errorf("provided persisted query hash does not match query")
+	}
+	rawParams.Query = query
+	return nil
+}
+
+// persistedQueryHash is the sha256Hash of the request's persistedQuery
+// extension, or "" when it has none
+func persistedQueryHash(rawParams *graphql.RawParams) string {
+	ext, _ := rawParams.Extensions["persistedQuery"].(map[string]any)
+	hash, _ := ext["sha256Hash"].(string)
+	return hash
+}
+
+// QueryHash is the hex SHA-256 hash clients send for a persisted query
+func QueryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadManifest reads a JSON object mapping each query's hash to its text,
+// checking that every hash matches its query
+func LoadManifest(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read persisted query manifest: %w", err)
+	}
+	var queries map[string]string
+	if err := json.Unmarshal(data, &queries); err != nil {
+		return nil, fmt.Errorf("parse persisted query manifest %s: %w", path, err)
+	}
+	for hash, query := range queries {
+		if QueryHash(query) != hash {
+			return nil, fmt.Errorf("persisted query manifest %s: hash %s does not match its query", path, hash)
+		}
+	}
+	return queries, nil
+}
@@ -0,0 +1,130 @@
+package querylimit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+var testSchema = gqlparser.MustLoadSchema(&ast.Source{Input: `
+	type Query { category: Category }
+	type Category { name: String! children: [Category!]! }
+`})
+
+func TestDepth(t *testing.T) {
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{`{ category { name } }`, 2},
+		{`{ category { children { children { name } } } }`, 4},
+		{`{ category { ...Kids } } fragment Kids on Category { children { name } }`, 3},
+		{`{ category { ... on Category { children { name } } } }`, 3},
+		{`{ __typename category { __typename name } }`, 2},
+		{`{ __schema { types { fields { type { ofType { name } } } } } }`, 0},
+	}
+	for _, tt := range tests {
+		doc, errs := gqlparser.LoadQuery(testSchema, tt.query)
+		if errs != nil {
+			t.Fatalf("parse %s: %v", tt.query, errs)
+		}
+		if got := Depth(doc.Operations[0].SelectionSet); got != tt.want {
+			t.Errorf("Depth(%s) = %d, want %d", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestDepthLimit(t *testing.T) {
+	doc, errs := gqlparser.LoadQuery(testSchema, `{ category { children { children { name } } } }`)
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	opCtx := &graphql.OperationContext{Doc: doc, Operation: doc.Operations[0]}
+
+	if err := (DepthLimit{Max: 4}).MutateOperationContext(context.Background(), opCtx); err != nil {
+		t.Errorf("depth 4 with limit 4: %v", err)
+	}
+	err := (DepthLimit{Max: 3}).MutateOperationContext(context.Background(), opCtx)
+	if err == nil || err.Extensions["code"] != CodeDepthLimit {
+		t.Errorf("depth 4 with limit 3: got %v, want a %s error", err, CodeDepthLimit)
+	}
+}
+
+func persisted(hash string) map[string]any {
+	return map[string]any{"persistedQuery": map[string]any{"version": 1, "sha256Hash": hash}}
+}
+
+func TestPersistedQueries(t *testing.T) {
+	const registered = `{ category { name } }`
+	const other = `{ category { children { name } } }`
+	hash := QueryHash(registered)
+	queries := map[string]string{hash: registered}
+
+	tests := []struct {
+		name      string
+		strict    bool
+		params    graphql.RawParams
+		wantQuery string
+		wantCode  string
+		wantErr   bool
+	}{
+		{name: "registered hash", params: graphql.RawParams{Extensions: persisted(hash)}, wantQuery: registered},
+		{name: "registered hash when strict", strict: true, params: graphql.RawParams{Extensions: persisted(hash)}, wantQuery: registered},
+		{name: "unknown hash is left for APQ", params: graphql.RawParams{Extensions: persisted(QueryHash(other))}},
+		{name: "unknown hash when strict", strict: true, params: graphql.RawParams{Extensions: persisted(QueryHash(other))}, wantCode: CodePersistedQueryNotFound},
+		{name: "hash of another query", params: graphql.RawParams{Query: other, Extensions: persisted(hash)}, wantErr: true},
+		{name: "full query", params: graphql.RawParams{Query: other}, wantQuery: other},
+		{name: "full registered query when strict", strict: true, params: graphql.RawParams{Query: registered}, wantQuery: registered},
+		{name: "full query when strict", strict: true, params: graphql.RawParams{Query: other}, wantCode: CodePersistedQueryOnly},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := tt.params
+			err := PersistedQueries{Queries: queries, Strict: tt.strict}.MutateOperationParameters(context.Background(), &params)
+			switch {
+			case tt.wantCode != "":
+				if err == nil || err.Extensions["code"] != tt.wantCode {
+					t.Fatalf("got error %v, want code %s", err, tt.wantCode)
+				}
+			case tt.wantErr:
+				if err == nil {
+					t.Fatal("got no error")
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case params.Query != tt.wantQuery:
+				t.Errorf("query = %q, want %q", params.Query, tt.wantQuery)
+			}
+		})
+	}
+}
+
+func TestLoadManifest(t *testing.T) {
+	dir := t.TempDir()
+	query := `{ category { name } }`
+
+	good := filepath.Join(dir, "good.json")
+	if err := os.WriteFile(good, []byte(`{"`+QueryHash(query)+`": "{ category { name } }"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	queries, err := LoadManifest(good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queries[QueryHash(query)] != query {
+		t.Errorf("got %v", queries)
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte(`{"`+QueryHash("{ other }")+`": "{ category { name } }"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadManifest(bad); err == nil {
+		t.Error("loaded a manifest whose hash doesn't match its query")
+	}
+}
@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Postgres keeps buckets in the rate_limit_buckets table, so every replica
+// connected to the database counts against the same limits
+type Postgres struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgres drops refilled buckets every minute until ctx is done
+func NewPostgres(ctx context.Context, pool *pgxpool.Pool) *Postgres {
+	p := &Postgres{pool: pool}
+	go p.sweep(ctx)
+	return p
+}
+
+func (p *Postgres) Take(ctx context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return false, 0, fmt.Errorf("begin rate limit tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// make sure the row exists so concurrent requests queue on its lock
+	_, err = tx.Exec(ctx, `
+		INSERT INTO rate_limit_buckets (key, tokens, updated_at, full_at)
+		VALUES ($1, $2, NOW(), NOW())
+		ON CONFLICT (key) DO NOTHING`, key, float64(limit.Requests))
+	if err != nil {
+		return false, 0, fmt.Errorf("create rate limit bucket: %w", err)
+	}
+
+	var b bucket
+	err = tx.QueryRow(ctx, `
+		SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE`, key,
+	).Scan(&b.tokens, &b.updated)
+	if err != nil {
+		return false, 0, fmt.Errorf("get rate limit bucket: %w", err)
+	}
+
+	ok, retryAfter := b.take(time.Now(), limit)
+	_, err = tx.Exec(ctx, `
+		UPDATE rate_limit_buckets SET tokens = $2, updated_at = $3, full_at = $4 WHERE key = $1`,
+		key, b.tokens, b.updated, b.fullAt)
+	if err != nil {
+		return false, 0, fmt.Errorf("update rate limit bucket: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, 0, fmt.Errorf("commit rate limit tx: %w", err)
+	}
+	return ok, retryAfter, nil
+}
+
+func (p *Postgres) sweep(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := p.pool.Exec(ctx, `DELETE FROM rate_limit_buckets WHERE full_at < NOW()`); err != nil && ctx.Err() == nil {
+			log.Printf("ratelimit: dropping refilled buckets failed: %v", err)
+		}
+	}
+}
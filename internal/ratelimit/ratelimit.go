@@ -0,0 +1,176 @@
+// Package ratelimit throttles clients with token buckets. Each key, such as a
+// client and the operation it calls, has a bucket holding up to
+// Limit.Requests tokens that refills evenly over Limit.Per. Every request
+// takes a token and is turned away while the bucket is empty.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit allows Requests requests per Per, all at once or spread out
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// perSecond is how fast the bucket refills
+func (l Limit) perSecond() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// ParseLimit reads a limit written like "5/1m", five requests a minute
+func ParseLimit(s string) (Limit, error) {
+	requests, per, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: want requests/duration like 5/1m", s)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive number", s)
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: duration must be positive, like 1m or 1h", s)
+	}
+	return Limit{Requests: n, Per: d}, nil
+}
+
+// UnmarshalText reads a limit written like ParseLimit takes it
+func (l *Limit) UnmarshalText(text []byte) error {
+	limit, err := ParseLimit(string(text))
+	if err != nil {
+		return err
+	}
+	*l = limit
+	return nil
+}
+
+// Limits are limits by name, such as the GraphQL field they apply to
+type Limits map[string]Limit
+
+// ParseLimits reads comma separated name=limit pairs like
+// "customerLogin=5/1m,applyCoupon=20/1m"
+func ParseLimits(s string) (Limits, error) {
+	limits := Limits{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid rate limit %q: want name=requests/duration", pair)
+		}
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return nil, err
+		}
+		limits[strings.TrimSpace(name)] = limit
+	}
+	return limits, nil
+}
+
+// UnmarshalText reads limits written like ParseLimits takes them, adding to
+// or replacing the limits already in l
+func (l *Limits) UnmarshalText(text []byte) error {
+	limits, err := ParseLimits(string(text))
+	if err != nil {
+		return err
+	}
+	if *l == nil {
+		*l = Limits{}
+	}
+	maps.Copy(*l, limits)
+	return nil
+}
+
+// Store keeps the buckets
+type Store interface {
+	// Take takes a token from key's bucket. When there is none it reports
+	// false and how long until there will be.
+	Take(ctx context.Context, key string, limit Limit) (ok bool, retryAfter time.Duration, err error)
+}
+
+// bucket is the state of one key. A bucket that has refilled is the same as
+// no bucket at all, so stores can drop it after fullAt.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	fullAt  time.Time
+}
+
+// take refills b for the time since it was last updated, then takes a token
+// if there is a whole one
+func (b *bucket) take(now time.Time, limit Limit) (ok bool, retryAfter time.Duration) {
+	rate := limit.perSecond()
+	capacity := float64(limit.Requests)
+	if b.updated.IsZero() {
+		b.tokens = capacity
+	} else {
+		// clocks on different replicas can disagree a little
+		elapsed := max(now.Sub(b.updated), 0)
+		b.tokens = min(capacity, b.tokens+elapsed.Seconds()*rate)
+	}
+	b.updated = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		ok = true
+	} else {
+		retryAfter = seconds((1 - b.tokens) / rate)
+	}
+	b.fullAt = now.Add(seconds((capacity - b.tokens) / rate))
+	return ok, retryAfter
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// sweepInterval is how often idle buckets are dropped
+const sweepInterval = time.Minute
+
+// Memory keeps buckets in this process, so each replica counts on its own
+type Memory struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+func (m *Memory) Take(ctx context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		for k, b := range m.buckets {
+			if !now.Before(b.fullAt) {
+				delete(m.buckets, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	b, found := m.buckets[key]
+	if !found {
+		b = &bucket{}
+		m.buckets[key] = b
+	}
+	ok, retryAfter := b.take(now, limit)
+	return ok, retryAfter, nil
+}
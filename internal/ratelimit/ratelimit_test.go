@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("5/1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit != (Limit{Requests: 5, Per: time.Minute}) {
+		t.Errorf("got %v", limit)
+	}
+	for _, bad := range []string{"", "5", "0/1m", "five/1m", "5/soon", "5/-1m"} {
+		if _, err := ParseLimit(bad); err == nil {
+			t.Errorf("ParseLimit(%q) accepted", bad)
+		}
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits(" customerLogin=3/1m, applyCoupon=10/1h,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limits) != 2 || limits["customerLogin"].Requests != 3 || limits["applyCoupon"].Per != time.Hour {
+		t.Errorf("got %v", limits)
+	}
+	if _, err := ParseLimits("customerLogin"); err == nil {
+		t.Error("accepted a name without a limit")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+	limit := Limit{Requests: 3, Per: time.Minute}
+
+	for i := range 3 {
+		if ok, _, _ := m.Take(ctx, "a", limit); !ok {
+			t.Fatalf("request %d was turned away", i+1)
+		}
+	}
+	ok, retryAfter, _ := m.Take(ctx, "a", limit)
+	if ok || retryAfter != 20*time.Second {
+		t.Fatalf("4th request: ok %v, retry after %s, want turned away for 20s", ok, retryAfter)
+	}
+	if ok, _, _ := m.Take(ctx, "b", limit); !ok {
+		t.Error("another key was turned away")
+	}
+
+	now = now.Add(20 * time.Second)
+	if ok, _, _ := m.Take(ctx, "a", limit); !ok {
+		t.Error("turned away after a token refilled")
+	}
+	if ok, _, _ := m.Take(ctx, "a", limit); ok {
+		t.Error("let through with the bucket empty again")
+	}
+
+	// a refilled bucket is dropped
+	now = now.Add(2 * time.Minute)
+	m.Take(ctx, "c", limit)
+	if _, found := m.buckets["a"]; found {
+		t.Error("refilled bucket was kept")
+	}
+}
@@ -0,0 +1,172 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AddressRepo struct {
+	DB *pgxpool.Pool
+}
+
+func NewAddressRepo(db *pgxpool.Pool) *AddressRepo {
+	return &AddressRepo{DB: db}
+}
+
+const addressColumns = `id, customer_id, label, full_name, line1, line2, city, region, postal_code,
+	country, phone, is_default_shipping, is_default_billing, created_at`
+
+func scanAddress(row pgx.Row, a *models.CustomerAddress) error {
+	return row.Scan(&a.ID, &a.CustomerID, &a.Label, &a.FullName, &a.Line1, &a.Line2, &a.City, &a.Region, &a.PostalCode,
+		&a.Country, &a.Phone, &a.IsDefaultShipping, &a.IsDefaultBilling, &a.CreatedAt)
+}
+
+// CreateAddress adds an address to the customer's address book. A customer's
+// first address becomes their default shipping and billing address.
+func (r *AddressRepo) CreateAddress(ctx context.Context, a *models.CustomerAddress) (*models.CustomerAddress, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// lock the customer so two first addresses can't both become the default
+	var hasAddresses bool
+	err = tx.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM customer_addresses WHERE customer_id = c.id)
+		 FROM customers c WHERE c.id = $1 FOR UPDATE`, a.CustomerID,
+	).Scan(&hasAddresses)
+	if err != nil {
+		return nil, fmt.Errorf("lock customer: %w", err)
+	}
+
+	shipping := a.IsDefaultShipping || !hasAddresses
+	billing := a.IsDefaultBilling || !hasAddresses
+	if err := clearDefaults(ctx, tx, a.CustomerID, shipping, billing); err != nil {
+		return nil, err
+	}
+
+	var created models.CustomerAddress
+	err = scanAddress(tx.QueryRow(ctx,
+		`INSERT INTO customer_addresses (customer_id, label, full_name, line1, line2, city, region, postal_code,
+			country, phone, is_default_shipping, is_default_billing)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		 RETURNING `+addressColumns,
+		a.CustomerID, a.Label, a.FullName, a.Line1, a.Line2, a.City, a.Region, a.PostalCode,
+		a.Country, a.Phone, shipping, billing,
+	), &created)
+	if err != nil {
+		return nil, fmt.Errorf("create address: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UpdateAddress overwrites one of the customer's addresses. Setting a default
+// flag moves it off the customer's other addresses; clearing one leaves the
+// customer without that default.
+func (r *AddressRepo) UpdateAddress(ctx context.Context, a *models.CustomerAddress) (*models.CustomerAddress, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := clearDefaults(ctx, tx, a.CustomerID, a.IsDefaultShipping, a.IsDefaultBilling); err != nil {
+		return nil, err
+	}
+
+	var updated models.CustomerAddress
+	err = scanAddress(tx.QueryRow(ctx,
+		`UPDATE customer_addresses
+		 SET label = $3, full_name = $4, line1 = $5, line2 = $6, city = $7, region = $8, postal_code = $9,
+		     country = $10, phone = $11, is_default_shipping = $12, is_default_billing = $13
+		 WHERE id = $1 AND customer_id = $2
+		 RETURNING `+addressColumns,
+		a.ID, a.CustomerID, a.Label, a.FullName, a.Line1, a.Line2, a.City, a.Region, a.PostalCode,
+		a.Country, a.Phone, a.IsDefaultShipping, a.IsDefaultBilling,
+	), &updated)
+	if err != nil {
+		return nil, fmt.Errorf("update address: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// get one of a customer's addresses
+func (r *AddressRepo) GetAddress(ctx context.Context, customerID, id int) (*models.CustomerAddress, error) {
+	var a models.CustomerAddress
+	err := scanAddress(r.DB.QueryRow(ctx,
+		`SELECT `+addressColumns+` FROM customer_addresses WHERE id = $1 AND customer_id = $2`,
+		id, customerID,
+	), &a)
+	if err != nil {
+		return nil, rowErr(err, "get address", "address %d not found", id)
+	}
+	return &a, nil
+}
+
+// get a customer's address book, defaults first
+func (r *AddressRepo) ListAddressesByCustomer(ctx context.Context, customerID int) ([]models.CustomerAddress, error) {
+	rows, err := r.DB.Query(ctx,
+		`SELECT `+addressColumns+` FROM customer_addresses WHERE customer_id = $1
+		 ORDER BY is_default_shipping DESC, is_default_billing DESC, id`,
+		customerID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list addresses: %w", err)
+	}
+	defer rows.Close()
+
+	var addresses []models.CustomerAddress
+	for rows.Next() {
+		var a models.CustomerAddress
+		if err := scanAddress(rows, &a); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, rows.Err()
+}
+
+// removes one of a customer's addresses, orders keep their own copy
+func (r *AddressRepo) DeleteAddress(ctx context.Context, customerID, id int) error {
+	cmdTag, err := r.DB.Exec(ctx,
+		`DELETE FROM customer_addresses WHERE id = $1 AND customer_id = $2`, id, customerID)
+	if err != nil {
+		return fmt.Errorf("delete address: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return NotFound("no address found with id %d", id)
+	}
+	return nil
+}
+
+// clearDefaults unsets the customer's current default shipping and/or billing
+// address so another one can take its place
+func clearDefaults(ctx context.Context, tx pgx.Tx, customerID int, shipping, billing bool) error {
+	if !shipping && !billing {
+		return nil
+	}
+	_, err := tx.Exec(ctx,
+		`UPDATE customer_addresses
+		 SET is_default_shipping = is_default_shipping AND NOT $2,
+		     is_default_billing = is_default_billing AND NOT $3
+		 WHERE customer_id = $1`,
+		customerID, shipping, billing,
+	)
+	if err != nil {
+		return fmt.Errorf("clear default addresses: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func TestAddressDefaultsAndOrderSnapshot(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	customerRepo := repo.NewCustomerRepo(db)
+	addressRepo := repo.NewAddressRepo(db)
+	orderRepo := repo.NewOrderRepo(db)
+	productRepo := repo.NewProductRepo(db)
+
+	customer, err := customerRepo.CreateCustomer(ctx, &models.Customer{
+		AuthID:    "auth0|address-test-" + RandString(8),
+		FirstName: "Address",
+		LastName:  "Tester",
+		Email:     "address_tester_" + RandString(8) + "@example.com",
+		Phone:     "+1111111111",
+	})
+	if err != nil {
+		t.Fatalf("failed to create customer: %v", err)
+	}
+
+	home, err := addressRepo.CreateAddress(ctx, &models.CustomerAddress{
+		CustomerID: customer.ID,
+		Address:    models.Address{FullName: "Address Tester", Line1: "1 Moi Avenue", City: "Nairobi", Country: "KE"},
+	})
+	if err != nil {
+		t.Fatalf("CreateAddress failed: %v", err)
+	}
+	if !home.IsDefaultShipping || !home.IsDefaultBilling {
+		t.Errorf("expected the first address to become both defaults, got %+v", home)
+	}
+
+	work, err := addressRepo.CreateAddress(ctx, &models.CustomerAddress{
+		CustomerID:        customer.ID,
+		Address:           models.Address{FullName: "Address Tester", Line1: "2 Kenyatta Avenue", City: "Nairobi", Country: "KE"},
+		IsDefaultShipping: true,
+	})
+	if err != nil {
+		t.Fatalf("CreateAddress failed: %v", err)
+	}
+
+	home, err = addressRepo.GetAddress(ctx, customer.ID, home.ID)
+	if err != nil {
+		t.Fatalf("GetAddress failed: %v", err)
+	}
+	if !work.IsDefaultShipping || home.IsDefaultShipping || !home.IsDefaultBilling {
+		t.Errorf("expected shipping default to move to the new address, got home=%+v work=%+v", home, work)
+	}
+
+	// another customer can't see the address
+	if _, err := addressRepo.GetAddress(ctx, customer.ID+1000000, home.ID); err == nil {
+		t.Error("expected addresses to be scoped to their customer")
+	}
+
+	product, err := productRepo.CreateProduct(ctx, "Parcel", nil, 10, nil)
+	if err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+	order, err := orderRepo.PlaceOrder(ctx, models.OrderDraft{
+		CustomerID:      customer.ID,
+		Items:           []models.OrderItemInput{{ProductID: product.ID, Quantity: 1, Price: 10}},
+		ShippingAddress: &work.Address,
+	})
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+
+	// editing the address book leaves the order's copy alone
+	work.Line1 = "3 Moved Street"
+	if _, err := addressRepo.UpdateAddress(ctx, work); err != nil {
+		t.Fatalf("UpdateAddress failed: %v", err)
+	}
+	if err := addressRepo.DeleteAddress(ctx, customer.ID, work.ID); err != nil {
+		t.Fatalf("DeleteAddress failed: %v", err)
+	}
+
+	fetched, err := orderRepo.GetOrder(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetOrder failed: %v", err)
+	}
+	if fetched.ShippingAddress == nil || fetched.ShippingAddress.Line1 != "2 Kenyatta Avenue" {
+		t.Errorf("expected the order to keep its original address, got %+v", fetched.ShippingAddress)
+	}
+}
@@ -37,7 +37,7 @@ func (r *CategoryRepo) GetCategoryById(ctx context.Context, id int) (*models.Cat
 		id,
 	).Scan(&c.ID, &c.Name, &c.ParentID)
 	if err != nil {
-		return nil, fmt.Errorf("get category: %w", err)
+		return nil, rowErr(err, "get category", "category %d not found", id)
 	}
 	return &c, nil
 }
@@ -60,3 +60,28 @@ func (r *CategoryRepo) ListCategories(ctx context.Context) ([]models.Category, e
 	}
 	return categories, nil
 }
+
+// ListDescendantIDs returns the category's ID together with the IDs of every category below it
+func (r *CategoryRepo) ListDescendantIDs(ctx context.Context, id int) ([]int, error) {
+	rows, err := r.DB.Query(ctx, `
+		WITH RECURSIVE tree AS (
+			SELECT id FROM categories WHERE id = $1
+			UNION ALL
+			SELECT c.id FROM categories c JOIN tree t ON c.parent_id = t.id
+		)
+		SELECT id FROM tree`, id)
+	if err != nil {
+		return nil, fmt.Errorf("list descendant categories: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var childID int
+		if err := rows.Scan(&childID); err != nil {
+			return nil, err
+		}
+		ids = append(ids, childID)
+	}
+	return ids, rows.Err()
+}
@@ -43,7 +43,7 @@ func (r *CustomerRepo) FindCustomerIDByAuth0Sub(ctx context.Context, sub string)
 	var customerID int
 	err := r.DB.QueryRow(ctx, `SELECT id FROM customers WHERE auth_id = $1`, sub).Scan(&customerID)
 	if err != nil {
-		return 0, fmt.Errorf("find customer by auth_id: %w", err)
+		return 0, rowErr(err, "find customer by auth_id", "no customer account is linked to this sign-in")
 	}
 	return customerID, nil
 }
@@ -58,7 +58,7 @@ func (r *CustomerRepo) GetCustomerById(ctx context.Context, id int) (*models.Cus
 		&c.ID, &c.AuthID, &c.FirstName, &c.LastName, &c.Email, &c.Phone, &c.CreatedAt,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("get customer: %w", err)
+		return nil, rowErr(err, "get customer", "customer %d not found", id)
 	}
 	return &c, nil
 }
@@ -73,7 +73,7 @@ func (r *CustomerRepo) GetCustomerByEmail(ctx context.Context, email string) (*m
 		&c.ID, &c.AuthID, &c.FirstName, &c.LastName, &c.Email, &c.Phone, &c.CreatedAt,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("get customer by email: %w", err)
+		return nil, rowErr(err, "get customer by email", "no customer found with email %s", email)
 	}
 	return &c, nil
 }
@@ -107,7 +107,7 @@ func (r *CustomerRepo) FindByEmailOrPhone(ctx context.Context, identifier string
 	var c models.Customer
 	err := r.DB.QueryRow(ctx, query, identifier).Scan(&c.ID, &c.Email, &c.Phone)
 	if err != nil {
-		return nil, err
+		return nil, rowErr(err, "find customer by email or phone", "no customer found with email or phone %s", identifier)
 	}
 	return &c, nil
 }
@@ -0,0 +1,76 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrorKind classifies an Error so clients can handle it without parsing
+// messages. The GraphQL API sends it as the error's extensions.code.
+type ErrorKind string
+
+const (
+	KindNotFound     ErrorKind = "NOT_FOUND"
+	KindConflict     ErrorKind = "CONFLICT"
+	KindValidation   ErrorKind = "VALIDATION"
+	KindUnauthorized ErrorKind = "UNAUTHORIZED"
+	KindOutOfStock   ErrorKind = "OUT_OF_STOCK"
+)
+
+// Error is a domain error. Its message is meant for the people using the API,
+// so it never carries database or other internal details.
+type Error struct {
+	Kind    ErrorKind
+	Message string
+	// Field is the path of the input at fault in validation errors, like
+	// "input.items.0.quantity", or "" when it isn't down to one field
+	Field string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// ErrNotFound matches every NotFound error with errors.Is, whatever its message
+var ErrNotFound = &Error{Kind: KindNotFound, Message: "not found"}
+
+func (e *Error) Is(target error) bool {
+	return target == ErrNotFound && e.Kind == KindNotFound
+}
+
+// rowErr reports a missing row as a NotFound error with the given message and
+// wraps any other error with what was being done
+func rowErr(err error, doing, format string, args ...any) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return NotFound(format, args...)
+	}
+	return fmt.Errorf("%s: %w", doing, err)
+}
+
+// NotFound reports that something the request refers to doesn't exist
+func NotFound(format string, args ...any) error {
+	return &Error{Kind: KindNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+// Conflict reports a request that clashes with the current state, such as a
+// duplicate or a transition the record is past
+func Conflict(format string, args ...any) error {
+	return &Error{Kind: KindConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+// Validation reports bad input, naming the field at fault when there is one
+func Validation(field, format string, args ...any) error {
+	return &Error{Kind: KindValidation, Message: fmt.Sprintf(format, args...), Field: field}
+}
+
+// Unauthorized reports a caller who isn't signed in or may not do this
+func Unauthorized(format string, args ...any) error {
+	return &Error{Kind: KindUnauthorized, Message: fmt.Sprintf(format, args...)}
+}
+
+// OutOfStock reports an order for more than is left
+func OutOfStock(format string, args ...any) error {
+	return &Error{Kind: KindOutOfStock, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,113 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// IdempotencyKeyTTL is how long a key is remembered. Retries after that run
+// the mutation again.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// IdempotencyKeyLease is how long a claimed key without a response counts as
+// in progress. A process that dies mid-mutation, e.g. during a rollout, never
+// completes or releases its key, so after this the key can be claimed again.
+// It is well above the server's write timeout so live requests keep their key.
+const IdempotencyKeyLease = 2 * time.Minute
+
+var (
+	// ErrIdempotencyKeyReused is returned when a key comes back with a different payload
+	ErrIdempotencyKeyReused = Conflict("idempotency key was already used for a different request")
+	// ErrIdempotencyKeyInProgress is returned while the first request with a key is still running
+	ErrIdempotencyKeyInProgress = Conflict("a request with this idempotency key is still in progress")
+)
+
+type IdempotencyRepo struct {
+	DB *pgxpool.Pool
+}
+
+func NewIdempotencyRepo(db *pgxpool.Pool) *IdempotencyRepo {
+	return &IdempotencyRepo{DB: db}
+}
+
+// Claim reserves a key for a request. It returns a nil response when the
+// caller now owns the key and should run the mutation, or the stored response
+// of the earlier request with the same key and payload. Claims that got no
+// response within IdempotencyKeyLease are taken over.
+func (r *IdempotencyRepo) Claim(ctx context.Context, key models.IdempotencyKey) ([]byte, error) {
+	// forget the key if it has expired or its claim was abandoned, so it can
+	// be claimed afresh. Ages are measured by the database clock, the one
+	// created_at was set by.
+	_, err := r.DB.Exec(ctx,
+		`DELETE FROM idempotency_keys
+		 WHERE owner = $1 AND operation = $2 AND key = $3
+		   AND (created_at < NOW() - $4 * INTERVAL '1 second'
+		        OR (response IS NULL AND created_at < NOW() - $5 * INTERVAL '1 second'))`,
+		key.Owner, key.Operation, key.Key, IdempotencyKeyTTL.Seconds(), IdempotencyKeyLease.Seconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("expire idempotency key: %w", err)
+	}
+
+	cmdTag, err := r.DB.Exec(ctx,
+		`INSERT INTO idempotency_keys (owner, operation, key, request_hash)
+		 VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`,
+		key.Owner, key.Operation, key.Key, key.RequestHash,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claim idempotency key: %w", err)
+	}
+	if cmdTag.RowsAffected() == 1 {
+		return nil, nil
+	}
+
+	var requestHash string
+	var response []byte
+	err = r.DB.QueryRow(ctx,
+		`SELECT request_hash, response FROM idempotency_keys
+		 WHERE owner = $1 AND operation = $2 AND key = $3`,
+		key.Owner, key.Operation, key.Key,
+	).Scan(&requestHash, &response)
+	if err != nil {
+		return nil, fmt.Errorf("get idempotency key: %w", err)
+	}
+
+	switch {
+	case requestHash != key.RequestHash:
+		return nil, ErrIdempotencyKeyReused
+	case response == nil:
+		return nil, ErrIdempotencyKeyInProgress
+	}
+	return response, nil
+}
+
+// Complete stores the response of a claimed key for replays
+func (r *IdempotencyRepo) Complete(ctx context.Context, key models.IdempotencyKey, response []byte) error {
+	_, err := r.DB.Exec(ctx,
+		`UPDATE idempotency_keys SET response = $4
+		 WHERE owner = $1 AND operation = $2 AND key = $3`,
+		key.Owner, key.Operation, key.Key, response,
+	)
+	if err != nil {
+		return fmt.Errorf("store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release gives up a claimed key after the mutation failed, so the client
+// can retry with it
+func (r *IdempotencyRepo) Release(ctx context.Context, key models.IdempotencyKey) error {
+	_, err := r.DB.Exec(ctx,
+		`DELETE FROM idempotency_keys
+		 WHERE owner = $1 AND operation = $2 AND key = $3 AND response IS NULL`,
+		key.Owner, key.Operation, key.Key,
+	)
+	if err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
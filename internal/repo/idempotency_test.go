@@ -0,0 +1,85 @@
+package repo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func TestIdempotencyKeyReplaysFirstResponse(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	idempotencyRepo := repo.NewIdempotencyRepo(db)
+
+	key := models.IdempotencyKey{
+		Owner:       "auth0|idempotency-test-" + RandString(8),
+		Operation:   "createOrder",
+		Key:         RandString(16),
+		RequestHash: "first",
+	}
+
+	stored, err := idempotencyRepo.Claim(ctx, key)
+	if err != nil || stored != nil {
+		t.Fatalf("expected to claim a new key, got %s, %v", stored, err)
+	}
+	if _, err := idempotencyRepo.Claim(ctx, key); !errors.Is(err, repo.ErrIdempotencyKeyInProgress) {
+		t.Errorf("expected %v while the first request runs, got %v", repo.ErrIdempotencyKeyInProgress, err)
+	}
+
+	if err := idempotencyRepo.Complete(ctx, key, []byte(`{"id": "42"}`)); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if err := idempotencyRepo.Release(ctx, key); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+
+	stored, err = idempotencyRepo.Claim(ctx, key)
+	if err != nil || string(stored) != `{"id": "42"}` {
+		t.Errorf("expected the stored response to be replayed, got %s, %v", stored, err)
+	}
+
+	other := key
+	other.RequestHash = "second"
+	if _, err := idempotencyRepo.Claim(ctx, other); !errors.Is(err, repo.ErrIdempotencyKeyReused) {
+		t.Errorf("expected %v for a different payload, got %v", repo.ErrIdempotencyKeyReused, err)
+	}
+}
+
+func TestAbandonedIdempotencyKeyCanBeClaimedAgain(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	idempotencyRepo := repo.NewIdempotencyRepo(db)
+
+	key := models.IdempotencyKey{
+		Owner:       "auth0|idempotency-lease-test-" + RandString(8),
+		Operation:   "createOrder",
+		Key:         RandString(16),
+		RequestHash: "first",
+	}
+	if _, err := idempotencyRepo.Claim(ctx, key); err != nil {
+		t.Fatalf("Claim failed: %v", err)
+	}
+
+	// the process holding the key died before completing it
+	_, err := db.Exec(ctx,
+		`UPDATE idempotency_keys SET created_at = created_at - $4 * INTERVAL '1 second'
+		 WHERE owner = $1 AND operation = $2 AND key = $3`,
+		key.Owner, key.Operation, key.Key, (repo.IdempotencyKeyLease + time.Minute).Seconds(),
+	)
+	if err != nil {
+		t.Fatalf("failed to backdate claim: %v", err)
+	}
+
+	stored, err := idempotencyRepo.Claim(ctx, key)
+	if err != nil || stored != nil {
+		t.Errorf("expected the abandoned key to be claimed again, got %s, %v", stored, err)
+	}
+}
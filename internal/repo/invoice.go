@@ -0,0 +1,162 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/invoice"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type InvoiceRepo struct {
+	DB *pgxpool.Pool
+}
+
+func NewInvoiceRepo(db *pgxpool.Pool) *InvoiceRepo {
+	return &InvoiceRepo{DB: db}
+}
+
+const invoiceColumns = `id, order_id, number, tax_rate, tax_amount, storage_key, title, refunded_total, issued_at`
+
+func scanInvoice(row pgx.Row, i *models.Invoice) error {
+	return row.Scan(&i.ID, &i.OrderID, &i.Number, &i.TaxRate, &i.TaxAmount, &i.StorageKey, &i.Title, &i.RefundedTotal, &i.IssuedAt)
+}
+
+// get the invoice issued for an order, pgx.ErrNoRows when there is none yet
+func (r *InvoiceRepo) GetInvoiceByOrder(ctx context.Context, orderID int) (*models.Invoice, error) {
+	var i models.Invoice
+	err := scanInvoice(r.DB.QueryRow(ctx,
+		`SELECT `+invoiceColumns+` FROM invoices WHERE order_id = $1`, orderID,
+	), &i)
+	if err != nil {
+		return nil, fmt.Errorf("get invoice: %w", err)
+	}
+	return &i, nil
+}
+
+// IssueInvoice gives the order the next invoice number of the year and records
+// it with the tax its tax-inclusive total contains at taxRate. store renders
+// and saves the document for the numbered invoice and returns its storage
+// key; when it fails nothing is recorded and the number is not used up.
+//
+// An order keeps its invoice number, but the stored document is rendered again
+// whenever it is out of date: an invoice becomes a receipt once the order is
+// paid, and shows the refunds made since. store is then given the invoice
+// with its current storage key, which it may overwrite.
+func (r *InvoiceRepo) IssueInvoice(ctx context.Context, orderID int, taxRate float64, store func(models.Invoice, models.Order) (string, error)) (*models.Invoice, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// lock the order so two requests can't both issue its invoice, and
+	// refunds can't be added while its document is rendered
+	var order models.Order
+	err = scanOrder(tx.QueryRow(ctx,
+		`SELECT `+orderColumns+` FROM orders WHERE id = $1 FOR UPDATE`, orderID,
+	), &order)
+	if err != nil {
+		return nil, rowErr(err, "get order", "order %d not found", orderID)
+	}
+
+	var refunded float64
+	err = tx.QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount), 0) FROM refunds WHERE order_id = $1`, orderID,
+	).Scan(&refunded)
+	if err != nil {
+		return nil, fmt.Errorf("get refunded total: %w", err)
+	}
+
+	var existing models.Invoice
+	err = scanInvoice(tx.QueryRow(ctx,
+		`SELECT `+invoiceColumns+` FROM invoices WHERE order_id = $1`, orderID,
+	), &existing)
+	if err == nil {
+		// cancelled orders keep the document they were issued
+		if order.Status == models.OrderStatusCancelled ||
+			(existing.Title == invoice.Title(order.Status) && existing.RefundedTotal == refunded) {
+			return &existing, nil
+		}
+		return r.reissue(ctx, tx, existing, order, refunded, store)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("get invoice: %w", err)
+	}
+	if order.Status == models.OrderStatusCancelled {
+		return nil, Conflict("order %d is cancelled", orderID)
+	}
+
+	issuedAt := time.Now()
+	var sequence int
+	err = tx.QueryRow(ctx,
+		`INSERT INTO invoice_counters (year, last_number) VALUES ($1, 1)
+		 ON CONFLICT (year) DO UPDATE SET last_number = invoice_counters.last_number + 1
+		 RETURNING last_number`, issuedAt.Year(),
+	).Scan(&sequence)
+	if err != nil {
+		return nil, fmt.Errorf("next invoice number: %w", err)
+	}
+
+	issued := models.Invoice{
+		OrderID:       orderID,
+		Number:        fmt.Sprintf("INV-%d-%06d", issuedAt.Year(), sequence),
+		TaxRate:       taxRate,
+		TaxAmount:     invoice.IncludedTax(order.Total, taxRate),
+		Title:         invoice.Title(order.Status),
+		RefundedTotal: refunded,
+		IssuedAt:      issuedAt,
+	}
+
+	issued.StorageKey, err = store(issued, order)
+	if err != nil {
+		return nil, err
+	}
+
+	var created models.Invoice
+	err = scanInvoice(tx.QueryRow(ctx,
+		`INSERT INTO invoices (order_id, number, tax_rate, tax_amount, storage_key, title, refunded_total, issued_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING `+invoiceColumns,
+		issued.OrderID, issued.Number, issued.TaxRate, issued.TaxAmount, issued.StorageKey,
+		issued.Title, issued.RefundedTotal, issued.IssuedAt,
+	), &created)
+	if err != nil {
+		return nil, fmt.Errorf("create invoice: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// reissue renders an order's invoice again, under the same number, for the
+// order as it stands now
+func (r *InvoiceRepo) reissue(ctx context.Context, tx pgx.Tx, inv models.Invoice, order models.Order, refunded float64, store func(models.Invoice, models.Order) (string, error)) (*models.Invoice, error) {
+	inv.Title = invoice.Title(order.Status)
+	inv.RefundedTotal = refunded
+
+	key, err := store(inv, order)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated models.Invoice
+	err = scanInvoice(tx.QueryRow(ctx,
+		`UPDATE invoices SET storage_key = $2, title = $3, refunded_total = $4
+		 WHERE id = $1 RETURNING `+invoiceColumns,
+		inv.ID, key, inv.Title, inv.RefundedTotal,
+	), &updated)
+	if err != nil {
+		return nil, fmt.Errorf("update invoice: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
@@ -0,0 +1,170 @@
+package repo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func TestInvoiceNumbersRunWithoutGaps(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	customerRepo := repo.NewCustomerRepo(db)
+	productRepo := repo.NewProductRepo(db)
+	orderRepo := repo.NewOrderRepo(db)
+	invoiceRepo := repo.NewInvoiceRepo(db)
+
+	customer, err := customerRepo.CreateCustomer(ctx, &models.Customer{
+		AuthID:    "auth0|invoice-test-" + RandString(8),
+		FirstName: "Invoice",
+		LastName:  "Tester",
+		Email:     "invoice_tester_" + RandString(8) + "@example.com",
+		Phone:     "+1111111111",
+	})
+	if err != nil {
+		t.Fatalf("failed to create customer: %v", err)
+	}
+	product, err := productRepo.CreateProduct(ctx, "Desk", nil, 116, nil)
+	if err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	placeOrder := func() *models.Order {
+		order, err := orderRepo.CreateOrder(ctx, customer.ID, []models.OrderItemInput{
+			{ProductID: product.ID, Quantity: 1, Price: 116},
+		})
+		if err != nil {
+			t.Fatalf("CreateOrder failed: %v", err)
+		}
+		return order
+	}
+	store := func(inv models.Invoice, _ models.Order) (string, error) {
+		return "invoices/" + inv.Number + ".pdf", nil
+	}
+
+	first, second := placeOrder(), placeOrder()
+
+	if _, err := invoiceRepo.IssueInvoice(ctx, first.ID, 0.16, func(models.Invoice, models.Order) (string, error) {
+		return "", errors.New("storage is down")
+	}); err == nil {
+		t.Fatal("expected a failed upload to fail the invoice")
+	}
+
+	firstInvoice, err := invoiceRepo.IssueInvoice(ctx, first.ID, 0.16, store)
+	if err != nil {
+		t.Fatalf("IssueInvoice failed: %v", err)
+	}
+	if firstInvoice.TaxAmount != 16 {
+		t.Errorf("expected 16 of tax in 116, got %v", firstInvoice.TaxAmount)
+	}
+
+	again, err := invoiceRepo.IssueInvoice(ctx, first.ID, 0.16, store)
+	if err != nil {
+		t.Fatalf("IssueInvoice failed: %v", err)
+	}
+	if again.Number != firstInvoice.Number {
+		t.Errorf("expected the same invoice again, got %s and %s", firstInvoice.Number, again.Number)
+	}
+
+	secondInvoice, err := invoiceRepo.IssueInvoice(ctx, second.ID, 0.16, store)
+	if err != nil {
+		t.Fatalf("IssueInvoice failed: %v", err)
+	}
+
+	var year, n int
+	if _, err := fmt.Sscanf(firstInvoice.Number, "INV-%d-%d", &year, &n); err != nil {
+		t.Fatalf("unexpected invoice number %q", firstInvoice.Number)
+	}
+	if want := fmt.Sprintf("INV-%d-%06d", year, n+1); secondInvoice.Number != want {
+		t.Errorf("expected %s after %s, got %s", want, firstInvoice.Number, secondInvoice.Number)
+	}
+}
+
+func TestInvoiceBecomesReceiptOncePaid(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	customerRepo := repo.NewCustomerRepo(db)
+	productRepo := repo.NewProductRepo(db)
+	orderRepo := repo.NewOrderRepo(db)
+	invoiceRepo := repo.NewInvoiceRepo(db)
+
+	customer, err := customerRepo.CreateCustomer(ctx, &models.Customer{
+		AuthID:    "auth0|receipt-test-" + RandString(8),
+		FirstName: "Receipt",
+		LastName:  "Tester",
+		Email:     "receipt_tester_" + RandString(8) + "@example.com",
+		Phone:     "+1111111111",
+	})
+	if err != nil {
+		t.Fatalf("failed to create customer: %v", err)
+	}
+	product, err := productRepo.CreateProduct(ctx, "Lamp", nil, 116, nil)
+	if err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+	order, err := orderRepo.CreateOrder(ctx, customer.ID, []models.OrderItemInput{
+		{ProductID: product.ID, Quantity: 1, Price: 116},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+
+	var rendered []string
+	store := func(inv models.Invoice, _ models.Order) (string, error) {
+		rendered = append(rendered, inv.Title)
+		if inv.StorageKey != "" {
+			return inv.StorageKey, nil
+		}
+		return "invoices/" + inv.Number + ".pdf", nil
+	}
+
+	pending, err := invoiceRepo.IssueInvoice(ctx, order.ID, 0.16, store)
+	if err != nil {
+		t.Fatalf("IssueInvoice failed: %v", err)
+	}
+	if pending.Title != "INVOICE" {
+		t.Errorf("expected an INVOICE for a pending order, got %s", pending.Title)
+	}
+
+	if err := orderRepo.UpdateOrderStatus(ctx, order.ID, models.OrderStatusPaid, nil); err != nil {
+		t.Fatalf("UpdateOrderStatus failed: %v", err)
+	}
+	paid, err := invoiceRepo.IssueInvoice(ctx, order.ID, 0.16, store)
+	if err != nil {
+		t.Fatalf("IssueInvoice failed: %v", err)
+	}
+	if paid.Title != "RECEIPT" {
+		t.Errorf("expected a RECEIPT once the order is paid, got %s", paid.Title)
+	}
+	if paid.Number != pending.Number || paid.StorageKey != pending.StorageKey {
+		t.Errorf("expected the receipt to keep %s at %s, got %s at %s", pending.Number, pending.StorageKey, paid.Number, paid.StorageKey)
+	}
+
+	if _, err := invoiceRepo.IssueInvoice(ctx, order.ID, 0.16, store); err != nil {
+		t.Fatalf("IssueInvoice failed: %v", err)
+	}
+	if len(rendered) != 2 {
+		t.Errorf("expected an up to date receipt not to be rendered again, rendered %v", rendered)
+	}
+
+	if _, err := db.Exec(ctx, `INSERT INTO refunds (order_id, amount) VALUES ($1, 16)`, order.ID); err != nil {
+		t.Fatalf("failed to record a refund: %v", err)
+	}
+	refunded, err := invoiceRepo.IssueInvoice(ctx, order.ID, 0.16, store)
+	if err != nil {
+		t.Fatalf("IssueInvoice failed: %v", err)
+	}
+	if refunded.RefundedTotal != 16 || len(rendered) != 3 {
+		t.Errorf("expected the receipt rendered again with 16 refunded, got %v after %v", refunded.RefundedTotal, rendered)
+	}
+}
@@ -0,0 +1,190 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type MediaRepo struct {
+	DB *pgxpool.Pool
+}
+
+func NewMediaRepo(db *pgxpool.Pool) *MediaRepo {
+	return &MediaRepo{DB: db}
+}
+
+const mediaColumns = `id, product_id, original_key, medium_key, thumbnail_key, content_type,
+	width, height, alt_text, position, is_primary, created_at`
+
+func scanMedia(row pgx.Row, m *models.ProductMedia) error {
+	return row.Scan(&m.ID, &m.ProductID, &m.OriginalKey, &m.MediumKey, &m.ThumbnailKey, &m.ContentType,
+		&m.Width, &m.Height, &m.AltText, &m.Position, &m.IsPrimary, &m.CreatedAt)
+}
+
+// CreateMedia appends an image to the end of the product's gallery. The first
+// image of a product becomes its primary image.
+func (r *MediaRepo) CreateMedia(ctx context.Context, m *models.ProductMedia) (*models.ProductMedia, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// lock the product so concurrent uploads get distinct positions
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM products WHERE id = $1 FOR UPDATE`, m.ProductID); err != nil {
+		return nil, fmt.Errorf("lock product: %w", err)
+	}
+
+	var created models.ProductMedia
+	err = scanMedia(tx.QueryRow(ctx,
+		`INSERT INTO product_media (product_id, original_key, medium_key, thumbnail_key, content_type,
+			width, height, alt_text, position, is_primary)
+		 SELECT $1::int, $2::text, $3::text, $4::text, $5::text, $6::int, $7::int, $8::text,
+			COALESCE(MAX(position) + 1, 0),
+			NOT COALESCE(BOOL_OR(is_primary), FALSE)
+		 FROM product_media WHERE product_id = $1
+		 RETURNING `+mediaColumns,
+		m.ProductID, m.OriginalKey, m.MediumKey, m.ThumbnailKey, m.ContentType,
+		m.Width, m.Height, m.AltText,
+	), &created)
+	if err != nil {
+		return nil, fmt.Errorf("create product media: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// get a product image by ID
+func (r *MediaRepo) GetMedia(ctx context.Context, id int) (*models.ProductMedia, error) {
+	var m models.ProductMedia
+	err := scanMedia(r.DB.QueryRow(ctx,
+		`SELECT `+mediaColumns+` FROM product_media WHERE id = $1`, id,
+	), &m)
+	if err != nil {
+		return nil, rowErr(err, "get product media", "image %d not found", id)
+	}
+	return &m, nil
+}
+
+// get the images of a product in gallery order
+func (r *MediaRepo) ListMediaByProduct(ctx context.Context, productID int) ([]models.ProductMedia, error) {
+	rows, err := r.DB.Query(ctx,
+		`SELECT `+mediaColumns+` FROM product_media WHERE product_id = $1 ORDER BY position, id`,
+		productID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list product media: %w", err)
+	}
+	defer rows.Close()
+
+	var media []models.ProductMedia
+	for rows.Next() {
+		var m models.ProductMedia
+		if err := scanMedia(rows, &m); err != nil {
+			return nil, err
+		}
+		media = append(media, m)
+	}
+	return media, rows.Err()
+}
+
+// makes an image the primary image of its product
+func (r *MediaRepo) SetPrimaryMedia(ctx context.Context, id int) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var productID int
+	if err := tx.QueryRow(ctx, `SELECT product_id FROM product_media WHERE id = $1`, id).Scan(&productID); err != nil {
+		return rowErr(err, "get product media", "image %d not found", id)
+	}
+
+	// clear first, the partial unique index allows only one primary at a time
+	if _, err := tx.Exec(ctx,
+		`UPDATE product_media SET is_primary = FALSE WHERE product_id = $1 AND is_primary`, productID,
+	); err != nil {
+		return fmt.Errorf("clear primary media: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE product_media SET is_primary = TRUE WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("set primary media: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ReorderMedia sets the gallery order of a product. ids must list every image of the product.
+func (r *MediaRepo) ReorderMedia(ctx context.Context, productID int, ids []int) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var count int
+	if err := tx.QueryRow(ctx,
+		`SELECT COUNT(*) FROM product_media WHERE product_id = $1`, productID,
+	).Scan(&count); err != nil {
+		return fmt.Errorf("count product media: %w", err)
+	}
+	if count != len(ids) {
+		return Validation("imageIDs", "expected all %d images of product %d, got %d", count, productID, len(ids))
+	}
+
+	for position, id := range ids {
+		cmdTag, err := tx.Exec(ctx,
+			`UPDATE product_media SET position = $1 WHERE id = $2 AND product_id = $3`,
+			position, id, productID,
+		)
+		if err != nil {
+			return fmt.Errorf("reorder product media: %w", err)
+		}
+		if cmdTag.RowsAffected() == 0 {
+			return Validation("imageIDs", "image %d does not belong to product %d", id, productID)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeleteMedia removes an image and returns it so its files can be cleaned up.
+// When the primary image goes the next image in the gallery takes its place.
+func (r *MediaRepo) DeleteMedia(ctx context.Context, id int) (*models.ProductMedia, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var m models.ProductMedia
+	err = scanMedia(tx.QueryRow(ctx,
+		`DELETE FROM product_media WHERE id = $1 RETURNING `+mediaColumns, id,
+	), &m)
+	if err != nil {
+		return nil, fmt.Errorf("delete product media: %w", err)
+	}
+
+	if m.IsPrimary {
+		_, err := tx.Exec(ctx,
+			`UPDATE product_media SET is_primary = TRUE
+			 WHERE id = (SELECT id FROM product_media WHERE product_id = $1 ORDER BY position, id LIMIT 1)`,
+			m.ProductID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("promote primary media: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
@@ -2,9 +2,15 @@ package repo
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"slices"
+	"time"
 
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/ordernumber"
 	"github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -16,34 +22,112 @@ func NewOrderRepo(db *pgxpool.Pool) *OrderRepo {
 	return &OrderRepo{DB: db}
 }
 
+const orderColumns = `id, order_number, customer_id, order_date, status, subtotal, discount_total, total,
+	shipping_method_id, shipping_method_name, shipping_cost, shipping_address`
+
+// how many fresh order numbers PlaceOrder tries before giving up
+const maxOrderNumberAttempts = 5
+
+func scanOrder(row pgx.Row, o *models.Order) error {
+	return row.Scan(&o.ID, &o.Number, &o.CustomerID, &o.OrderDate, &o.Status, &o.Subtotal, &o.DiscountTotal, &o.Total,
+		&o.ShippingMethodID, &o.ShippingMethodName, &o.ShippingCost, &o.ShippingAddress)
+}
+
 // insert an order with items
 func (r *OrderRepo) CreateOrder(ctx context.Context, customerID int, items []models.OrderItemInput) (*models.Order, error) {
+	return r.PlaceOrder(ctx, models.OrderDraft{CustomerID: customerID, Items: items})
+}
+
+// PlaceOrder inserts the order, its items and discount lines in a single transaction.
+// The order gets a fresh order number for customers to refer to it by.
+// Shipping is added to the total after discounts, which never apply to it.
+// Lines are priced from the catalogue inside the transaction, whatever price the
+// draft carries. Variant stock is reserved as items are inserted, and promotion usage limits are
+// re-checked under a row lock so concurrent checkouts can't redeem a coupon more
+// often than allowed.
+func (r *OrderRepo) PlaceOrder(ctx context.Context, draft models.OrderDraft) (*models.Order, error) {
 	tx, err := r.DB.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback(ctx)
 
-	var order models.Order
-	err = tx.QueryRow(ctx,
-		`INSERT INTO orders (customer_id) VALUES ($1) RETURNING id, customer_id, order_date, status`,
-		customerID,
-	).Scan(&order.ID, &order.CustomerID, &order.OrderDate, &order.Status)
+	if err := checkProductsExist(ctx, tx, draft.Items); err != nil {
+		return nil, err
+	}
+	draft.Items, err = priceItems(ctx, tx, draft.Items)
 	if err != nil {
 		return nil, err
 	}
+	if err := enforceUsageLimits(ctx, tx, draft.CustomerID, draft.Discounts); err != nil {
+		return nil, err
+	}
+
+	var subtotal float64
+	for _, item := range draft.Items {
+		subtotal += float64(item.Quantity) * item.Price
+	}
+	subtotal = roundMoney(subtotal)
+	discountTotal := math.Min(sumDiscounts(draft.Discounts), subtotal)
+
+	var methodID *int
+	var methodName *string
+	var shippingCost float64
+	if draft.Shipping != nil {
+		methodID, methodName = &draft.Shipping.Method.ID, &draft.Shipping.Method.Name
+		shippingCost = draft.Shipping.Cost
+	}
+
+	// draw order numbers until one is free, clashes are rare but possible
+	var order models.Order
+	for attempt := 1; ; attempt++ {
+		number, err := ordernumber.New(time.Now())
+		if err != nil {
+			return nil, err
+		}
+
+		err = scanOrder(tx.QueryRow(ctx,
+			`INSERT INTO orders (order_number, customer_id, subtotal, discount_total, total,
+				shipping_method_id, shipping_method_name, shipping_cost, shipping_address)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			 ON CONFLICT (order_number) DO NOTHING
+			 RETURNING `+orderColumns,
+			number, draft.CustomerID, subtotal, discountTotal, roundMoney(subtotal-discountTotal+shippingCost),
+			methodID, methodName, shippingCost, draft.ShippingAddress,
+		), &order)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, pgx.ErrNoRows) || attempt == maxOrderNumberAttempts {
+			return nil, err
+		}
+	}
+
+	for _, item := range draft.Items {
+		if err := reserveStock(ctx, tx, item); err != nil {
+			return nil, err
+		}
 
-	for _, item := range items {
 		_, err := tx.Exec(ctx,
-			`INSERT INTO order_items (order_id, product_id, quantity, price)
-			 VALUES ($1, $2, $3, $4)`,
-			order.ID, item.ProductID, item.Quantity, item.Price,
+			`INSERT INTO order_items (order_id, product_id, variant_id, quantity, price)
+			 VALUES ($1, $2, $3, $4, $5)`,
+			order.ID, item.ProductID, item.VariantID, item.Quantity, item.Price,
 		)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	if err := insertDiscounts(ctx, tx, order.ID, draft.Discounts); err != nil {
+		return nil, err
+	}
+	if err := recordOrderStatus(ctx, tx, order.ID, order.Status, nil, nil); err != nil {
+		return nil, err
+	}
+	if err := enqueueOrderEvent(ctx, tx, models.WebhookOrderCreated, order.ID, nil); err != nil {
+		return nil, err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return nil, err
 	}
@@ -51,15 +135,99 @@ func (r *OrderRepo) CreateOrder(ctx context.Context, customerID int, items []mod
 	return &order, nil
 }
 
+// ReplaceCouponDiscounts swaps the coupon discount lines of a pending order for
+// the given ones and recalculates the order totals. Automatic discounts are kept.
+func (r *OrderRepo) ReplaceCouponDiscounts(ctx context.Context, orderID int, discounts []models.OrderDiscount) (*models.Order, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var order models.Order
+	err = scanOrder(tx.QueryRow(ctx,
+		`SELECT `+orderColumns+` FROM orders WHERE id = $1 FOR UPDATE`, orderID,
+	), &order)
+	if err != nil {
+		return nil, fmt.Errorf("get order: %w", err)
+	}
+	if order.Status != models.OrderStatusPending {
+		return nil, Conflict("coupons can only be applied to pending orders, order %d is %s", orderID, order.Status)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM order_discounts WHERE order_id = $1 AND code IS NOT NULL`, orderID); err != nil {
+		return nil, fmt.Errorf("remove coupon discounts: %w", err)
+	}
+	if err := enforceUsageLimits(ctx, tx, order.CustomerID, discounts); err != nil {
+		return nil, err
+	}
+	if err := insertDiscounts(ctx, tx, orderID, discounts); err != nil {
+		return nil, err
+	}
+
+	err = scanOrder(tx.QueryRow(ctx,
+		`UPDATE orders o
+		 SET discount_total = LEAST(d.amount, o.subtotal),
+		     total = o.subtotal - LEAST(d.amount, o.subtotal) + o.shipping_cost
+		 FROM (SELECT COALESCE(SUM(amount), 0) AS amount FROM order_discounts WHERE order_id = $1) d
+		 WHERE o.id = $1
+		 RETURNING `+orderColumns, orderID,
+	), &order)
+	if err != nil {
+		return nil, fmt.Errorf("update order totals: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// get the discount lines of an order
+func (r *OrderRepo) ListDiscountsByOrder(ctx context.Context, orderID int) ([]models.OrderDiscount, error) {
+	rows, err := r.DB.Query(ctx,
+		`SELECT id, order_id, promotion_id, code, description, amount
+		 FROM order_discounts WHERE order_id = $1 ORDER BY id`,
+		orderID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list discounts by order: %w", err)
+	}
+	defer rows.Close()
+
+	var discounts []models.OrderDiscount
+	for rows.Next() {
+		var d models.OrderDiscount
+		if err := rows.Scan(&d.ID, &d.OrderID, &d.PromotionID, &d.Code, &d.Description, &d.Amount); err != nil {
+			return nil, err
+		}
+		discounts = append(discounts, d)
+	}
+	return discounts, rows.Err()
+}
+
 // get an order by ID
 func (r *OrderRepo) GetOrder(ctx context.Context, id int) (*models.Order, error) {
 	var o models.Order
-	err := r.DB.QueryRow(ctx,
-		`SELECT id, customer_id, order_date, status FROM orders WHERE id = $1`,
+	err := scanOrder(r.DB.QueryRow(ctx,
+		`SELECT `+orderColumns+` FROM orders WHERE id = $1`,
 		id,
-	).Scan(&o.ID, &o.CustomerID, &o.OrderDate, &o.Status)
+	), &o)
 	if err != nil {
-		return nil, fmt.Errorf("get order: %w", err)
+		return nil, rowErr(err, "get order", "order %d not found", id)
+	}
+	return &o, nil
+}
+
+// get an order by its order number
+func (r *OrderRepo) GetOrderByNumber(ctx context.Context, number string) (*models.Order, error) {
+	var o models.Order
+	err := scanOrder(r.DB.QueryRow(ctx,
+		`SELECT `+orderColumns+` FROM orders WHERE order_number = $1`,
+		number,
+	), &o)
+	if err != nil {
+		return nil, rowErr(err, "get order by number", "order %s not found", number)
 	}
 	return &o, nil
 }
@@ -67,7 +235,7 @@ func (r *OrderRepo) GetOrder(ctx context.Context, id int) (*models.Order, error)
 // get all orders
 func (r *OrderRepo) ListOrders(ctx context.Context) ([]models.Order, error) {
 	rows, err := r.DB.Query(ctx,
-		`SELECT id, customer_id, order_date, status FROM orders`)
+		`SELECT `+orderColumns+` FROM orders`)
 	if err != nil {
 		return nil, fmt.Errorf("list orders: %w", err)
 	}
@@ -76,7 +244,7 @@ func (r *OrderRepo) ListOrders(ctx context.Context) ([]models.Order, error) {
 	var orders []models.Order
 	for rows.Next() {
 		var o models.Order
-		if err := rows.Scan(&o.ID, &o.CustomerID, &o.OrderDate, &o.Status); err != nil {
+		if err := scanOrder(rows, &o); err != nil {
 			return nil, err
 		}
 		orders = append(orders, o)
@@ -87,7 +255,7 @@ func (r *OrderRepo) ListOrders(ctx context.Context) ([]models.Order, error) {
 // get all orders made by a specific customer
 func (r *OrderRepo) ListOrdersByCustomer(ctx context.Context, customerID int) ([]models.Order, error) {
 	rows, err := r.DB.Query(ctx,
-		`SELECT id, customer_id, order_date, status FROM orders WHERE customer_id = $1`,
+		`SELECT `+orderColumns+` FROM orders WHERE customer_id = $1`,
 		customerID,
 	)
 	if err != nil {
@@ -98,7 +266,7 @@ func (r *OrderRepo) ListOrdersByCustomer(ctx context.Context, customerID int) ([
 	var orders []models.Order
 	for rows.Next() {
 		var o models.Order
-		if err := rows.Scan(&o.ID, &o.CustomerID, &o.OrderDate, &o.Status); err != nil {
+		if err := scanOrder(rows, &o); err != nil {
 			return nil, err
 		}
 		orders = append(orders, o)
@@ -106,17 +274,395 @@ func (r *OrderRepo) ListOrdersByCustomer(ctx context.Context, customerID int) ([
 	return orders, nil
 }
 
-// updates the status of a given order
-func (r *OrderRepo) UpdateOrderStatus(ctx context.Context, orderID int, status string) error {
-	cmdTag, err := r.DB.Exec(ctx,
-		`UPDATE orders SET status = $1 WHERE id = $2`,
-		status, orderID,
+// ListCustomerOrders returns one page of a customer's orders, newest first,
+// and how many orders match the filter across all pages
+func (r *OrderRepo) ListCustomerOrders(ctx context.Context, f models.OrderFilter) ([]models.Order, int, error) {
+	const where = `customer_id = $1
+		AND ($2::text IS NULL OR status = $2)
+		AND ($3::timestamp IS NULL OR order_date >= $3)
+		AND ($4::timestamp IS NULL OR order_date < $4)`
+
+	var total int
+	err := r.DB.QueryRow(ctx,
+		`SELECT COUNT(*) FROM orders WHERE `+where,
+		f.CustomerID, f.Status, f.From, f.To,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("count customer orders: %w", err)
+	}
+
+	rows, err := r.DB.Query(ctx,
+		`SELECT `+orderColumns+` FROM orders
+		 WHERE `+where+` AND ($5::int IS NULL OR id < $5)
+		 ORDER BY id DESC LIMIT $6`,
+		f.CustomerID, f.Status, f.From, f.To, f.AfterID, f.Limit,
 	)
 	if err != nil {
+		return nil, 0, fmt.Errorf("list customer orders: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		var o models.Order
+		if err := scanOrder(rows, &o); err != nil {
+			return nil, 0, err
+		}
+		orders = append(orders, o)
+	}
+	return orders, total, rows.Err()
+}
+
+// statuses staff can move an order to by hand, cancelling goes through CancelOrder
+var settableOrderStatuses = []string{
+	models.OrderStatusPending,
+	models.OrderStatusPaid,
+	models.OrderStatusPartiallyShipped,
+	models.OrderStatusShipped,
+	models.OrderStatusDelivered,
+}
+
+// orderTransitions lists the statuses staff can move an order to by hand from
+// each status. Orders only move forward, though steps may be skipped, and
+// cancelled or delivered orders stay put.
+var orderTransitions = map[string][]string{
+	models.OrderStatusPending: {
+		models.OrderStatusPaid, models.OrderStatusPartiallyShipped, models.OrderStatusShipped, models.OrderStatusDelivered,
+	},
+	models.OrderStatusPaid: {
+		models.OrderStatusPartiallyShipped, models.OrderStatusShipped, models.OrderStatusDelivered,
+	},
+	models.OrderStatusPartiallyShipped: {models.OrderStatusShipped, models.OrderStatusDelivered},
+	models.OrderStatusShipped:          {models.OrderStatusDelivered},
+}
+
+// updates the status of a given order and records the change in its history
+func (r *OrderRepo) UpdateOrderStatus(ctx context.Context, orderID int, status string, changedBy *string) error {
+	if status == models.OrderStatusCancelled {
+		return Validation("status", "orders must be cancelled with cancelOrder so their stock is released")
+	}
+	if !slices.Contains(settableOrderStatuses, status) {
+		return Validation("status", "unknown order status %q", status)
+	}
+
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var previous string
+	err = tx.QueryRow(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, orderID).Scan(&previous)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return NotFound("no order found with id %d", orderID)
+	}
+	if err != nil {
+		return fmt.Errorf("get order status: %w", err)
+	}
+	if !slices.Contains(orderTransitions[previous], status) {
+		return Conflict("order %d is %s and can't be moved to %s", orderID, previous, status)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE orders SET status = $1 WHERE id = $2`, status, orderID); err != nil {
 		return fmt.Errorf("update order status: %w", err)
 	}
-	if cmdTag.RowsAffected() == 0 {
-		return fmt.Errorf("no order found with id %d", orderID)
+	if err := recordOrderStatus(ctx, tx, orderID, status, nil, changedBy); err != nil {
+		return err
+	}
+	if err := enqueueOrderEvent(ctx, tx, models.WebhookOrderStatusChanged, orderID, &previous); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// CancelOrder cancels an order that is still pending or paid. Reserved variant
+// stock is put back, the reason is kept in the order's history and a pending
+// refund of the order total is created when the order was paid, which is
+// returned alongside the order.
+func (r *OrderRepo) CancelOrder(ctx context.Context, orderID int, reason string, changedBy *string) (*models.Order, *models.Refund, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var order models.Order
+	err = scanOrder(tx.QueryRow(ctx,
+		`SELECT `+orderColumns+` FROM orders WHERE id = $1 FOR UPDATE`, orderID,
+	), &order)
+	if err != nil {
+		return nil, nil, rowErr(err, "get order", "order %d not found", orderID)
+	}
+	if order.Status != models.OrderStatusPending && order.Status != models.OrderStatusPaid {
+		return nil, nil, Conflict("only pending or paid orders can be cancelled, order %d is %s", orderID, order.Status)
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE product_variants v SET stock = v.stock + reserved.quantity
+		 FROM (
+			SELECT variant_id, SUM(quantity) AS quantity
+			FROM order_items
+			WHERE order_id = $1 AND variant_id IS NOT NULL
+			GROUP BY variant_id
+		 ) reserved
+		 WHERE v.id = reserved.variant_id`, orderID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("release stock: %w", err)
+	}
+
+	previous := order.Status
+	wasPaid := previous == models.OrderStatusPaid
+	err = scanOrder(tx.QueryRow(ctx,
+		`UPDATE orders SET status = $1 WHERE id = $2 RETURNING `+orderColumns,
+		models.OrderStatusCancelled, orderID,
+	), &order)
+	if err != nil {
+		return nil, nil, fmt.Errorf("update order status: %w", err)
+	}
+	if err := recordOrderStatus(ctx, tx, orderID, order.Status, &reason, changedBy); err != nil {
+		return nil, nil, err
+	}
+	if err := enqueueOrderEvent(ctx, tx, models.WebhookOrderStatusChanged, orderID, &previous); err != nil {
+		return nil, nil, err
+	}
+
+	var refund *models.Refund
+	if wasPaid {
+		refundReason := "Order cancelled: " + reason
+		refund, err = insertRefund(ctx, tx, models.Refund{
+			OrderID: orderID,
+			Amount:  order.Total,
+			Reason:  &refundReason,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+	return &order, refund, nil
+}
+
+// get the status history of an order, oldest first
+func (r *OrderRepo) ListStatusHistory(ctx context.Context, orderID int) ([]models.OrderStatusChange, error) {
+	rows, err := r.DB.Query(ctx,
+		`SELECT status, note, changed_by, created_at FROM order_status_history
+		 WHERE order_id = $1 ORDER BY id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("list order status history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []models.OrderStatusChange
+	for rows.Next() {
+		var change models.OrderStatusChange
+		if err := rows.Scan(&change.Status, &change.Note, &change.ChangedBy, &change.CreatedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, change)
+	}
+	return history, rows.Err()
+}
+
+func recordOrderStatus(ctx context.Context, tx pgx.Tx, orderID int, status string, note, changedBy *string) error {
+	_, err := tx.Exec(ctx,
+		`INSERT INTO order_status_history (order_id, status, note, changed_by) VALUES ($1, $2, $3, $4)`,
+		orderID, status, note, changedBy,
+	)
+	if err != nil {
+		return fmt.Errorf("record order status: %w", err)
 	}
 	return nil
 }
+
+// checkProductsExist fails with a NotFound error naming the first ordered
+// product that doesn't exist. The foreign key would stop the order too, but
+// without saying which product was at fault.
+func checkProductsExist(ctx context.Context, tx pgx.Tx, items []models.OrderItemInput) error {
+	ids := make([]int, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ProductID)
+	}
+
+	var missing *int
+	err := tx.QueryRow(ctx,
+		`SELECT MIN(wanted.id) FROM unnest($1::int[]) AS wanted(id)
+		 WHERE NOT EXISTS (SELECT 1 FROM products p WHERE p.id = wanted.id)`, ids,
+	).Scan(&missing)
+	if err != nil {
+		return fmt.Errorf("check products: %w", err)
+	}
+	if missing != nil {
+		return NotFound("product %d not found", *missing)
+	}
+	return nil
+}
+
+// itemPricesQuery returns the catalogue price of each line, in line order:
+// the variant price when the line's variant overrides it, the product price
+// otherwise. Lines whose product doesn't exist come back NULL.
+const itemPricesQuery = `
+	SELECT COALESCE(v.price, p.price)
+	FROM unnest($1::int[], $2::int[]) WITH ORDINALITY AS line(product_id, variant_id, n)
+	LEFT JOIN products p ON p.id = line.product_id
+	LEFT JOIN product_variants v ON v.id = line.variant_id AND v.product_id = line.product_id
+	ORDER BY line.n`
+
+// PriceItems returns a copy of the items priced from the catalogue, for
+// working out discounts and quotes before the order is placed
+func (r *OrderRepo) PriceItems(ctx context.Context, items []models.OrderItemInput) ([]models.OrderItemInput, error) {
+	productIDs, variantIDs := itemIDs(items)
+	rows, err := r.DB.Query(ctx, itemPricesQuery, productIDs, variantIDs)
+	if err != nil {
+		return nil, fmt.Errorf("price items: %w", err)
+	}
+	return scanItemPrices(rows, items)
+}
+
+// priceItems is PriceItems inside the order's transaction
+func priceItems(ctx context.Context, tx pgx.Tx, items []models.OrderItemInput) ([]models.OrderItemInput, error) {
+	productIDs, variantIDs := itemIDs(items)
+	rows, err := tx.Query(ctx, itemPricesQuery, productIDs, variantIDs)
+	if err != nil {
+		return nil, fmt.Errorf("price items: %w", err)
+	}
+	return scanItemPrices(rows, items)
+}
+
+func itemIDs(items []models.OrderItemInput) ([]int, []*int) {
+	productIDs := make([]int, 0, len(items))
+	variantIDs := make([]*int, 0, len(items))
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+		variantIDs = append(variantIDs, item.VariantID)
+	}
+	return productIDs, variantIDs
+}
+
+func scanItemPrices(rows pgx.Rows, items []models.OrderItemInput) ([]models.OrderItemInput, error) {
+	defer rows.Close()
+
+	priced := slices.Clone(items)
+	i := 0
+	for rows.Next() {
+		var price *float64
+		if err := rows.Scan(&price); err != nil {
+			return nil, fmt.Errorf("price items: %w", err)
+		}
+		if price == nil {
+			return nil, NotFound("product %d not found", priced[i].ProductID)
+		}
+		priced[i].Price = *price
+		i++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("price items: %w", err)
+	}
+	return priced, nil
+}
+
+// reserveStock takes the ordered quantity off the item's variant. Products
+// without variants are ordered without one, products with variants must name one.
+func reserveStock(ctx context.Context, tx pgx.Tx, item models.OrderItemInput) error {
+	if item.VariantID == nil {
+		var hasVariants bool
+		err := tx.QueryRow(ctx,
+			`SELECT EXISTS (SELECT 1 FROM product_variants WHERE product_id = $1)`, item.ProductID,
+		).Scan(&hasVariants)
+		if err != nil {
+			return fmt.Errorf("check product variants: %w", err)
+		}
+		if hasVariants {
+			return Validation("input.items", "product %d is sold in variants, a variant ID is required", item.ProductID)
+		}
+		return nil
+	}
+
+	cmdTag, err := tx.Exec(ctx,
+		`UPDATE product_variants SET stock = stock - $1
+		 WHERE id = $2 AND product_id = $3 AND stock >= $1`,
+		item.Quantity, *item.VariantID, item.ProductID,
+	)
+	if err != nil {
+		return fmt.Errorf("reserve stock: %w", err)
+	}
+	if cmdTag.RowsAffected() > 0 {
+		return nil
+	}
+
+	var stock int
+	err = tx.QueryRow(ctx,
+		`SELECT stock FROM product_variants WHERE id = $1 AND product_id = $2`,
+		*item.VariantID, item.ProductID,
+	).Scan(&stock)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Validation("input.items", "variant %d does not belong to product %d", *item.VariantID, item.ProductID)
+	}
+	if err != nil {
+		return fmt.Errorf("reserve stock: %w", err)
+	}
+	return OutOfStock("variant %d is out of stock, %d left", *item.VariantID, stock)
+}
+
+func insertDiscounts(ctx context.Context, tx pgx.Tx, orderID int, discounts []models.OrderDiscount) error {
+	for _, d := range discounts {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO order_discounts (order_id, promotion_id, code, description, amount)
+			 VALUES ($1, $2, $3, $4, $5)`,
+			orderID, d.PromotionID, d.Code, d.Description, d.Amount,
+		)
+		if err != nil {
+			return fmt.Errorf("insert order discount: %w", err)
+		}
+	}
+	return nil
+}
+
+// enforceUsageLimits locks each promotion row and fails if redeeming it once
+// more would exceed its global or per-customer usage limit
+func enforceUsageLimits(ctx context.Context, tx pgx.Tx, customerID int, discounts []models.OrderDiscount) error {
+	for _, d := range discounts {
+		if d.PromotionID == nil {
+			continue
+		}
+
+		var name string
+		var limit, perCustomer *int
+		err := tx.QueryRow(ctx,
+			`SELECT name, usage_limit, usage_limit_per_customer FROM promotions WHERE id = $1 FOR UPDATE`,
+			*d.PromotionID,
+		).Scan(&name, &limit, &perCustomer)
+		if err != nil {
+			return fmt.Errorf("lock promotion: %w", err)
+		}
+		if limit == nil && perCustomer == nil {
+			continue
+		}
+
+		var total, byCustomer int
+		if err := tx.QueryRow(ctx, redemptionCountQuery, *d.PromotionID, customerID).Scan(&total, &byCustomer); err != nil {
+			return fmt.Errorf("count redemptions: %w", err)
+		}
+		if limit != nil && total >= *limit {
+			return Conflict("promotion %q has reached its usage limit", name)
+		}
+		if perCustomer != nil && byCustomer >= *perCustomer {
+			return Conflict("promotion %q has already been used the maximum number of times by this customer", name)
+		}
+	}
+	return nil
+}
+
+func sumDiscounts(discounts []models.OrderDiscount) float64 {
+	var total float64
+	for _, d := range discounts {
+		total += d.Amount
+	}
+	return roundMoney(total)
+}
+
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,102 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func TestCancelOrderReleasesStockAndRefunds(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	customerRepo := repo.NewCustomerRepo(db)
+	productRepo := repo.NewProductRepo(db)
+	variantRepo := repo.NewVariantRepo(db)
+	orderRepo := repo.NewOrderRepo(db)
+
+	customer, err := customerRepo.CreateCustomer(ctx, &models.Customer{
+		AuthID:    "auth0|cancel-test-" + RandString(8),
+		FirstName: "Cancel",
+		LastName:  "Tester",
+		Email:     "cancel_tester_" + RandString(8) + "@example.com",
+		Phone:     "+1111111111",
+	})
+	if err != nil {
+		t.Fatalf("failed to create customer: %v", err)
+	}
+	product, err := productRepo.CreateProduct(ctx, "Backpack", nil, 60, nil)
+	if err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+	variant, err := variantRepo.CreateVariant(ctx, &models.ProductVariant{
+		ProductID: product.ID,
+		SKU:       "BAG-GREY-" + RandString(6),
+		Stock:     3,
+		Options:   []models.VariantOption{{Name: "colour", Value: "grey"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateVariant failed: %v", err)
+	}
+
+	stock := func() int {
+		variants, err := variantRepo.GetVariantsByIDs(ctx, []int{variant.ID})
+		if err != nil || len(variants) != 1 {
+			t.Fatalf("failed to load variant: %v", err)
+		}
+		return variants[0].Stock
+	}
+
+	order, err := orderRepo.CreateOrder(ctx, customer.ID, []models.OrderItemInput{
+		{ProductID: product.ID, VariantID: &variant.ID, Quantity: 2, Price: 60},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+	if got := stock(); got != 1 {
+		t.Fatalf("expected 1 unit left after ordering, got %d", got)
+	}
+
+	if err := orderRepo.UpdateOrderStatus(ctx, order.ID, models.OrderStatusCancelled, nil); err == nil {
+		t.Error("expected cancelling through UpdateOrderStatus to fail")
+	}
+	if err := orderRepo.UpdateOrderStatus(ctx, order.ID, models.OrderStatusPaid, nil); err != nil {
+		t.Fatalf("UpdateOrderStatus failed: %v", err)
+	}
+
+	cancelled, refund, err := orderRepo.CancelOrder(ctx, order.ID, "Ordered the wrong colour", nil)
+	if err != nil {
+		t.Fatalf("CancelOrder failed: %v", err)
+	}
+	if cancelled.Status != models.OrderStatusCancelled {
+		t.Errorf("expected %s, got %s", models.OrderStatusCancelled, cancelled.Status)
+	}
+	if refund == nil || refund.Amount != order.Total {
+		t.Errorf("expected a refund of %.2f, got %+v", order.Total, refund)
+	}
+	if got := stock(); got != 3 {
+		t.Errorf("expected the reserved units back in stock, got %d", got)
+	}
+
+	history, err := orderRepo.ListStatusHistory(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("ListStatusHistory failed: %v", err)
+	}
+	if len(history) != 3 || history[2].Note == nil || *history[2].Note != "Ordered the wrong colour" {
+		t.Errorf("expected pending, paid and cancelled with the reason, got %+v", history)
+	}
+
+	if _, _, err := orderRepo.CancelOrder(ctx, order.ID, "Again", nil); err == nil {
+		t.Error("expected cancelling a cancelled order to fail")
+	}
+	if err := orderRepo.UpdateOrderStatus(ctx, order.ID, models.OrderStatusPending, nil); err == nil {
+		t.Error("expected moving a cancelled order back to pending to fail")
+	}
+	if err := orderRepo.UpdateOrderStatus(ctx, order.ID, models.OrderStatusShipped, nil); err == nil {
+		t.Error("expected shipping a cancelled order to fail")
+	}
+}
@@ -21,9 +21,9 @@ func (r *OrderItemRepo) CreateOrderItem(ctx context.Context, orderID, productID,
 	var item models.OrderItem
 	err := r.DB.QueryRow(ctx,
 		`INSERT INTO order_items (order_id, product_id, quantity, price)
-		 VALUES ($1, $2, $3, $4) RETURNING id, order_id, product_id, quantity, price`,
+		 VALUES ($1, $2, $3, $4) RETURNING id, order_id, product_id, variant_id, quantity, price`,
 		orderID, productID, quantity, price,
-	).Scan(&item.ID, &item.OrderID, &item.ProductID, &item.Quantity, &item.Price)
+	).Scan(&item.ID, &item.OrderID, &item.ProductID, &item.VariantID, &item.Quantity, &item.Price)
 	if err != nil {
 		return nil, fmt.Errorf("create order item: %w", err)
 	}
@@ -33,7 +33,7 @@ func (r *OrderItemRepo) CreateOrderItem(ctx context.Context, orderID, productID,
 // get all items for a specific order
 func (r *OrderItemRepo) GetItemsByOrder(ctx context.Context, orderID int) ([]models.OrderItem, error) {
 	rows, err := r.DB.Query(ctx,
-		`SELECT id, order_id, product_id, quantity, price
+		`SELECT id, order_id, product_id, variant_id, quantity, price
 		 FROM order_items WHERE order_id = $1`,
 		orderID,
 	)
@@ -45,7 +45,7 @@ func (r *OrderItemRepo) GetItemsByOrder(ctx context.Context, orderID int) ([]mod
 	var items []models.OrderItem
 	for rows.Next() {
 		var item models.OrderItem
-		if err := rows.Scan(&item.ID, &item.OrderID, &item.ProductID, &item.Quantity, &item.Price); err != nil {
+		if err := rows.Scan(&item.ID, &item.OrderID, &item.ProductID, &item.VariantID, &item.Quantity, &item.Price); err != nil {
 			return nil, err
 		}
 		items = append(items, item)
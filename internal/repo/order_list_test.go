@@ -0,0 +1,84 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func TestListCustomerOrdersPagesNewestFirst(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	customerRepo := repo.NewCustomerRepo(db)
+	productRepo := repo.NewProductRepo(db)
+	orderRepo := repo.NewOrderRepo(db)
+
+	customer, err := customerRepo.CreateCustomer(ctx, &models.Customer{
+		AuthID:    "auth0|list-test-" + RandString(8),
+		FirstName: "List",
+		LastName:  "Tester",
+		Email:     "list_tester_" + RandString(8) + "@example.com",
+		Phone:     "+1111111111",
+	})
+	if err != nil {
+		t.Fatalf("failed to create customer: %v", err)
+	}
+	product, err := productRepo.CreateProduct(ctx, "Mug", nil, 12, nil)
+	if err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	var ids []int
+	for range 3 {
+		order, err := orderRepo.CreateOrder(ctx, customer.ID, []models.OrderItemInput{
+			{ProductID: product.ID, Quantity: 1, Price: 12},
+		})
+		if err != nil {
+			t.Fatalf("CreateOrder failed: %v", err)
+		}
+		ids = append(ids, order.ID)
+	}
+	if err := orderRepo.UpdateOrderStatus(ctx, ids[1], models.OrderStatusPaid, nil); err != nil {
+		t.Fatalf("UpdateOrderStatus failed: %v", err)
+	}
+
+	page, total, err := orderRepo.ListCustomerOrders(ctx, models.OrderFilter{CustomerID: customer.ID, Limit: 2})
+	if err != nil {
+		t.Fatalf("ListCustomerOrders failed: %v", err)
+	}
+	if total != 3 || len(page) != 2 || page[0].ID != ids[2] || page[1].ID != ids[1] {
+		t.Fatalf("expected orders %d and %d of 3, got %d of %d", ids[2], ids[1], len(page), total)
+	}
+
+	rest, _, err := orderRepo.ListCustomerOrders(ctx, models.OrderFilter{CustomerID: customer.ID, AfterID: &page[1].ID, Limit: 2})
+	if err != nil {
+		t.Fatalf("ListCustomerOrders failed: %v", err)
+	}
+	if len(rest) != 1 || rest[0].ID != ids[0] {
+		t.Errorf("expected only order %d after the first page, got %+v", ids[0], rest)
+	}
+
+	paid := models.OrderStatusPaid
+	filtered, total, err := orderRepo.ListCustomerOrders(ctx, models.OrderFilter{CustomerID: customer.ID, Status: &paid, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListCustomerOrders failed: %v", err)
+	}
+	if total != 1 || len(filtered) != 1 || filtered[0].ID != ids[1] {
+		t.Errorf("expected only the paid order %d, got %d of %d", ids[1], len(filtered), total)
+	}
+
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	_, total, err = orderRepo.ListCustomerOrders(ctx, models.OrderFilter{CustomerID: customer.ID, From: &tomorrow, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListCustomerOrders failed: %v", err)
+	}
+	if total != 0 {
+		t.Errorf("expected no orders from tomorrow on, got %d", total)
+	}
+}
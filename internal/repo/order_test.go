@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/ordernumber"
 	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
 	"github.com/godfreyowidi/simple-ecomm-demo/models"
 )
@@ -71,6 +72,17 @@ func TestCreateOrder(t *testing.T) {
 	if order.OrderDate.IsZero() {
 		t.Error("expected order date to be set")
 	}
+	if _, err := ordernumber.Normalize(order.Number); err != nil {
+		t.Errorf("expected a valid order number, got %q", order.Number)
+	}
+
+	byNumber, err := orderRepo.GetOrderByNumber(ctx, order.Number)
+	if err != nil {
+		t.Fatalf("GetOrderByNumber failed: %v", err)
+	}
+	if byNumber.ID != order.ID {
+		t.Errorf("expected order %d, got %d", order.ID, byNumber.ID)
+	}
 }
 
 // Helper
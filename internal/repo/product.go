@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -16,30 +17,61 @@ func NewProductRepo(db *pgxpool.Pool) *ProductRepo {
 	return &ProductRepo{DB: db}
 }
 
+const productColumns = `id, sku, name, description, price, category_id, weight_kg, length_cm, width_cm, height_cm,
+	rating_count, rating_total`
+
+func scanProduct(row pgx.Row, p *models.Product) error {
+	return row.Scan(&p.ID, &p.SKU, &p.Name, &p.Description, &p.Price, &p.CategoryID,
+		&p.WeightKg, &p.LengthCm, &p.WidthCm, &p.HeightCm, &p.RatingCount, &p.RatingTotal)
+}
+
 // inserts a new product
 func (r *ProductRepo) CreateProduct(ctx context.Context, name string, description *string, price float64, categoryID *int) (*models.Product, error) {
+	return r.InsertProduct(ctx, &models.Product{
+		Name:        name,
+		Description: description,
+		Price:       price,
+		CategoryID:  categoryID,
+	})
+}
+
+// inserts a new product, including its optional SKU, weight and dimensions
+func (r *ProductRepo) InsertProduct(ctx context.Context, product *models.Product) (*models.Product, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
 	var p models.Product
-	err := r.DB.QueryRow(ctx,
-		`INSERT INTO products (name, description, price, category_id)
-		 VALUES ($1, $2, $3, $4)
-		 RETURNING id, name, description, price, category_id`,
-		name, description, price, categoryID,
-	).Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.CategoryID)
+	err = scanProduct(tx.QueryRow(ctx,
+		`INSERT INTO products (sku, name, description, price, category_id, weight_kg, length_cm, width_cm, height_cm)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 RETURNING `+productColumns,
+		product.SKU, product.Name, product.Description, product.Price, product.CategoryID,
+		product.WeightKg, product.LengthCm, product.WidthCm, product.HeightCm,
+	), &p)
 	if err != nil {
 		return nil, fmt.Errorf("create product: %w", err)
 	}
+	if err := enqueueProductEvent(ctx, tx, models.WebhookProductCreated, p.ID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
 	return &p, nil
 }
 
 // get a product by ID
 func (r *ProductRepo) GetProduct(ctx context.Context, id int) (*models.Product, error) {
 	var p models.Product
-	err := r.DB.QueryRow(ctx,
-		`SELECT id, name, description, price, category_id FROM products WHERE id = $1`,
+	err := scanProduct(r.DB.QueryRow(ctx,
+		`SELECT `+productColumns+` FROM products WHERE id = $1`,
 		id,
-	).Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.CategoryID)
+	), &p)
 	if err != nil {
-		return nil, fmt.Errorf("get product: %w", err)
+		return nil, rowErr(err, "get product", "product %d not found", id)
 	}
 	return &p, nil
 }
@@ -47,7 +79,7 @@ func (r *ProductRepo) GetProduct(ctx context.Context, id int) (*models.Product,
 // returns all products
 func (r *ProductRepo) ListProducts(ctx context.Context) ([]models.Product, error) {
 	rows, err := r.DB.Query(ctx,
-		`SELECT id, name, description, price, category_id FROM products`)
+		`SELECT `+productColumns+` FROM products`)
 	if err != nil {
 		return nil, fmt.Errorf("list products: %w", err)
 	}
@@ -56,7 +88,7 @@ func (r *ProductRepo) ListProducts(ctx context.Context) ([]models.Product, error
 	var products []models.Product
 	for rows.Next() {
 		var p models.Product
-		if err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.CategoryID); err != nil {
+		if err := scanProduct(rows, &p); err != nil {
 			return nil, err
 		}
 		products = append(products, p)
@@ -64,6 +96,26 @@ func (r *ProductRepo) ListProducts(ctx context.Context) ([]models.Product, error
 	return products, nil
 }
 
+// returns the products with the given IDs
+func (r *ProductRepo) GetProductsByIDs(ctx context.Context, ids []int) ([]models.Product, error) {
+	rows, err := r.DB.Query(ctx,
+		`SELECT `+productColumns+` FROM products WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("get products by ids: %w", err)
+	}
+	defer rows.Close()
+
+	var products []models.Product
+	for rows.Next() {
+		var p models.Product
+		if err := scanProduct(rows, &p); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
 // returns the average price of products in a category
 func (r *ProductRepo) GetAveragePriceByCategory(ctx context.Context, categoryID int) (float64, error) {
 	var avg float64
@@ -0,0 +1,193 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// CategoryPathSeparator separates the levels of a category path
+const CategoryPathSeparator = "/"
+
+// ProductImport upserts products by SKU inside a single transaction. Every row
+// runs in its own savepoint so a bad row can be reported without aborting the
+// rows after it.
+type ProductImport struct {
+	tx pgx.Tx
+	// category path -> id, only holds categories from rows that succeeded
+	categories map[string]int
+}
+
+// BeginImport starts a product import transaction
+func (r *ProductRepo) BeginImport(ctx context.Context) (*ProductImport, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin import: %w", err)
+	}
+	return &ProductImport{tx: tx, categories: map[string]int{}}, nil
+}
+
+// Upsert inserts the product or updates the one with the same SKU, creating any
+// missing categories on its path. A record without a SKU updates the product
+// with its ID instead. Weights and dimensions the record leaves out keep their
+// current values. It reports whether the product was created.
+func (i *ProductImport) Upsert(ctx context.Context, rec models.ProductRecord) (bool, error) {
+	sp, err := i.tx.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("savepoint: %w", err)
+	}
+	defer sp.Rollback(ctx)
+
+	resolved := map[string]int{}
+	categoryID, err := i.resolveCategoryPath(ctx, sp, rec.CategoryPath, resolved)
+	if err != nil {
+		return false, err
+	}
+
+	var id int
+	var created bool
+	if rec.SKU == "" {
+		err = sp.QueryRow(ctx,
+			`UPDATE products
+			 SET name = $2, description = $3, price = $4, category_id = $5,
+			     weight_kg = COALESCE($6, weight_kg),
+			     length_cm = COALESCE($7, length_cm),
+			     width_cm = COALESCE($8, width_cm),
+			     height_cm = COALESCE($9, height_cm)
+			 WHERE id = $1 AND sku IS NULL
+			 RETURNING id`,
+			rec.ID, rec.Name, rec.Description, rec.Price, categoryID,
+			rec.WeightKg, rec.LengthCm, rec.WidthCm, rec.HeightCm,
+		).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, fmt.Errorf("no product %d without a SKU, give the row a sku", rec.ID)
+		}
+	} else {
+		err = sp.QueryRow(ctx,
+			`INSERT INTO products (sku, name, description, price, category_id, weight_kg, length_cm, width_cm, height_cm)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			 ON CONFLICT (sku) DO UPDATE
+			 SET name = EXCLUDED.name,
+			     description = EXCLUDED.description,
+			     price = EXCLUDED.price,
+			     category_id = EXCLUDED.category_id,
+			     weight_kg = COALESCE(EXCLUDED.weight_kg, products.weight_kg),
+			     length_cm = COALESCE(EXCLUDED.length_cm, products.length_cm),
+			     width_cm = COALESCE(EXCLUDED.width_cm, products.width_cm),
+			     height_cm = COALESCE(EXCLUDED.height_cm, products.height_cm)
+			 RETURNING id, (xmax = 0)`,
+			rec.SKU, rec.Name, rec.Description, rec.Price, categoryID,
+			rec.WeightKg, rec.LengthCm, rec.WidthCm, rec.HeightCm,
+		).Scan(&id, &created)
+	}
+	if err != nil {
+		return false, fmt.Errorf("upsert product: %w", err)
+	}
+
+	event := models.WebhookProductUpdated
+	if created {
+		event = models.WebhookProductCreated
+	}
+	if err := enqueueProductEvent(ctx, sp, event, id); err != nil {
+		return false, err
+	}
+
+	if err := sp.Commit(ctx); err != nil {
+		return false, fmt.Errorf("release savepoint: %w", err)
+	}
+	for path, id := range resolved {
+		i.categories[path] = id
+	}
+	return created, nil
+}
+
+// resolveCategoryPath walks the path from the top of the category tree and
+// creates the levels that don't exist yet. An empty path means no category.
+func (i *ProductImport) resolveCategoryPath(ctx context.Context, tx pgx.Tx, path string, resolved map[string]int) (*int, error) {
+	var parentID *int
+	var walked []string
+
+	for _, name := range strings.Split(path, CategoryPathSeparator) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		walked = append(walked, name)
+		key := strings.Join(walked, CategoryPathSeparator)
+
+		if id, ok := i.categories[key]; ok {
+			parentID = &id
+			continue
+		}
+
+		var id int
+		err := tx.QueryRow(ctx,
+			`SELECT id FROM categories
+			 WHERE name = $1 AND parent_id IS NOT DISTINCT FROM $2
+			 ORDER BY id LIMIT 1`,
+			name, parentID,
+		).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = tx.QueryRow(ctx,
+				`INSERT INTO categories (name, parent_id) VALUES ($1, $2) RETURNING id`,
+				name, parentID,
+			).Scan(&id)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("resolve category %q: %w", key, err)
+		}
+
+		// cached by Upsert once the row's savepoint is released
+		resolved[key] = id
+		parentID = &id
+	}
+	return parentID, nil
+}
+
+// Commit makes the import permanent
+func (i *ProductImport) Commit(ctx context.Context) error {
+	return i.tx.Commit(ctx)
+}
+
+// Rollback discards the import, used for dry runs and failed imports
+func (i *ProductImport) Rollback(ctx context.Context) error {
+	return i.tx.Rollback(ctx)
+}
+
+// ExportProducts streams every product with its category path, weight and
+// dimensions to fn
+func (r *ProductRepo) ExportProducts(ctx context.Context, fn func(models.ProductRecord) error) error {
+	rows, err := r.DB.Query(ctx, `
+		WITH RECURSIVE paths AS (
+			SELECT id, name::text AS path FROM categories WHERE parent_id IS NULL
+			UNION ALL
+			SELECT c.id, p.path || '`+CategoryPathSeparator+`' || c.name
+			FROM categories c JOIN paths p ON c.parent_id = p.id
+		)
+		SELECT p.id, COALESCE(p.sku, ''), p.name, p.description, p.price, COALESCE(paths.path, ''),
+		       p.weight_kg, p.length_cm, p.width_cm, p.height_cm
+		FROM products p
+		LEFT JOIN paths ON paths.id = p.category_id
+		ORDER BY p.id`)
+	if err != nil {
+		return fmt.Errorf("export products: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rec models.ProductRecord
+		err := rows.Scan(&rec.ID, &rec.SKU, &rec.Name, &rec.Description, &rec.Price, &rec.CategoryPath,
+			&rec.WeightKg, &rec.LengthCm, &rec.WidthCm, &rec.HeightCm)
+		if err != nil {
+			return err
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,136 @@
+package repo_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/bulk"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func TestProductImportUpsertsBySKU(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	productRepo := repo.NewProductRepo(db)
+	sku := fmt.Sprintf("IMP-%d", time.Now().UnixNano())
+	path := fmt.Sprintf("Imported %d/Phones", time.Now().UnixNano())
+
+	imp, err := productRepo.BeginImport(ctx)
+	if err != nil {
+		t.Fatalf("BeginImport failed: %v", err)
+	}
+	created, err := imp.Upsert(ctx, models.ProductRecord{SKU: sku, Name: "Phone", Price: 100, CategoryPath: path})
+	if err != nil || !created {
+		t.Fatalf("expected the first upsert to create the product, got created=%v err=%v", created, err)
+	}
+	created, err = imp.Upsert(ctx, models.ProductRecord{SKU: sku, Name: "Phone v2", Price: 120, CategoryPath: path})
+	if err != nil || created {
+		t.Fatalf("expected the second upsert to update the product, got created=%v err=%v", created, err)
+	}
+	if err := imp.Commit(ctx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	var found *models.ProductRecord
+	err = productRepo.ExportProducts(ctx, func(rec models.ProductRecord) error {
+		if rec.SKU == sku {
+			found = &rec
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportProducts failed: %v", err)
+	}
+	if found == nil {
+		t.Fatal("expected the imported product in the export")
+	}
+	if found.Name != "Phone v2" || found.Price != 120 || found.CategoryPath != path {
+		t.Errorf("unexpected exported product: %+v", found)
+	}
+}
+
+func TestProductImportRollback(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	productRepo := repo.NewProductRepo(db)
+	sku := fmt.Sprintf("DRY-%d", time.Now().UnixNano())
+
+	imp, err := productRepo.BeginImport(ctx)
+	if err != nil {
+		t.Fatalf("BeginImport failed: %v", err)
+	}
+	if _, err := imp.Upsert(ctx, models.ProductRecord{SKU: sku, Name: "Dry run", Price: 1}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if err := imp.Rollback(ctx); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+
+	err = productRepo.ExportProducts(ctx, func(rec models.ProductRecord) error {
+		if rec.SKU == sku {
+			t.Errorf("expected rolled back product %s to be gone", sku)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportProducts failed: %v", err)
+	}
+}
+
+func TestProductExportImportsBack(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	productRepo := repo.NewProductRepo(db)
+	sku := fmt.Sprintf("RT-%d", time.Now().UnixNano())
+	weight, length, width, height := 2.5, 30.0, 20.0, 10.0
+
+	withSKU, err := productRepo.InsertProduct(ctx, &models.Product{
+		SKU: &sku, Name: "Kettle", Price: 40, WeightKg: &weight, LengthCm: &length, WidthCm: &width, HeightCm: &height,
+	})
+	if err != nil {
+		t.Fatalf("InsertProduct failed: %v", err)
+	}
+	withoutSKU, err := productRepo.InsertProduct(ctx, &models.Product{Name: "Toaster", Price: 60, WeightKg: &weight})
+	if err != nil {
+		t.Fatalf("InsertProduct failed: %v", err)
+	}
+
+	for _, format := range []string{bulk.FormatCSV, bulk.FormatJSONL} {
+		var file bytes.Buffer
+		if err := bulk.Export(ctx, productRepo, &file, format); err != nil {
+			t.Fatalf("Export %s failed: %v", format, err)
+		}
+		report, err := bulk.Import(ctx, productRepo, &file, format, false)
+		if err != nil {
+			t.Fatalf("Import %s failed: %v", format, err)
+		}
+		if report.Failed > 0 || report.Created > 0 || !report.Committed {
+			t.Fatalf("expected the %s export to import back as updates, got %+v", format, report)
+		}
+
+		kettle, err := productRepo.GetProduct(ctx, withSKU.ID)
+		if err != nil {
+			t.Fatalf("GetProduct failed: %v", err)
+		}
+		if kettle.WeightKg == nil || *kettle.WeightKg != weight || kettle.HeightCm == nil || *kettle.HeightCm != height {
+			t.Errorf("%s round trip lost the kettle's shipping data: %+v", format, kettle)
+		}
+		toaster, err := productRepo.GetProduct(ctx, withoutSKU.ID)
+		if err != nil {
+			t.Fatalf("GetProduct failed: %v", err)
+		}
+		if toaster.SKU != nil || toaster.Name != "Toaster" || toaster.WeightKg == nil || *toaster.WeightKg != weight {
+			t.Errorf("%s round trip changed the toaster: %+v", format, toaster)
+		}
+	}
+}
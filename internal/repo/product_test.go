@@ -2,9 +2,11 @@ package repo_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
 )
 
 func TestCreateProduct(t *testing.T) {
@@ -76,3 +78,27 @@ func TestGetAveragePriceByCategory(t *testing.T) {
 		t.Errorf("Expected average %v, got %v", expected, avg)
 	}
 }
+
+func TestGetMissingProductIsNotFound(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	productRepo := repo.NewProductRepo(db)
+	orderRepo := repo.NewOrderRepo(db)
+
+	_, err := productRepo.GetProduct(ctx, -1)
+	if !errors.Is(err, repo.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing product, got %v", err)
+	}
+	var domainErr *repo.Error
+	if !errors.As(err, &domainErr) || domainErr.Kind != repo.KindNotFound || domainErr.Message != "product -1 not found" {
+		t.Errorf("expected a NOT_FOUND error naming the product, got %#v", err)
+	}
+
+	_, err = orderRepo.CreateOrder(ctx, 1, []models.OrderItemInput{{ProductID: -1, Quantity: 1, Price: 10}})
+	if !errors.Is(err, repo.ErrNotFound) {
+		t.Errorf("expected ordering a missing product to be NOT_FOUND, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PromotionRepo struct {
+	DB *pgxpool.Pool
+}
+
+func NewPromotionRepo(db *pgxpool.Pool) *PromotionRepo {
+	return &PromotionRepo{DB: db}
+}
+
+const promotionColumns = `id, name, code, kind, value, category_id, buy_quantity, get_quantity,
+	min_subtotal, usage_limit, usage_limit_per_customer, starts_at, ends_at, active, created_at`
+
+func scanPromotion(row pgx.Row, p *models.Promotion) error {
+	return row.Scan(
+		&p.ID, &p.Name, &p.Code, &p.Kind, &p.Value, &p.CategoryID, &p.BuyQuantity, &p.GetQuantity,
+		&p.MinSubtotal, &p.UsageLimit, &p.UsageLimitPerCustomer, &p.StartsAt, &p.EndsAt, &p.Active, &p.CreatedAt,
+	)
+}
+
+// new promotion
+func (r *PromotionRepo) CreatePromotion(ctx context.Context, p *models.Promotion) (*models.Promotion, error) {
+	var created models.Promotion
+	err := scanPromotion(r.DB.QueryRow(ctx,
+		`INSERT INTO promotions (name, code, kind, value, category_id, buy_quantity, get_quantity,
+			min_subtotal, usage_limit, usage_limit_per_customer, starts_at, ends_at, active)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		 RETURNING `+promotionColumns,
+		p.Name, p.Code, p.Kind, p.Value, p.CategoryID, p.BuyQuantity, p.GetQuantity,
+		p.MinSubtotal, p.UsageLimit, p.UsageLimitPerCustomer, p.StartsAt, p.EndsAt, p.Active,
+	), &created)
+	if err != nil {
+		return nil, fmt.Errorf("create promotion: %w", err)
+	}
+	return &created, nil
+}
+
+// get a promotion by ID
+func (r *PromotionRepo) GetPromotion(ctx context.Context, id int) (*models.Promotion, error) {
+	var p models.Promotion
+	err := scanPromotion(r.DB.QueryRow(ctx,
+		`SELECT `+promotionColumns+` FROM promotions WHERE id = $1`, id,
+	), &p)
+	if err != nil {
+		return nil, rowErr(err, "get promotion", "promotion %d not found", id)
+	}
+	return &p, nil
+}
+
+// get a coupon by its code
+func (r *PromotionRepo) GetPromotionByCode(ctx context.Context, code string) (*models.Promotion, error) {
+	var p models.Promotion
+	err := scanPromotion(r.DB.QueryRow(ctx,
+		`SELECT `+promotionColumns+` FROM promotions WHERE code = $1`, code,
+	), &p)
+	if err != nil {
+		return nil, rowErr(err, "get promotion by code", "no promotion found with code %q", code)
+	}
+	return &p, nil
+}
+
+// get all promotions
+func (r *PromotionRepo) ListPromotions(ctx context.Context) ([]models.Promotion, error) {
+	return r.list(ctx, `SELECT `+promotionColumns+` FROM promotions ORDER BY id`)
+}
+
+// ListAutomaticPromotions returns the code-less promotions running at the given time
+func (r *PromotionRepo) ListAutomaticPromotions(ctx context.Context, at time.Time) ([]models.Promotion, error) {
+	return r.list(ctx,
+		`SELECT `+promotionColumns+` FROM promotions
+		 WHERE code IS NULL AND active
+		   AND (starts_at IS NULL OR starts_at <= $1)
+		   AND (ends_at IS NULL OR ends_at > $1)
+		 ORDER BY id`,
+		at,
+	)
+}
+
+func (r *PromotionRepo) list(ctx context.Context, query string, args ...any) ([]models.Promotion, error) {
+	rows, err := r.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list promotions: %w", err)
+	}
+	defer rows.Close()
+
+	var promotions []models.Promotion
+	for rows.Next() {
+		var p models.Promotion
+		if err := scanPromotion(rows, &p); err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, p)
+	}
+	return promotions, rows.Err()
+}
+
+// switches a promotion on or off
+func (r *PromotionRepo) SetPromotionActive(ctx context.Context, id int, active bool) error {
+	cmdTag, err := r.DB.Exec(ctx, `UPDATE promotions SET active = $1 WHERE id = $2`, active, id)
+	if err != nil {
+		return fmt.Errorf("set promotion active: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return NotFound("no promotion found with id %d", id)
+	}
+	return nil
+}
+
+// CountRedemptions returns how many orders used the promotion overall and for the given customer
+func (r *PromotionRepo) CountRedemptions(ctx context.Context, promotionID, customerID int) (total int, byCustomer int, err error) {
+	err = r.DB.QueryRow(ctx, redemptionCountQuery, promotionID, customerID).Scan(&total, &byCustomer)
+	if err != nil {
+		return 0, 0, fmt.Errorf("count redemptions: %w", err)
+	}
+	return total, byCustomer, nil
+}
+
+// cancelled orders give their redemptions back
+const redemptionCountQuery = `
+	SELECT COUNT(DISTINCT o.id),
+	       COUNT(DISTINCT o.id) FILTER (WHERE o.customer_id = $2)
+	FROM order_discounts d
+	JOIN orders o ON o.id = d.order_id
+	WHERE d.promotion_id = $1 AND o.status <> 'cancelled'`
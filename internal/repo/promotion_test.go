@@ -0,0 +1,82 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func TestPlaceOrderEnforcesCouponUsageLimit(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	customerRepo := repo.NewCustomerRepo(db)
+	productRepo := repo.NewProductRepo(db)
+	promotionRepo := repo.NewPromotionRepo(db)
+	orderRepo := repo.NewOrderRepo(db)
+
+	customer, err := customerRepo.CreateCustomer(ctx, &models.Customer{
+		AuthID:    "auth0|promo-test-" + RandString(8),
+		FirstName: "Promo",
+		LastName:  "Tester",
+		Email:     "promo_tester_" + RandString(8) + "@example.com",
+		Phone:     "+1111111111",
+	})
+	if err != nil {
+		t.Fatalf("failed to create customer: %v", err)
+	}
+
+	product, err := productRepo.CreateProduct(ctx, "Promo Product", nil, 100, nil)
+	if err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	code := "ONCE-" + RandString(6)
+	limit := 1
+	promotion, err := promotionRepo.CreatePromotion(ctx, &models.Promotion{
+		Name:                  "Ten percent once",
+		Code:                  &code,
+		Kind:                  models.PromotionPercentage,
+		Value:                 10,
+		UsageLimitPerCustomer: &limit,
+		Active:                true,
+	})
+	if err != nil {
+		t.Fatalf("CreatePromotion failed: %v", err)
+	}
+
+	draft := models.OrderDraft{
+		CustomerID: customer.ID,
+		Items:      []models.OrderItemInput{{ProductID: product.ID, Quantity: 1, Price: product.Price}},
+		Discounts: []models.OrderDiscount{{
+			PromotionID: &promotion.ID,
+			Code:        promotion.Code,
+			Description: promotion.Name,
+			Amount:      10,
+		}},
+	}
+
+	order, err := orderRepo.PlaceOrder(ctx, draft)
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if order.Subtotal != 100 || order.DiscountTotal != 10 || order.Total != 90 {
+		t.Errorf("unexpected totals: %+v", order)
+	}
+
+	if _, err := orderRepo.PlaceOrder(ctx, draft); err == nil {
+		t.Error("expected second redemption to exceed the per-customer limit")
+	}
+
+	total, byCustomer, err := promotionRepo.CountRedemptions(ctx, promotion.ID, customer.ID)
+	if err != nil {
+		t.Fatalf("CountRedemptions failed: %v", err)
+	}
+	if total != 1 || byCustomer != 1 {
+		t.Errorf("expected 1 redemption, got total=%d byCustomer=%d", total, byCustomer)
+	}
+}
@@ -0,0 +1,90 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type RefundRepo struct {
+	DB *pgxpool.Pool
+}
+
+func NewRefundRepo(db *pgxpool.Pool) *RefundRepo {
+	return &RefundRepo{DB: db}
+}
+
+const refundColumns = `id, order_id, return_id, amount, reason, status, created_at, processed_at`
+
+func scanRefund(row pgx.Row, r *models.Refund) error {
+	return row.Scan(&r.ID, &r.OrderID, &r.ReturnID, &r.Amount, &r.Reason, &r.Status, &r.CreatedAt, &r.ProcessedAt)
+}
+
+// get the refunds of an order, oldest first
+func (r *RefundRepo) ListRefundsByOrder(ctx context.Context, orderID int) ([]models.Refund, error) {
+	rows, err := r.DB.Query(ctx,
+		`SELECT `+refundColumns+` FROM refunds WHERE order_id = $1 ORDER BY id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("list refunds by order: %w", err)
+	}
+	defer rows.Close()
+
+	var refunds []models.Refund
+	for rows.Next() {
+		var refund models.Refund
+		if err := scanRefund(rows, &refund); err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, refund)
+	}
+	return refunds, rows.Err()
+}
+
+// ProcessRefund marks a pending refund as paid out
+func (r *RefundRepo) ProcessRefund(ctx context.Context, id int) (*models.Refund, error) {
+	var refund models.Refund
+	err := scanRefund(r.DB.QueryRow(ctx,
+		`UPDATE refunds SET status = $2, processed_at = CURRENT_TIMESTAMP
+		 WHERE id = $1 AND status = $3
+		 RETURNING `+refundColumns,
+		id, models.RefundProcessed, models.RefundPending,
+	), &refund)
+	if err != nil {
+		return nil, fmt.Errorf("process refund %d, it may not exist or was already processed: %w", id, err)
+	}
+	return &refund, nil
+}
+
+// insertRefund records a pending refund inside the caller's transaction. The
+// amount is capped so an order never refunds more than its total, and the
+// order row is locked first so concurrent refunds can't both pass the cap.
+func insertRefund(ctx context.Context, tx pgx.Tx, refund models.Refund) (*models.Refund, error) {
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM orders WHERE id = $1 FOR UPDATE`, refund.OrderID); err != nil {
+		return nil, fmt.Errorf("lock order: %w", err)
+	}
+
+	var total, refunded float64
+	err := tx.QueryRow(ctx,
+		`SELECT o.total, COALESCE((SELECT SUM(amount) FROM refunds WHERE order_id = o.id), 0)
+		 FROM orders o WHERE o.id = $1`, refund.OrderID,
+	).Scan(&total, &refunded)
+	if err != nil {
+		return nil, fmt.Errorf("get refundable amount: %w", err)
+	}
+	amount := roundMoney(math.Max(0, math.Min(refund.Amount, total-refunded)))
+
+	var created models.Refund
+	err = scanRefund(tx.QueryRow(ctx,
+		`INSERT INTO refunds (order_id, return_id, amount, reason)
+		 VALUES ($1, $2, $3, $4) RETURNING `+refundColumns,
+		refund.OrderID, refund.ReturnID, amount, refund.Reason,
+	), &created)
+	if err != nil {
+		return nil, fmt.Errorf("create refund: %w", err)
+	}
+	return &created, nil
+}
@@ -0,0 +1,345 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultReturnWindowDays is how long after delivery items can be returned
+// when no other window is configured
+const DefaultReturnWindowDays = 14
+
+// returnTransitions lists the statuses a return can move to from each status
+var returnTransitions = map[string][]string{
+	models.ReturnRequested: {models.ReturnApproved, models.ReturnRejected},
+	models.ReturnApproved:  {models.ReturnReceived},
+}
+
+type ReturnRepo struct {
+	DB *pgxpool.Pool
+}
+
+func NewReturnRepo(db *pgxpool.Pool) *ReturnRepo {
+	return &ReturnRepo{DB: db}
+}
+
+const returnColumns = `id, order_id, reason, status, created_at`
+
+func scanReturn(row pgx.Row, r *models.Return) error {
+	return row.Scan(&r.ID, &r.OrderID, &r.Reason, &r.Status, &r.CreatedAt)
+}
+
+// CreateReturn opens a return for delivered items of an order. Each item must
+// have arrived within the last windowDays days, and no more units can be
+// returned than were delivered, counting earlier returns that weren't rejected.
+// Items of an order marked delivered without a delivered shipment count as
+// arriving, in full, when the order was marked.
+func (r *ReturnRepo) CreateReturn(ctx context.Context, ret *models.Return, windowDays int, changedBy *string) (*models.Return, error) {
+	if len(ret.Items) == 0 {
+		return nil, Validation("input.items", "a return needs at least one item")
+	}
+
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// lock the order so concurrent requests can't return the same units twice
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM orders WHERE id = $1 FOR UPDATE`, ret.OrderID); err != nil {
+		return nil, fmt.Errorf("lock order: %w", err)
+	}
+
+	for _, item := range ret.Items {
+		var delivered, returned int
+		var deadline *time.Time
+		var windowClosed bool
+		err := tx.QueryRow(ctx, `
+			WITH shipped AS (
+				SELECT COALESCE(SUM(si.quantity), 0) AS quantity, MAX(s.delivered_at) AS delivered_at
+				FROM shipment_items si
+				JOIN shipments s ON s.id = si.shipment_id
+				WHERE si.order_item_id = $1 AND s.delivered_at IS NOT NULL
+			),
+			-- orders staff marked delivered without shipments arrived when marked
+			marked AS (
+				SELECT MAX(h.created_at) AS delivered_at
+				FROM order_status_history h
+				JOIN orders o ON o.id = h.order_id
+				WHERE h.order_id = $2 AND h.status = $5 AND o.status = $5
+			),
+			delivery AS (
+				SELECT s.delivered_at IS NULL AND m.delivered_at IS NOT NULL AS marked_only,
+				       s.quantity, COALESCE(s.delivered_at, m.delivered_at) AS delivered_at
+				FROM shipped s, marked m
+			)
+			SELECT CASE WHEN d.marked_only THEN oi.quantity ELSE d.quantity END,
+			       (SELECT COALESCE(SUM(ri.quantity), 0)
+			        FROM return_items ri JOIN returns rt ON rt.id = ri.return_id
+			        WHERE ri.order_item_id = oi.id AND rt.status <> $4),
+			       d.delivered_at + $3 * INTERVAL '1 day',
+			       COALESCE(d.delivered_at + $3 * INTERVAL '1 day' < LOCALTIMESTAMP, FALSE)
+			FROM order_items oi, delivery d
+			WHERE oi.id = $1 AND oi.order_id = $2`,
+			item.OrderItemID, ret.OrderID, windowDays, models.ReturnRejected, models.OrderStatusDelivered,
+		).Scan(&delivered, &returned, &deadline, &windowClosed)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, Validation("input.items", "order item %d does not belong to order %d", item.OrderItemID, ret.OrderID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("check returnable quantity: %w", err)
+		}
+
+		switch {
+		case deadline == nil:
+			return nil, Conflict("order item %d has not been delivered yet", item.OrderItemID)
+		case windowClosed:
+			return nil, Conflict("the %d-day return window for order item %d closed on %s",
+				windowDays, item.OrderItemID, deadline.Format("2006-01-02"))
+		case item.Quantity > delivered-returned:
+			return nil, Validation("input.items", "order item %d has %d units that can be returned, got %d",
+				item.OrderItemID, delivered-returned, item.Quantity)
+		}
+	}
+
+	var created models.Return
+	err = scanReturn(tx.QueryRow(ctx,
+		`INSERT INTO returns (order_id, reason) VALUES ($1, $2) RETURNING `+returnColumns,
+		ret.OrderID, ret.Reason,
+	), &created)
+	if err != nil {
+		return nil, fmt.Errorf("create return: %w", err)
+	}
+
+	for _, item := range ret.Items {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO return_items (return_id, order_item_id, quantity) VALUES ($1, $2, $3)`,
+			created.ID, item.OrderItemID, item.Quantity,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("create return item: %w", err)
+		}
+	}
+	if err := recordReturnStatus(ctx, tx, created.ID, created.Status, nil, changedBy); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return r.GetReturn(ctx, created.ID)
+}
+
+// approves a requested return
+func (r *ReturnRepo) ApproveReturn(ctx context.Context, id int, note, changedBy *string) (*models.Return, error) {
+	return r.transition(ctx, id, models.ReturnApproved, note, changedBy, nil)
+}
+
+// rejects a requested return
+func (r *ReturnRepo) RejectReturn(ctx context.Context, id int, note, changedBy *string) (*models.Return, error) {
+	return r.transition(ctx, id, models.ReturnRejected, note, changedBy, nil)
+}
+
+// ReceiveReturn records that the items of an approved return are back. The
+// units go back into variant stock when restock is set, and a pending refund
+// for the items is created, net of their share of the order's discounts.
+func (r *ReturnRepo) ReceiveReturn(ctx context.Context, id int, restock bool, note, changedBy *string) (*models.Return, error) {
+	return r.transition(ctx, id, models.ReturnReceived, note, changedBy, func(tx pgx.Tx, ret *models.Return) error {
+		if restock {
+			_, err := tx.Exec(ctx,
+				`UPDATE product_variants v SET stock = v.stock + returned.quantity
+				 FROM (
+					SELECT oi.variant_id, SUM(ri.quantity) AS quantity
+					FROM return_items ri
+					JOIN order_items oi ON oi.id = ri.order_item_id
+					WHERE ri.return_id = $1 AND oi.variant_id IS NOT NULL
+					GROUP BY oi.variant_id
+				 ) returned
+				 WHERE v.id = returned.variant_id`, ret.ID)
+			if err != nil {
+				return fmt.Errorf("restock returned items: %w", err)
+			}
+		}
+
+		// order discounts are spread over the lines in proportion to their
+		// value, so each unit is refunded at what was actually paid for it
+		var amount float64
+		err := tx.QueryRow(ctx,
+			`SELECT COALESCE(SUM(ri.quantity * oi.price *
+			        CASE WHEN o.subtotal > 0 THEN 1 - o.discount_total / o.subtotal ELSE 0 END), 0)
+			 FROM return_items ri
+			 JOIN order_items oi ON oi.id = ri.order_item_id
+			 JOIN orders o ON o.id = oi.order_id
+			 WHERE ri.return_id = $1`, ret.ID,
+		).Scan(&amount)
+		if err != nil {
+			return fmt.Errorf("get return value: %w", err)
+		}
+
+		reason := fmt.Sprintf("Return #%d", ret.ID)
+		_, err = insertRefund(ctx, tx, models.Refund{
+			OrderID:  ret.OrderID,
+			ReturnID: &ret.ID,
+			Amount:   amount,
+			Reason:   &reason,
+		})
+		return err
+	})
+}
+
+// transition moves a return to a new status, running apply inside the same
+// transaction when given
+func (r *ReturnRepo) transition(ctx context.Context, id int, status string, note, changedBy *string, apply func(pgx.Tx, *models.Return) error) (*models.Return, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var ret models.Return
+	err = scanReturn(tx.QueryRow(ctx,
+		`SELECT `+returnColumns+` FROM returns WHERE id = $1 FOR UPDATE`, id,
+	), &ret)
+	if err != nil {
+		return nil, rowErr(err, "get return", "return %d not found", id)
+	}
+	if !slices.Contains(returnTransitions[ret.Status], status) {
+		return nil, Conflict("return %d is %s and can't be %s", id, ret.Status, status)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE returns SET status = $1 WHERE id = $2`, status, id); err != nil {
+		return nil, fmt.Errorf("update return status: %w", err)
+	}
+	if err := recordReturnStatus(ctx, tx, id, status, note, changedBy); err != nil {
+		return nil, err
+	}
+	if apply != nil {
+		if err := apply(tx, &ret); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return r.GetReturn(ctx, id)
+}
+
+// get a return with its items and history
+func (r *ReturnRepo) GetReturn(ctx context.Context, id int) (*models.Return, error) {
+	returns, err := r.list(ctx, `SELECT `+returnColumns+` FROM returns WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(returns) == 0 {
+		return nil, NotFound("return %d not found", id)
+	}
+	return &returns[0], nil
+}
+
+// get the returns of an order, oldest first
+func (r *ReturnRepo) ListReturnsByOrder(ctx context.Context, orderID int) ([]models.Return, error) {
+	return r.list(ctx, `SELECT `+returnColumns+` FROM returns WHERE order_id = $1 ORDER BY id`, orderID)
+}
+
+// get all returns, optionally only those with the given status
+func (r *ReturnRepo) ListReturns(ctx context.Context, status *string) ([]models.Return, error) {
+	return r.list(ctx,
+		`SELECT `+returnColumns+` FROM returns WHERE $1::text IS NULL OR status = $1 ORDER BY id`, status)
+}
+
+func (r *ReturnRepo) list(ctx context.Context, query string, args ...any) ([]models.Return, error) {
+	rows, err := r.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list returns: %w", err)
+	}
+	defer rows.Close()
+
+	var returns []models.Return
+	for rows.Next() {
+		var ret models.Return
+		if err := scanReturn(rows, &ret); err != nil {
+			return nil, err
+		}
+		returns = append(returns, ret)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadDetails(ctx, returns); err != nil {
+		return nil, err
+	}
+	return returns, nil
+}
+
+// loadDetails fills in the items and status history of the returns
+func (r *ReturnRepo) loadDetails(ctx context.Context, returns []models.Return) error {
+	if len(returns) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(returns))
+	byID := make(map[int]*models.Return, len(returns))
+	for i := range returns {
+		ids[i] = returns[i].ID
+		byID[returns[i].ID] = &returns[i]
+		returns[i].Items = []models.ReturnItem{}
+		returns[i].History = []models.ReturnStatusChange{}
+	}
+
+	rows, err := r.DB.Query(ctx,
+		`SELECT return_id, order_item_id, quantity FROM return_items
+		 WHERE return_id = ANY($1) ORDER BY order_item_id`, ids)
+	if err != nil {
+		return fmt.Errorf("load return items: %w", err)
+	}
+	for rows.Next() {
+		var returnID int
+		var item models.ReturnItem
+		if err := rows.Scan(&returnID, &item.OrderItemID, &item.Quantity); err != nil {
+			rows.Close()
+			return err
+		}
+		byID[returnID].Items = append(byID[returnID].Items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = r.DB.Query(ctx,
+		`SELECT return_id, status, note, changed_by, created_at FROM return_status_history
+		 WHERE return_id = ANY($1) ORDER BY id`, ids)
+	if err != nil {
+		return fmt.Errorf("load return history: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var returnID int
+		var change models.ReturnStatusChange
+		if err := rows.Scan(&returnID, &change.Status, &change.Note, &change.ChangedBy, &change.CreatedAt); err != nil {
+			return err
+		}
+		byID[returnID].History = append(byID[returnID].History, change)
+	}
+	return rows.Err()
+}
+
+func recordReturnStatus(ctx context.Context, tx pgx.Tx, returnID int, status string, note, changedBy *string) error {
+	_, err := tx.Exec(ctx,
+		`INSERT INTO return_status_history (return_id, status, note, changed_by) VALUES ($1, $2, $3, $4)`,
+		returnID, status, note, changedBy,
+	)
+	if err != nil {
+		return fmt.Errorf("record return status: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func TestReturnIsRefundedOnceReceived(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	customerRepo := repo.NewCustomerRepo(db)
+	productRepo := repo.NewProductRepo(db)
+	orderRepo := repo.NewOrderRepo(db)
+	orderItemRepo := repo.NewOrderItemRepo(db)
+	shipmentRepo := repo.NewShipmentRepo(db)
+	returnRepo := repo.NewReturnRepo(db)
+	refundRepo := repo.NewRefundRepo(db)
+
+	customer, err := customerRepo.CreateCustomer(ctx, &models.Customer{
+		AuthID:    "auth0|return-test-" + RandString(8),
+		FirstName: "Return",
+		LastName:  "Tester",
+		Email:     "return_tester_" + RandString(8) + "@example.com",
+		Phone:     "+1111111111",
+	})
+	if err != nil {
+		t.Fatalf("failed to create customer: %v", err)
+	}
+	product, err := productRepo.CreateProduct(ctx, "Lamp", nil, 40, nil)
+	if err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	order, err := orderRepo.CreateOrder(ctx, customer.ID, []models.OrderItemInput{
+		{ProductID: product.ID, Quantity: 2, Price: 40},
+	})
+	if err != nil {
+		t.Fatalf("failed to create order: %v", err)
+	}
+	items, err := orderItemRepo.GetItemsByOrder(ctx, order.ID)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("failed to load order items: %v", err)
+	}
+	itemID := items[0].ID
+
+	request := func(quantity int) (*models.Return, error) {
+		return returnRepo.CreateReturn(ctx, &models.Return{
+			OrderID: order.ID,
+			Reason:  "Too dim",
+			Items:   []models.ReturnItem{{OrderItemID: itemID, Quantity: quantity}},
+		}, repo.DefaultReturnWindowDays, nil)
+	}
+
+	if _, err := request(1); err == nil {
+		t.Error("expected returning undelivered items to fail")
+	}
+
+	shipment, err := shipmentRepo.CreateShipment(ctx, &models.Shipment{
+		OrderID: order.ID,
+		Carrier: "G4S",
+		Items:   []models.ShipmentItem{{OrderItemID: itemID, Quantity: 2}},
+	})
+	if err != nil {
+		t.Fatalf("CreateShipment failed: %v", err)
+	}
+	if _, err := shipmentRepo.MarkDelivered(ctx, shipment.ID); err != nil {
+		t.Fatalf("MarkDelivered failed: %v", err)
+	}
+
+	ret, err := request(1)
+	if err != nil {
+		t.Fatalf("CreateReturn failed: %v", err)
+	}
+	if ret.Status != models.ReturnRequested || len(ret.History) != 1 {
+		t.Errorf("expected a requested return with one history entry, got %+v", ret)
+	}
+	if _, err := request(2); err == nil {
+		t.Error("expected returning more units than are left to fail")
+	}
+
+	if _, err := returnRepo.ReceiveReturn(ctx, ret.ID, true, nil, nil); err == nil {
+		t.Error("expected receiving a return that wasn't approved to fail")
+	}
+	if _, err := returnRepo.ApproveReturn(ctx, ret.ID, nil, nil); err != nil {
+		t.Fatalf("ApproveReturn failed: %v", err)
+	}
+	received, err := returnRepo.ReceiveReturn(ctx, ret.ID, true, nil, nil)
+	if err != nil {
+		t.Fatalf("ReceiveReturn failed: %v", err)
+	}
+	if received.Status != models.ReturnReceived || len(received.History) != 3 {
+		t.Errorf("expected a received return with three history entries, got %+v", received)
+	}
+
+	refunds, err := refundRepo.ListRefundsByOrder(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("ListRefundsByOrder failed: %v", err)
+	}
+	if len(refunds) != 1 || refunds[0].Amount != 40 || refunds[0].Status != models.RefundPending {
+		t.Fatalf("expected one pending refund of 40, got %+v", refunds)
+	}
+	if _, err := refundRepo.ProcessRefund(ctx, refunds[0].ID); err != nil {
+		t.Fatalf("ProcessRefund failed: %v", err)
+	}
+
+	// move the delivery back past the return window
+	if _, err := db.Exec(ctx,
+		`UPDATE shipments SET delivered_at = delivered_at - INTERVAL '30 days' WHERE id = $1`, shipment.ID,
+	); err != nil {
+		t.Fatalf("failed to backdate delivery: %v", err)
+	}
+	if _, err := request(1); err == nil {
+		t.Error("expected a return after the window closed to fail")
+	}
+}
+
+func TestReturnOfOrderMarkedDeliveredWithoutShipment(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	customerRepo := repo.NewCustomerRepo(db)
+	productRepo := repo.NewProductRepo(db)
+	orderRepo := repo.NewOrderRepo(db)
+	orderItemRepo := repo.NewOrderItemRepo(db)
+	returnRepo := repo.NewReturnRepo(db)
+
+	customer, err := customerRepo.CreateCustomer(ctx, &models.Customer{
+		AuthID:    "auth0|return-marked-test-" + RandString(8),
+		FirstName: "Return",
+		LastName:  "Tester",
+		Email:     "return_marked_tester_" + RandString(8) + "@example.com",
+		Phone:     "+1111111111",
+	})
+	if err != nil {
+		t.Fatalf("failed to create customer: %v", err)
+	}
+	product, err := productRepo.CreateProduct(ctx, "Rug", nil, 90, nil)
+	if err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	order, err := orderRepo.CreateOrder(ctx, customer.ID, []models.OrderItemInput{
+		{ProductID: product.ID, Quantity: 2, Price: 90},
+	})
+	if err != nil {
+		t.Fatalf("failed to create order: %v", err)
+	}
+	items, err := orderItemRepo.GetItemsByOrder(ctx, order.ID)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("failed to load order items: %v", err)
+	}
+
+	request := func(quantity int) (*models.Return, error) {
+		return returnRepo.CreateReturn(ctx, &models.Return{
+			OrderID: order.ID,
+			Reason:  "Wrong colour",
+			Items:   []models.ReturnItem{{OrderItemID: items[0].ID, Quantity: quantity}},
+		}, repo.DefaultReturnWindowDays, nil)
+	}
+
+	if err := orderRepo.UpdateOrderStatus(ctx, order.ID, models.OrderStatusDelivered, nil); err != nil {
+		t.Fatalf("UpdateOrderStatus failed: %v", err)
+	}
+	if _, err := request(3); err == nil {
+		t.Error("expected returning more units than were ordered to fail")
+	}
+	if _, err := request(2); err != nil {
+		t.Fatalf("expected an order marked delivered to be returnable, got %v", err)
+	}
+
+	// move the delivery back past the return window
+	if _, err := db.Exec(ctx,
+		`UPDATE order_status_history SET created_at = created_at - INTERVAL '30 days'
+		 WHERE order_id = $1 AND status = $2`, order.ID, models.OrderStatusDelivered,
+	); err != nil {
+		t.Fatalf("failed to backdate delivery: %v", err)
+	}
+	if _, err := db.Exec(ctx, `DELETE FROM returns WHERE order_id = $1`, order.ID); err != nil {
+		t.Fatalf("failed to clear returns: %v", err)
+	}
+	if _, err := request(1); err == nil {
+		t.Error("expected a return after the window closed to fail")
+	}
+}
+
+func TestReceiveReturnRestocksSharedVariantAndProratesDiscounts(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	customerRepo := repo.NewCustomerRepo(db)
+	productRepo := repo.NewProductRepo(db)
+	variantRepo := repo.NewVariantRepo(db)
+	orderRepo := repo.NewOrderRepo(db)
+	orderItemRepo := repo.NewOrderItemRepo(db)
+	shipmentRepo := repo.NewShipmentRepo(db)
+	returnRepo := repo.NewReturnRepo(db)
+	refundRepo := repo.NewRefundRepo(db)
+
+	customer, err := customerRepo.CreateCustomer(ctx, &models.Customer{
+		AuthID:    "auth0|return-variant-test-" + RandString(8),
+		FirstName: "Return",
+		LastName:  "Tester",
+		Email:     "return_variant_tester_" + RandString(8) + "@example.com",
+		Phone:     "+1111111111",
+	})
+	if err != nil {
+		t.Fatalf("failed to create customer: %v", err)
+	}
+	product, err := productRepo.CreateProduct(ctx, "Sock", nil, 50, nil)
+	if err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+	variant, err := variantRepo.CreateVariant(ctx, &models.ProductVariant{
+		ProductID: product.ID,
+		SKU:       "SOCK-M-" + RandString(6),
+		Stock:     5,
+		Options:   []models.VariantOption{{Name: "size", Value: "M"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateVariant failed: %v", err)
+	}
+
+	// the same variant on two lines, with 30 off the 150 subtotal
+	order, err := orderRepo.PlaceOrder(ctx, models.OrderDraft{
+		CustomerID: customer.ID,
+		Items: []models.OrderItemInput{
+			{ProductID: product.ID, VariantID: &variant.ID, Quantity: 1},
+			{ProductID: product.ID, VariantID: &variant.ID, Quantity: 2},
+		},
+		Discounts: []models.OrderDiscount{{Description: "Loyalty", Amount: 30}},
+	})
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	items, err := orderItemRepo.GetItemsByOrder(ctx, order.ID)
+	if err != nil || len(items) != 2 {
+		t.Fatalf("failed to load order items: %v", err)
+	}
+
+	shipment, err := shipmentRepo.CreateShipment(ctx, &models.Shipment{
+		OrderID: order.ID,
+		Carrier: "G4S",
+		Items: []models.ShipmentItem{
+			{OrderItemID: items[0].ID, Quantity: items[0].Quantity},
+			{OrderItemID: items[1].ID, Quantity: items[1].Quantity},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateShipment failed: %v", err)
+	}
+	if _, err := shipmentRepo.MarkDelivered(ctx, shipment.ID); err != nil {
+		t.Fatalf("MarkDelivered failed: %v", err)
+	}
+
+	ret, err := returnRepo.CreateReturn(ctx, &models.Return{
+		OrderID: order.ID,
+		Reason:  "Wrong colour",
+		Items: []models.ReturnItem{
+			{OrderItemID: items[0].ID, Quantity: 1},
+			{OrderItemID: items[1].ID, Quantity: 1},
+		},
+	}, repo.DefaultReturnWindowDays, nil)
+	if err != nil {
+		t.Fatalf("CreateReturn failed: %v", err)
+	}
+	if _, err := returnRepo.ApproveReturn(ctx, ret.ID, nil, nil); err != nil {
+		t.Fatalf("ApproveReturn failed: %v", err)
+	}
+	if _, err := returnRepo.ReceiveReturn(ctx, ret.ID, true, nil, nil); err != nil {
+		t.Fatalf("ReceiveReturn failed: %v", err)
+	}
+
+	// both returned units go back, not just one per variant
+	restocked, err := variantRepo.GetVariantsByIDs(ctx, []int{variant.ID})
+	if err != nil || len(restocked) != 1 {
+		t.Fatalf("failed to load variant: %v", err)
+	}
+	if restocked[0].Stock != 4 {
+		t.Errorf("expected 4 in stock after returning 2 of 3, got %d", restocked[0].Stock)
+	}
+
+	// two units at 50, less their fifth of the discount
+	refunds, err := refundRepo.ListRefundsByOrder(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("ListRefundsByOrder failed: %v", err)
+	}
+	if len(refunds) != 1 || refunds[0].Amount != 80 {
+		t.Errorf("expected one refund of 80, got %+v", refunds)
+	}
+}
@@ -0,0 +1,197 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrReviewNotAllowed is returned when the customer hasn't received the product
+	ErrReviewNotAllowed = Unauthorized("only customers who received the product can review it")
+	// ErrAlreadyReviewed is returned for a second review of the same product
+	ErrAlreadyReviewed = Conflict("you have already reviewed this product")
+)
+
+// reviewTransitions lists the statuses a review can move to from each
+// status. Staff can change their mind about a review they moderated.
+var reviewTransitions = map[string][]string{
+	models.ReviewPending:  {models.ReviewApproved, models.ReviewRejected},
+	models.ReviewApproved: {models.ReviewRejected},
+	models.ReviewRejected: {models.ReviewApproved},
+}
+
+type ReviewRepo struct {
+	DB *pgxpool.Pool
+}
+
+func NewReviewRepo(db *pgxpool.Pool) *ReviewRepo {
+	return &ReviewRepo{DB: db}
+}
+
+// reviewers are shown by first name and last initial
+const reviewColumns = `r.id, r.product_id, r.customer_id,
+	TRIM(c.first_name || ' ' || COALESCE(LEFT(NULLIF(c.last_name, ''), 1) || '.', '')),
+	r.rating, r.body, r.status, r.moderated_by, r.created_at`
+
+const reviewsFrom = ` FROM reviews r JOIN customers c ON c.id = r.customer_id `
+
+func scanReview(row pgx.Row, r *models.Review) error {
+	return row.Scan(&r.ID, &r.ProductID, &r.CustomerID, &r.CustomerName,
+		&r.Rating, &r.Body, &r.Status, &r.ModeratedBy, &r.CreatedAt)
+}
+
+// CreateReview saves a pending review. The customer must have received the
+// product, either in a delivered shipment or in an order marked delivered,
+// and can review each product once.
+func (r *ReviewRepo) CreateReview(ctx context.Context, review *models.Review) (*models.Review, error) {
+	if review.Rating < 1 || review.Rating > 5 {
+		return nil, Validation("input.rating", "rating must be between 1 and 5, got %d", review.Rating)
+	}
+
+	var received bool
+	err := r.DB.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM orders o
+			JOIN order_items oi ON oi.order_id = o.id
+			WHERE o.customer_id = $1 AND oi.product_id = $2
+			  AND (o.status = $3 OR EXISTS (
+				SELECT 1 FROM shipment_items si
+				JOIN shipments s ON s.id = si.shipment_id
+				WHERE si.order_item_id = oi.id AND s.delivered_at IS NOT NULL))
+		)`,
+		review.CustomerID, review.ProductID, models.OrderStatusDelivered,
+	).Scan(&received)
+	if err != nil {
+		return nil, fmt.Errorf("check product delivered: %w", err)
+	}
+	if !received {
+		return nil, ErrReviewNotAllowed
+	}
+
+	var id int
+	err = r.DB.QueryRow(ctx,
+		`INSERT INTO reviews (product_id, customer_id, rating, body)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (product_id, customer_id) DO NOTHING
+		 RETURNING id`,
+		review.ProductID, review.CustomerID, review.Rating, review.Body,
+	).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAlreadyReviewed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create review: %w", err)
+	}
+	return r.GetReview(ctx, id)
+}
+
+// ModerateReview moves a review to approved or rejected and keeps the
+// product's rating totals in step, so they only ever count approved reviews
+func (r *ReviewRepo) ModerateReview(ctx context.Context, id int, status string, moderatedBy *string) (*models.Review, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var productID, rating int
+	var current string
+	err = tx.QueryRow(ctx,
+		`SELECT product_id, rating, status FROM reviews WHERE id = $1 FOR UPDATE`, id,
+	).Scan(&productID, &rating, &current)
+	if err != nil {
+		return nil, rowErr(err, "get review", "review %d not found", id)
+	}
+	if !slices.Contains(reviewTransitions[current], status) {
+		return nil, Conflict("review %d is %s and can't be %s", id, current, status)
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE reviews SET status = $1, moderated_by = $2 WHERE id = $3`, status, moderatedBy, id)
+	if err != nil {
+		return nil, fmt.Errorf("update review status: %w", err)
+	}
+
+	// add the rating when the review goes live, take it out when it's withdrawn
+	sign := 0
+	switch {
+	case status == models.ReviewApproved:
+		sign = 1
+	case current == models.ReviewApproved:
+		sign = -1
+	}
+	if sign != 0 {
+		_, err = tx.Exec(ctx,
+			`UPDATE products SET rating_count = rating_count + $1, rating_total = rating_total + $2
+			 WHERE id = $3`, sign, sign*rating, productID)
+		if err != nil {
+			return nil, fmt.Errorf("update product rating: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return r.GetReview(ctx, id)
+}
+
+// get a review by ID
+func (r *ReviewRepo) GetReview(ctx context.Context, id int) (*models.Review, error) {
+	var review models.Review
+	err := scanReview(r.DB.QueryRow(ctx, `SELECT `+reviewColumns+reviewsFrom+`WHERE r.id = $1`, id), &review)
+	if err != nil {
+		return nil, rowErr(err, "get review", "review %d not found", id)
+	}
+	return &review, nil
+}
+
+// ListProductReviews returns one page of a product's approved reviews, newest
+// first after the review afterID when given, and how many there are in all
+func (r *ReviewRepo) ListProductReviews(ctx context.Context, productID int, afterID *int, limit int) ([]models.Review, int, error) {
+	var total int
+	err := r.DB.QueryRow(ctx,
+		`SELECT COUNT(*) FROM reviews WHERE product_id = $1 AND status = $2`,
+		productID, models.ReviewApproved,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("count reviews: %w", err)
+	}
+
+	reviews, err := r.list(ctx,
+		`SELECT `+reviewColumns+reviewsFrom+`
+		 WHERE r.product_id = $1 AND r.status = $2 AND ($3::int IS NULL OR r.id < $3)
+		 ORDER BY r.id DESC LIMIT $4`,
+		productID, models.ReviewApproved, afterID, limit,
+	)
+	return reviews, total, err
+}
+
+// get all reviews, oldest first, optionally only those with the given status
+func (r *ReviewRepo) ListReviews(ctx context.Context, status *string) ([]models.Review, error) {
+	return r.list(ctx,
+		`SELECT `+reviewColumns+reviewsFrom+`WHERE $1::text IS NULL OR r.status = $1 ORDER BY r.id`, status)
+}
+
+func (r *ReviewRepo) list(ctx context.Context, query string, args ...any) ([]models.Review, error) {
+	rows, err := r.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list reviews: %w", err)
+	}
+	defer rows.Close()
+
+	var reviews []models.Review
+	for rows.Next() {
+		var review models.Review
+		if err := scanReview(rows, &review); err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, rows.Err()
+}
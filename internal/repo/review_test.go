@@ -0,0 +1,100 @@
+package repo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func TestReviewModerationKeepsRatingTotals(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	customerRepo := repo.NewCustomerRepo(db)
+	productRepo := repo.NewProductRepo(db)
+	orderRepo := repo.NewOrderRepo(db)
+	reviewRepo := repo.NewReviewRepo(db)
+
+	customer, err := customerRepo.CreateCustomer(ctx, &models.Customer{
+		AuthID:    "auth0|review-test-" + RandString(8),
+		FirstName: "Review",
+		LastName:  "Tester",
+		Email:     "review_tester_" + RandString(8) + "@example.com",
+		Phone:     "+1111111111",
+	})
+	if err != nil {
+		t.Fatalf("failed to create customer: %v", err)
+	}
+	product, err := productRepo.CreateProduct(ctx, "Kettle", nil, 35, nil)
+	if err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	review := &models.Review{ProductID: product.ID, CustomerID: customer.ID, Rating: 4, Body: "Boils fast"}
+	if _, err := reviewRepo.CreateReview(ctx, review); !errors.Is(err, repo.ErrReviewNotAllowed) {
+		t.Fatalf("expected a review before delivery to be refused, got %v", err)
+	}
+
+	order, err := orderRepo.CreateOrder(ctx, customer.ID, []models.OrderItemInput{
+		{ProductID: product.ID, Quantity: 1, Price: 35},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+	if err := orderRepo.UpdateOrderStatus(ctx, order.ID, models.OrderStatusDelivered, nil); err != nil {
+		t.Fatalf("UpdateOrderStatus failed: %v", err)
+	}
+
+	created, err := reviewRepo.CreateReview(ctx, review)
+	if err != nil {
+		t.Fatalf("CreateReview failed: %v", err)
+	}
+	if created.Status != models.ReviewPending || created.CustomerName != "Review T." {
+		t.Errorf("expected a pending review by Review T., got %+v", created)
+	}
+	if _, err := reviewRepo.CreateReview(ctx, review); !errors.Is(err, repo.ErrAlreadyReviewed) {
+		t.Errorf("expected a second review to be refused, got %v", err)
+	}
+
+	rating := func() (int, *float64) {
+		p, err := productRepo.GetProduct(ctx, product.ID)
+		if err != nil {
+			t.Fatalf("GetProduct failed: %v", err)
+		}
+		return p.RatingCount, p.AverageRating()
+	}
+	if count, avg := rating(); count != 0 || avg != nil {
+		t.Errorf("expected pending reviews not to count, got %d with average %v", count, avg)
+	}
+
+	staff := "auth0|staff"
+	if _, err := reviewRepo.ModerateReview(ctx, created.ID, models.ReviewApproved, &staff); err != nil {
+		t.Fatalf("ModerateReview failed: %v", err)
+	}
+	if count, avg := rating(); count != 1 || avg == nil || *avg != 4 {
+		t.Errorf("expected one rating of 4, got %d with average %v", count, avg)
+	}
+
+	page, total, err := reviewRepo.ListProductReviews(ctx, product.ID, nil, 10)
+	if err != nil {
+		t.Fatalf("ListProductReviews failed: %v", err)
+	}
+	if total != 1 || len(page) != 1 || page[0].ID != created.ID {
+		t.Errorf("expected the approved review to be listed, got %d of %d", len(page), total)
+	}
+
+	if _, err := reviewRepo.ModerateReview(ctx, created.ID, models.ReviewRejected, &staff); err != nil {
+		t.Fatalf("ModerateReview failed: %v", err)
+	}
+	if count, avg := rating(); count != 0 || avg != nil {
+		t.Errorf("expected the rejected rating to be taken out, got %d with average %v", count, avg)
+	}
+	if _, err := reviewRepo.ModerateReview(ctx, created.ID, models.ReviewPending, &staff); err == nil {
+		t.Error("expected moving a review back to pending to fail")
+	}
+}
@@ -0,0 +1,279 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ShipmentRepo struct {
+	DB *pgxpool.Pool
+}
+
+func NewShipmentRepo(db *pgxpool.Pool) *ShipmentRepo {
+	return &ShipmentRepo{DB: db}
+}
+
+const shipmentColumns = `id, order_id, carrier, tracking_number, shipped_at, delivered_at`
+
+func scanShipment(row pgx.Row, s *models.Shipment) error {
+	return row.Scan(&s.ID, &s.OrderID, &s.Carrier, &s.TrackingNumber, &s.ShippedAt, &s.DeliveredAt)
+}
+
+// CreateShipment records a parcel going out for an order and moves the order
+// to partially_shipped or shipped. No order item can ship more units than
+// were ordered across all of the order's shipments.
+func (r *ShipmentRepo) CreateShipment(ctx context.Context, s *models.Shipment) (*models.Shipment, error) {
+	if len(s.Items) == 0 {
+		return nil, Validation("input.items", "a shipment needs at least one item")
+	}
+
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// lock the order so concurrent shipments can't both ship the same units
+	var status string
+	err = tx.QueryRow(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, s.OrderID).Scan(&status)
+	if err != nil {
+		return nil, rowErr(err, "get order", "order %d not found", s.OrderID)
+	}
+	if status == models.OrderStatusShipped || status == models.OrderStatusDelivered {
+		return nil, Conflict("order %d has already been shipped in full", s.OrderID)
+	}
+	if status == models.OrderStatusCancelled {
+		return nil, Conflict("order %d is cancelled", s.OrderID)
+	}
+
+	var created models.Shipment
+	err = scanShipment(tx.QueryRow(ctx,
+		`INSERT INTO shipments (order_id, carrier, tracking_number)
+		 VALUES ($1, $2, $3) RETURNING `+shipmentColumns,
+		s.OrderID, s.Carrier, s.TrackingNumber,
+	), &created)
+	if err != nil {
+		return nil, fmt.Errorf("create shipment: %w", err)
+	}
+
+	for _, item := range s.Items {
+		var ordered, shipped int
+		err := tx.QueryRow(ctx,
+			`SELECT oi.quantity, COALESCE(SUM(si.quantity), 0)
+			 FROM order_items oi
+			 LEFT JOIN shipment_items si ON si.order_item_id = oi.id
+			 WHERE oi.id = $1 AND oi.order_id = $2
+			 GROUP BY oi.id`,
+			item.OrderItemID, s.OrderID,
+		).Scan(&ordered, &shipped)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, Validation("input.items", "order item %d does not belong to order %d", item.OrderItemID, s.OrderID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("check shipped quantity: %w", err)
+		}
+		if item.Quantity > ordered-shipped {
+			return nil, Validation("input.items", "order item %d has %d of %d units left to ship, got %d",
+				item.OrderItemID, ordered-shipped, ordered, item.Quantity)
+		}
+
+		_, err = tx.Exec(ctx,
+			`INSERT INTO shipment_items (shipment_id, order_item_id, quantity) VALUES ($1, $2, $3)`,
+			created.ID, item.OrderItemID, item.Quantity,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("create shipment item: %w", err)
+		}
+		created.Items = append(created.Items, item)
+	}
+
+	if err := syncFulfillmentStatus(ctx, tx, s.OrderID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// MarkDelivered records that a shipment arrived. Once every unit of the order
+// has shipped and arrived the order moves to delivered.
+func (r *ShipmentRepo) MarkDelivered(ctx context.Context, shipmentID int) (*models.Shipment, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// lock the order first, the same order CreateShipment takes its locks in
+	var orderID int
+	err = tx.QueryRow(ctx,
+		`SELECT o.id FROM orders o JOIN shipments s ON s.order_id = o.id WHERE s.id = $1 FOR UPDATE OF o`,
+		shipmentID,
+	).Scan(&orderID)
+	if err != nil {
+		return nil, rowErr(err, "get shipment", "shipment %d not found", shipmentID)
+	}
+
+	var s models.Shipment
+	err = scanShipment(tx.QueryRow(ctx,
+		`UPDATE shipments SET delivered_at = COALESCE(delivered_at, CURRENT_TIMESTAMP)
+		 WHERE id = $1 RETURNING `+shipmentColumns, shipmentID,
+	), &s)
+	if err != nil {
+		return nil, fmt.Errorf("mark shipment delivered: %w", err)
+	}
+
+	if err := syncFulfillmentStatus(ctx, tx, s.OrderID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	shipments := []models.Shipment{s}
+	if err := r.loadItems(ctx, shipments); err != nil {
+		return nil, err
+	}
+	return &shipments[0], nil
+}
+
+// get a shipment by ID
+func (r *ShipmentRepo) GetShipment(ctx context.Context, id int) (*models.Shipment, error) {
+	var s models.Shipment
+	err := scanShipment(r.DB.QueryRow(ctx,
+		`SELECT `+shipmentColumns+` FROM shipments WHERE id = $1`, id,
+	), &s)
+	if err != nil {
+		return nil, rowErr(err, "get shipment", "shipment %d not found", id)
+	}
+
+	shipments := []models.Shipment{s}
+	if err := r.loadItems(ctx, shipments); err != nil {
+		return nil, err
+	}
+	return &shipments[0], nil
+}
+
+// get the shipments of an order, oldest first
+func (r *ShipmentRepo) ListShipmentsByOrder(ctx context.Context, orderID int) ([]models.Shipment, error) {
+	rows, err := r.DB.Query(ctx,
+		`SELECT `+shipmentColumns+` FROM shipments WHERE order_id = $1 ORDER BY shipped_at, id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("list shipments by order: %w", err)
+	}
+	defer rows.Close()
+
+	var shipments []models.Shipment
+	for rows.Next() {
+		var s models.Shipment
+		if err := scanShipment(rows, &s); err != nil {
+			return nil, err
+		}
+		shipments = append(shipments, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadItems(ctx, shipments); err != nil {
+		return nil, err
+	}
+	return shipments, nil
+}
+
+// loadItems fills in the items of the shipments with a single query
+func (r *ShipmentRepo) loadItems(ctx context.Context, shipments []models.Shipment) error {
+	if len(shipments) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(shipments))
+	byID := make(map[int]*models.Shipment, len(shipments))
+	for i := range shipments {
+		ids[i] = shipments[i].ID
+		byID[shipments[i].ID] = &shipments[i]
+		shipments[i].Items = []models.ShipmentItem{}
+	}
+
+	rows, err := r.DB.Query(ctx,
+		`SELECT shipment_id, order_item_id, quantity FROM shipment_items
+		 WHERE shipment_id = ANY($1) ORDER BY order_item_id`, ids)
+	if err != nil {
+		return fmt.Errorf("load shipment items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var shipmentID int
+		var item models.ShipmentItem
+		if err := rows.Scan(&shipmentID, &item.OrderItemID, &item.Quantity); err != nil {
+			return err
+		}
+		byID[shipmentID].Items = append(byID[shipmentID].Items, item)
+	}
+	return rows.Err()
+}
+
+// syncFulfillmentStatus moves the order to the status its shipments add up to.
+// The caller must hold the order's row lock.
+func syncFulfillmentStatus(ctx context.Context, tx pgx.Tx, orderID int) error {
+	var allShipped, anyShipped, allDelivered bool
+	err := tx.QueryRow(ctx, `
+		WITH lines AS (
+			SELECT oi.quantity, COALESCE(SUM(si.quantity), 0) AS shipped
+			FROM order_items oi
+			LEFT JOIN shipment_items si ON si.order_item_id = oi.id
+			WHERE oi.order_id = $1
+			GROUP BY oi.id
+		)
+		SELECT COALESCE(BOOL_AND(shipped >= quantity), FALSE),
+		       COALESCE(BOOL_OR(shipped > 0), FALSE),
+		       NOT EXISTS (SELECT 1 FROM shipments WHERE order_id = $1 AND delivered_at IS NULL)
+		FROM lines`, orderID,
+	).Scan(&allShipped, &anyShipped, &allDelivered)
+	if err != nil {
+		return fmt.Errorf("check fulfillment: %w", err)
+	}
+
+	status := fulfillmentStatus(allShipped, anyShipped, allDelivered)
+	if status == "" {
+		return nil
+	}
+	var previous string
+	err = tx.QueryRow(ctx, `SELECT status FROM orders WHERE id = $1`, orderID).Scan(&previous)
+	if err != nil {
+		return fmt.Errorf("get order status: %w", err)
+	}
+	if previous == status {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, `UPDATE orders SET status = $1 WHERE id = $2`, status, orderID); err != nil {
+		return fmt.Errorf("update order status: %w", err)
+	}
+	if err := recordOrderStatus(ctx, tx, orderID, status, nil, nil); err != nil {
+		return err
+	}
+	return enqueueOrderEvent(ctx, tx, models.WebhookOrderStatusChanged, orderID, &previous)
+}
+
+// fulfillmentStatus is the order status for how much of it has shipped and
+// arrived, or "" when nothing has shipped yet
+func fulfillmentStatus(allShipped, anyShipped, allDelivered bool) string {
+	switch {
+	case allShipped && allDelivered:
+		return models.OrderStatusDelivered
+	case allShipped:
+		return models.OrderStatusShipped
+	case anyShipped:
+		return models.OrderStatusPartiallyShipped
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func TestPartialShipmentsDriveOrderStatus(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	customerRepo := repo.NewCustomerRepo(db)
+	productRepo := repo.NewProductRepo(db)
+	orderRepo := repo.NewOrderRepo(db)
+	orderItemRepo := repo.NewOrderItemRepo(db)
+	shipmentRepo := repo.NewShipmentRepo(db)
+
+	customer, err := customerRepo.CreateCustomer(ctx, &models.Customer{
+		AuthID:    "auth0|shipment-test-" + RandString(8),
+		FirstName: "Shipment",
+		LastName:  "Tester",
+		Email:     "shipment_tester_" + RandString(8) + "@example.com",
+		Phone:     "+1111111111",
+	})
+	if err != nil {
+		t.Fatalf("failed to create customer: %v", err)
+	}
+	product, err := productRepo.CreateProduct(ctx, "Mug", nil, 5, nil)
+	if err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	order, err := orderRepo.CreateOrder(ctx, customer.ID, []models.OrderItemInput{
+		{ProductID: product.ID, Quantity: 3, Price: 5},
+	})
+	if err != nil {
+		t.Fatalf("failed to create order: %v", err)
+	}
+	items, err := orderItemRepo.GetItemsByOrder(ctx, order.ID)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("failed to load order items: %v", err)
+	}
+	itemID := items[0].ID
+
+	ship := func(quantity int) (*models.Shipment, error) {
+		return shipmentRepo.CreateShipment(ctx, &models.Shipment{
+			OrderID: order.ID,
+			Carrier: "G4S",
+			Items:   []models.ShipmentItem{{OrderItemID: itemID, Quantity: quantity}},
+		})
+	}
+	status := func() string {
+		o, err := orderRepo.GetOrder(ctx, order.ID)
+		if err != nil {
+			t.Fatalf("GetOrder failed: %v", err)
+		}
+		return o.Status
+	}
+
+	first, err := ship(2)
+	if err != nil {
+		t.Fatalf("CreateShipment failed: %v", err)
+	}
+	if got := status(); got != models.OrderStatusPartiallyShipped {
+		t.Errorf("expected %s, got %s", models.OrderStatusPartiallyShipped, got)
+	}
+
+	if _, err := ship(2); err == nil {
+		t.Error("expected shipping more units than are left to fail")
+	}
+
+	second, err := ship(1)
+	if err != nil {
+		t.Fatalf("CreateShipment failed: %v", err)
+	}
+	if got := status(); got != models.OrderStatusShipped {
+		t.Errorf("expected %s, got %s", models.OrderStatusShipped, got)
+	}
+
+	if _, err := shipmentRepo.MarkDelivered(ctx, first.ID); err != nil {
+		t.Fatalf("MarkDelivered failed: %v", err)
+	}
+	if got := status(); got != models.OrderStatusShipped {
+		t.Errorf("expected the order to stay %s until every shipment arrives, got %s", models.OrderStatusShipped, got)
+	}
+	if _, err := shipmentRepo.MarkDelivered(ctx, second.ID); err != nil {
+		t.Fatalf("MarkDelivered failed: %v", err)
+	}
+	if got := status(); got != models.OrderStatusDelivered {
+		t.Errorf("expected %s, got %s", models.OrderStatusDelivered, got)
+	}
+
+	shipments, err := shipmentRepo.ListShipmentsByOrder(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("ListShipmentsByOrder failed: %v", err)
+	}
+	if len(shipments) != 2 || shipments[0].Items[0].Quantity != 2 || shipments[1].DeliveredAt == nil {
+		t.Errorf("unexpected shipments: %+v", shipments)
+	}
+}
@@ -0,0 +1,172 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/shipping"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ShippingRepo struct {
+	DB *pgxpool.Pool
+}
+
+func NewShippingRepo(db *pgxpool.Pool) *ShippingRepo {
+	return &ShippingRepo{DB: db}
+}
+
+const shippingMethodColumns = `id, code, name, description, active, created_at`
+
+func scanShippingMethod(row pgx.Row, m *models.ShippingMethod) error {
+	return row.Scan(&m.ID, &m.Code, &m.Name, &m.Description, &m.Active, &m.CreatedAt)
+}
+
+const shippingRateColumns = `id, method_id, zone_id, min_weight_kg, max_weight_kg, price`
+
+func scanShippingRate(row pgx.Row, r *models.ShippingRate) error {
+	return row.Scan(&r.ID, &r.MethodID, &r.ZoneID, &r.MinWeightKg, &r.MaxWeightKg, &r.Price)
+}
+
+// new shipping method
+func (r *ShippingRepo) CreateMethod(ctx context.Context, m *models.ShippingMethod) (*models.ShippingMethod, error) {
+	var created models.ShippingMethod
+	err := scanShippingMethod(r.DB.QueryRow(ctx,
+		`INSERT INTO shipping_methods (code, name, description, active)
+		 VALUES ($1, $2, $3, $4) RETURNING `+shippingMethodColumns,
+		m.Code, m.Name, m.Description, m.Active,
+	), &created)
+	if err != nil {
+		return nil, fmt.Errorf("create shipping method: %w", err)
+	}
+	return &created, nil
+}
+
+// get all shipping methods, including inactive ones
+func (r *ShippingRepo) ListMethods(ctx context.Context) ([]models.ShippingMethod, error) {
+	rows, err := r.DB.Query(ctx, `SELECT `+shippingMethodColumns+` FROM shipping_methods ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list shipping methods: %w", err)
+	}
+	defer rows.Close()
+
+	var methods []models.ShippingMethod
+	for rows.Next() {
+		var m models.ShippingMethod
+		if err := scanShippingMethod(rows, &m); err != nil {
+			return nil, err
+		}
+		methods = append(methods, m)
+	}
+	return methods, rows.Err()
+}
+
+// enables or disables a shipping method
+func (r *ShippingRepo) SetMethodActive(ctx context.Context, id int, active bool) error {
+	cmdTag, err := r.DB.Exec(ctx, `UPDATE shipping_methods SET active = $1 WHERE id = $2`, active, id)
+	if err != nil {
+		return fmt.Errorf("set shipping method active: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return NotFound("no shipping method found with id %d", id)
+	}
+	return nil
+}
+
+// new shipping zone
+func (r *ShippingRepo) CreateZone(ctx context.Context, z *models.ShippingZone) (*models.ShippingZone, error) {
+	var created models.ShippingZone
+	err := r.DB.QueryRow(ctx,
+		`INSERT INTO shipping_zones (name, countries, regions)
+		 VALUES ($1, $2, $3) RETURNING id, name, countries, regions`,
+		z.Name, z.Countries, z.Regions,
+	).Scan(&created.ID, &created.Name, &created.Countries, &created.Regions)
+	if err != nil {
+		return nil, fmt.Errorf("create shipping zone: %w", err)
+	}
+	return &created, nil
+}
+
+// get all shipping zones
+func (r *ShippingRepo) ListZones(ctx context.Context) ([]models.ShippingZone, error) {
+	rows, err := r.DB.Query(ctx, `SELECT id, name, countries, regions FROM shipping_zones ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list shipping zones: %w", err)
+	}
+	defer rows.Close()
+
+	var zones []models.ShippingZone
+	for rows.Next() {
+		var z models.ShippingZone
+		if err := rows.Scan(&z.ID, &z.Name, &z.Countries, &z.Regions); err != nil {
+			return nil, err
+		}
+		zones = append(zones, z)
+	}
+	return zones, rows.Err()
+}
+
+// new shipping rate
+func (r *ShippingRepo) CreateRate(ctx context.Context, rate *models.ShippingRate) (*models.ShippingRate, error) {
+	var created models.ShippingRate
+	err := scanShippingRate(r.DB.QueryRow(ctx,
+		`INSERT INTO shipping_rates (method_id, zone_id, min_weight_kg, max_weight_kg, price)
+		 VALUES ($1, $2, $3, $4, $5) RETURNING `+shippingRateColumns,
+		rate.MethodID, rate.ZoneID, rate.MinWeightKg, rate.MaxWeightKg, rate.Price,
+	), &created)
+	if err != nil {
+		return nil, fmt.Errorf("create shipping rate: %w", err)
+	}
+	return &created, nil
+}
+
+// get all shipping rates
+func (r *ShippingRepo) ListRates(ctx context.Context) ([]models.ShippingRate, error) {
+	rows, err := r.DB.Query(ctx,
+		`SELECT `+shippingRateColumns+` FROM shipping_rates ORDER BY method_id, zone_id, min_weight_kg`)
+	if err != nil {
+		return nil, fmt.Errorf("list shipping rates: %w", err)
+	}
+	defer rows.Close()
+
+	var rates []models.ShippingRate
+	for rows.Next() {
+		var rate models.ShippingRate
+		if err := scanShippingRate(rows, &rate); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, rows.Err()
+}
+
+// removes a shipping rate
+func (r *ShippingRepo) DeleteRate(ctx context.Context, id int) error {
+	cmdTag, err := r.DB.Exec(ctx, `DELETE FROM shipping_rates WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete shipping rate: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return NotFound("no shipping rate found with id %d", id)
+	}
+	return nil
+}
+
+// LoadTable reads the whole shipping configuration for the rate calculator
+func (r *ShippingRepo) LoadTable(ctx context.Context) (*shipping.Table, error) {
+	methods, err := r.ListMethods(ctx)
+	if err != nil {
+		return nil, err
+	}
+	zones, err := r.ListZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rates, err := r.ListRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &shipping.Table{Methods: methods, Zones: zones, Rates: rates}, nil
+}
@@ -0,0 +1,71 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/shipping"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func TestShippingCostIsAddedToOrderTotal(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	customerRepo := repo.NewCustomerRepo(db)
+	productRepo := repo.NewProductRepo(db)
+	shippingRepo := repo.NewShippingRepo(db)
+	orderRepo := repo.NewOrderRepo(db)
+
+	customer, err := customerRepo.CreateCustomer(ctx, &models.Customer{
+		AuthID:    "auth0|shipping-test-" + RandString(8),
+		FirstName: "Shipping",
+		LastName:  "Tester",
+		Email:     "shipping_tester_" + RandString(8) + "@example.com",
+		Phone:     "+1111111111",
+	})
+	if err != nil {
+		t.Fatalf("failed to create customer: %v", err)
+	}
+
+	weight := 2.5
+	product, err := productRepo.InsertProduct(ctx, &models.Product{Name: "Kettle", Price: 2000, WeightKg: &weight})
+	if err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+	if product.WeightKg == nil || *product.WeightKg != weight {
+		t.Errorf("expected weight %v, got %v", weight, product.WeightKg)
+	}
+
+	table, err := shippingRepo.LoadTable(ctx)
+	if err != nil {
+		t.Fatalf("LoadTable failed: %v", err)
+	}
+
+	address := models.Address{FullName: "Shipping Tester", Line1: "1 Moi Avenue", City: "Nairobi", Country: "KE"}
+	quotes := shipping.Quotes(*table, address, weight)
+	if len(quotes) == 0 {
+		t.Fatal("expected the seeded methods to deliver within Nairobi")
+	}
+	quote := quotes[0]
+
+	order, err := orderRepo.PlaceOrder(ctx, models.OrderDraft{
+		CustomerID:      customer.ID,
+		Items:           []models.OrderItemInput{{ProductID: product.ID, Quantity: 1, Price: 2000}},
+		ShippingAddress: &address,
+		Shipping:        &quote,
+	})
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+
+	if order.ShippingCost != quote.Cost || order.Total != 2000+quote.Cost {
+		t.Errorf("expected shipping %.2f in the total, got %+v", quote.Cost, order)
+	}
+	if order.ShippingMethodName == nil || *order.ShippingMethodName != quote.Method.Name {
+		t.Errorf("expected method name %q on the order, got %v", quote.Method.Name, order.ShippingMethodName)
+	}
+}
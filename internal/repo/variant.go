@@ -0,0 +1,245 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type VariantRepo struct {
+	DB *pgxpool.Pool
+}
+
+func NewVariantRepo(db *pgxpool.Pool) *VariantRepo {
+	return &VariantRepo{DB: db}
+}
+
+const variantSelect = `
+	SELECT v.id, v.product_id, v.sku, v.price, COALESCE(v.price, p.price), v.stock, v.created_at
+	FROM product_variants v
+	JOIN products p ON p.id = v.product_id`
+
+func scanVariant(row pgx.Row, v *models.ProductVariant) error {
+	return row.Scan(&v.ID, &v.ProductID, &v.SKU, &v.Price, &v.UnitPrice, &v.Stock, &v.CreatedAt)
+}
+
+// new option type
+func (r *VariantRepo) CreateOptionType(ctx context.Context, name string) (*models.OptionType, error) {
+	var o models.OptionType
+	err := r.DB.QueryRow(ctx,
+		`INSERT INTO option_types (name) VALUES ($1) RETURNING id, name`, name,
+	).Scan(&o.ID, &o.Name)
+	if err != nil {
+		return nil, fmt.Errorf("create option type: %w", err)
+	}
+	return &o, nil
+}
+
+// get all option types
+func (r *VariantRepo) ListOptionTypes(ctx context.Context) ([]models.OptionType, error) {
+	rows, err := r.DB.Query(ctx, `SELECT id, name FROM option_types ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list option types: %w", err)
+	}
+	defer rows.Close()
+
+	var types []models.OptionType
+	for rows.Next() {
+		var o models.OptionType
+		if err := rows.Scan(&o.ID, &o.Name); err != nil {
+			return nil, err
+		}
+		types = append(types, o)
+	}
+	return types, rows.Err()
+}
+
+// CreateVariant inserts a variant with its option values. Options are matched to
+// option types by name and no two variants of a product may share the same options.
+func (r *VariantRepo) CreateVariant(ctx context.Context, v *models.ProductVariant) (*models.ProductVariant, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var created models.ProductVariant
+	err = tx.QueryRow(ctx,
+		`INSERT INTO product_variants (product_id, sku, price, stock)
+		 VALUES ($1, $2, $3, $4) RETURNING id, product_id`,
+		v.ProductID, v.SKU, v.Price, v.Stock,
+	).Scan(&created.ID, &created.ProductID)
+	if err != nil {
+		return nil, fmt.Errorf("create variant: %w", err)
+	}
+
+	for _, opt := range v.Options {
+		cmdTag, err := tx.Exec(ctx,
+			`INSERT INTO variant_option_values (variant_id, option_type_id, value)
+			 SELECT $1, id, $3 FROM option_types WHERE name = $2`,
+			created.ID, opt.Name, opt.Value,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("create variant option: %w", err)
+		}
+		if cmdTag.RowsAffected() == 0 {
+			return nil, Validation("input.options", "unknown option type %q", opt.Name)
+		}
+	}
+
+	// the new variant must differ from its siblings in at least one option
+	var duplicate bool
+	err = tx.QueryRow(ctx, `
+		WITH mine AS (
+			SELECT option_type_id, value FROM variant_option_values WHERE variant_id = $1
+		)
+		SELECT EXISTS (
+			SELECT 1 FROM product_variants v
+			WHERE v.product_id = $2 AND v.id <> $1
+			  AND NOT EXISTS (
+				(SELECT option_type_id, value FROM variant_option_values WHERE variant_id = v.id
+				 EXCEPT SELECT option_type_id, value FROM mine)
+				UNION ALL
+				(SELECT option_type_id, value FROM mine
+				 EXCEPT SELECT option_type_id, value FROM variant_option_values WHERE variant_id = v.id)
+			  )
+		)`, created.ID, created.ProductID,
+	).Scan(&duplicate)
+	if err != nil {
+		return nil, fmt.Errorf("check duplicate variant: %w", err)
+	}
+	if duplicate {
+		return nil, Conflict("product %d already has a variant with these options", created.ProductID)
+	}
+	if err := enqueueProductEvent(ctx, tx, models.WebhookProductUpdated, created.ProductID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	variants, err := r.GetVariantsByIDs(ctx, []int{created.ID})
+	if err != nil {
+		return nil, err
+	}
+	return &variants[0], nil
+}
+
+// get a variant by its SKU
+func (r *VariantRepo) GetVariantBySKU(ctx context.Context, sku string) (*models.ProductVariant, error) {
+	var v models.ProductVariant
+	err := scanVariant(r.DB.QueryRow(ctx,
+		variantSelect+` WHERE v.sku = $1`, sku,
+	), &v)
+	if err != nil {
+		return nil, rowErr(err, "get variant by sku", "no variant found with SKU %q", sku)
+	}
+
+	variants := []models.ProductVariant{v}
+	if err := r.loadOptions(ctx, variants); err != nil {
+		return nil, err
+	}
+	return &variants[0], nil
+}
+
+// get all variants of a product
+func (r *VariantRepo) ListVariantsByProduct(ctx context.Context, productID int) ([]models.ProductVariant, error) {
+	return r.list(ctx,
+		variantSelect+` WHERE v.product_id = $1 ORDER BY v.id`, productID)
+}
+
+// get the variants with the given IDs
+func (r *VariantRepo) GetVariantsByIDs(ctx context.Context, ids []int) ([]models.ProductVariant, error) {
+	return r.list(ctx,
+		variantSelect+` WHERE v.id = ANY($1) ORDER BY v.id`, ids)
+}
+
+// sets the stock level of a variant
+func (r *VariantRepo) UpdateVariantStock(ctx context.Context, variantID, stock int) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var productID int
+	err = tx.QueryRow(ctx,
+		`UPDATE product_variants SET stock = $1 WHERE id = $2 RETURNING product_id`, stock, variantID,
+	).Scan(&productID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return NotFound("no variant found with id %d", variantID)
+	}
+	if err != nil {
+		return fmt.Errorf("update variant stock: %w", err)
+	}
+	if err := enqueueProductEvent(ctx, tx, models.WebhookProductUpdated, productID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *VariantRepo) list(ctx context.Context, query string, args ...any) ([]models.ProductVariant, error) {
+	rows, err := r.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list variants: %w", err)
+	}
+	defer rows.Close()
+
+	var variants []models.ProductVariant
+	for rows.Next() {
+		var v models.ProductVariant
+		if err := scanVariant(rows, &v); err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadOptions(ctx, variants); err != nil {
+		return nil, err
+	}
+	return variants, nil
+}
+
+// loadOptions fills in the option values of the variants with a single query
+func (r *VariantRepo) loadOptions(ctx context.Context, variants []models.ProductVariant) error {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(variants))
+	byID := make(map[int]*models.ProductVariant, len(variants))
+	for i := range variants {
+		ids[i] = variants[i].ID
+		byID[variants[i].ID] = &variants[i]
+		variants[i].Options = []models.VariantOption{}
+	}
+
+	rows, err := r.DB.Query(ctx,
+		`SELECT vo.variant_id, ot.name, vo.value
+		 FROM variant_option_values vo
+		 JOIN option_types ot ON ot.id = vo.option_type_id
+		 WHERE vo.variant_id = ANY($1)
+		 ORDER BY ot.id`, ids)
+	if err != nil {
+		return fmt.Errorf("load variant options: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var variantID int
+		var opt models.VariantOption
+		if err := rows.Scan(&variantID, &opt.Name, &opt.Value); err != nil {
+			return err
+		}
+		byID[variantID].Options = append(byID[variantID].Options, opt)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,94 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func TestCreateVariantAndReserveStock(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	customerRepo := repo.NewCustomerRepo(db)
+	productRepo := repo.NewProductRepo(db)
+	variantRepo := repo.NewVariantRepo(db)
+	orderRepo := repo.NewOrderRepo(db)
+
+	customer, err := customerRepo.CreateCustomer(ctx, &models.Customer{
+		AuthID:    "auth0|variant-test-" + RandString(8),
+		FirstName: "Variant",
+		LastName:  "Tester",
+		Email:     "variant_tester_" + RandString(8) + "@example.com",
+		Phone:     "+1111111111",
+	})
+	if err != nil {
+		t.Fatalf("failed to create customer: %v", err)
+	}
+
+	product, err := productRepo.CreateProduct(ctx, "Running Shoe", nil, 80, nil)
+	if err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	override := 95.0
+	variant, err := variantRepo.CreateVariant(ctx, &models.ProductVariant{
+		ProductID: product.ID,
+		SKU:       "SHOE-42-RED-" + RandString(6),
+		Price:     &override,
+		Stock:     2,
+		Options: []models.VariantOption{
+			{Name: "size", Value: "42"},
+			{Name: "colour", Value: "red"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateVariant failed: %v", err)
+	}
+	if variant.UnitPrice != override || len(variant.Options) != 2 {
+		t.Errorf("unexpected variant: %+v", variant)
+	}
+
+	// same options again is rejected
+	_, err = variantRepo.CreateVariant(ctx, &models.ProductVariant{
+		ProductID: product.ID,
+		SKU:       "SHOE-42-RED-DUP-" + RandString(6),
+		Options: []models.VariantOption{
+			{Name: "colour", Value: "red"},
+			{Name: "size", Value: "42"},
+		},
+	})
+	if err == nil {
+		t.Error("expected duplicate variant options to be rejected")
+	}
+
+	// products with variants can't be ordered without one
+	_, err = orderRepo.CreateOrder(ctx, customer.ID, []models.OrderItemInput{
+		{ProductID: product.ID, Quantity: 1, Price: 95},
+	})
+	if err == nil {
+		t.Error("expected order without variant to fail")
+	}
+
+	// the variant price is charged, not the price the caller sends
+	order, err := orderRepo.CreateOrder(ctx, customer.ID, []models.OrderItemInput{
+		{ProductID: product.ID, VariantID: &variant.ID, Quantity: 2, Price: 1},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+	if order.Subtotal != 190 {
+		t.Errorf("expected the variant price in the subtotal, got %v", order.Subtotal)
+	}
+
+	_, err = orderRepo.CreateOrder(ctx, customer.ID, []models.OrderItemInput{
+		{ProductID: product.ID, VariantID: &variant.ID, Quantity: 1, Price: 95},
+	})
+	if err == nil {
+		t.Error("expected order to fail once the variant is out of stock")
+	}
+}
@@ -0,0 +1,362 @@
+package repo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WebhookRepo struct {
+	DB *pgxpool.Pool
+}
+
+func NewWebhookRepo(db *pgxpool.Pool) *WebhookRepo {
+	return &WebhookRepo{DB: db}
+}
+
+const webhookSubscriptionColumns = `id, url, secret, event_types, active, created_at`
+
+func scanWebhookSubscription(row pgx.Row, s *models.WebhookSubscription) error {
+	return row.Scan(&s.ID, &s.URL, &s.Secret, &s.EventTypes, &s.Active, &s.CreatedAt)
+}
+
+const webhookDeliveryColumns = `id, subscription_id, event_type, payload::text, status, attempts,
+	next_attempt_at, last_status_code, last_error, created_at, delivered_at`
+
+func scanWebhookDelivery(row pgx.Row, d *models.WebhookDelivery) error {
+	var payload string
+	err := row.Scan(&d.ID, &d.SubscriptionID, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+	d.Payload = []byte(payload)
+	return err
+}
+
+// creates a subscription, making up a secret when none is given
+func (r *WebhookRepo) CreateSubscription(ctx context.Context, s *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	secret := s.Secret
+	if secret == "" {
+		var err error
+		if secret, err = randomHex(32); err != nil {
+			return nil, err
+		}
+	}
+
+	var created models.WebhookSubscription
+	err := scanWebhookSubscription(r.DB.QueryRow(ctx,
+		`INSERT INTO webhook_subscriptions (url, secret, event_types) VALUES ($1, $2, $3)
+		 RETURNING `+webhookSubscriptionColumns,
+		s.URL, secret, s.EventTypes,
+	), &created)
+	if err != nil {
+		return nil, fmt.Errorf("create webhook subscription: %w", err)
+	}
+	return &created, nil
+}
+
+// get all subscriptions, oldest first
+func (r *WebhookRepo) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	rows, err := r.DB.Query(ctx,
+		`SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []models.WebhookSubscription
+	for rows.Next() {
+		var s models.WebhookSubscription
+		if err := scanWebhookSubscription(rows, &s); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	return subscriptions, rows.Err()
+}
+
+// SetSubscriptionActive pauses or resumes a subscription. Paused subscriptions
+// get no new deliveries, the ones already queued are still sent.
+func (r *WebhookRepo) SetSubscriptionActive(ctx context.Context, id int, active bool) (*models.WebhookSubscription, error) {
+	var s models.WebhookSubscription
+	err := scanWebhookSubscription(r.DB.QueryRow(ctx,
+		`UPDATE webhook_subscriptions SET active = $2 WHERE id = $1 RETURNING `+webhookSubscriptionColumns,
+		id, active,
+	), &s)
+	if err != nil {
+		return nil, rowErr(err, "update webhook subscription", "webhook subscription %d not found", id)
+	}
+	return &s, nil
+}
+
+// deletes a subscription along with its delivery log
+func (r *WebhookRepo) DeleteSubscription(ctx context.Context, id int) error {
+	cmdTag, err := r.DB.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete webhook subscription: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return NotFound("webhook subscription %d not found", id)
+	}
+	return nil
+}
+
+// ListDeliveries returns the delivery log, newest first, optionally only for
+// one subscription or in one status
+func (r *WebhookRepo) ListDeliveries(ctx context.Context, subscriptionID *int, status *string, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := r.DB.Query(ctx,
+		`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries
+		 WHERE ($1::int IS NULL OR subscription_id = $1) AND ($2::text IS NULL OR status = $2)
+		 ORDER BY id DESC LIMIT $3`,
+		subscriptionID, status, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := scanWebhookDelivery(rows, &d); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// ReplayDelivery queues the payload of an earlier delivery again as a new
+// delivery, whatever became of the original. The event keeps its ID so
+// receivers can tell it is a repeat.
+func (r *WebhookRepo) ReplayDelivery(ctx context.Context, id int) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	err := scanWebhookDelivery(r.DB.QueryRow(ctx,
+		`INSERT INTO webhook_deliveries (subscription_id, event_type, payload)
+		 SELECT subscription_id, event_type, payload FROM webhook_deliveries WHERE id = $1
+		 RETURNING `+webhookDeliveryColumns,
+		id,
+	), &d)
+	if err != nil {
+		return nil, rowErr(err, "replay webhook delivery", "webhook delivery %d not found", id)
+	}
+	return &d, nil
+}
+
+// DueDelivery is a delivery to attempt along with where it goes and how to sign it
+type DueDelivery struct {
+	models.WebhookDelivery
+	URL    string
+	Secret string
+}
+
+// ClaimDue takes up to limit pending deliveries whose next attempt is due and
+// pushes their next attempt lease into the future, so other dispatchers leave
+// them alone while they are being sent. A claim that is never recorded is
+// simply retried once the lease runs out.
+func (r *WebhookRepo) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]DueDelivery, error) {
+	rows, err := r.DB.Query(ctx, `
+		WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at, id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM due, webhook_subscriptions s
+		WHERE d.id = due.id AND s.id = d.subscription_id
+		RETURNING d.id, d.subscription_id, d.event_type, d.payload::text, d.status, d.attempts,
+		          d.next_attempt_at, d.last_status_code, d.last_error, d.created_at, d.delivered_at,
+		          s.url, s.secret`,
+		limit, lease.Seconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var due []DueDelivery
+	for rows.Next() {
+		var d DueDelivery
+		var payload string
+		err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventType, &payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt,
+			&d.URL, &d.Secret)
+		if err != nil {
+			return nil, err
+		}
+		d.Payload = []byte(payload)
+		due = append(due, d)
+	}
+	return due, rows.Err()
+}
+
+// RecordAttempt logs the outcome of sending a delivery. A nil retryAt with a
+// failure gives up on the delivery; statusCode is nil when no response came back.
+func (r *WebhookRepo) RecordAttempt(ctx context.Context, id int, statusCode *int, attemptErr error, retryAt *time.Time) error {
+	status := models.WebhookDeliveryDelivered
+	var lastError *string
+	if attemptErr != nil {
+		msg := attemptErr.Error()
+		lastError = &msg
+		status = models.WebhookDeliveryFailed
+		if retryAt != nil {
+			status = models.WebhookDeliveryPending
+		}
+	}
+
+	_, err := r.DB.Exec(ctx,
+		`UPDATE webhook_deliveries
+		 SET status = $2, attempts = attempts + 1, last_status_code = $3, last_error = $4,
+		     next_attempt_at = $5,
+		     delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() END
+		 WHERE id = $1`,
+		id, status, statusCode, lastError, retryAt,
+	)
+	if err != nil {
+		return fmt.Errorf("record webhook attempt: %w", err)
+	}
+	return nil
+}
+
+// ReleaseClaims makes claimed deliveries that weren't attempted due again, so
+// another dispatcher picks them up without waiting out the lease
+func (r *WebhookRepo) ReleaseClaims(ctx context.Context, ids []int) error {
+	_, err := r.DB.Exec(ctx,
+		`UPDATE webhook_deliveries SET next_attempt_at = NOW()
+		 WHERE id = ANY($1) AND status = 'pending'`,
+		ids,
+	)
+	if err != nil {
+		return fmt.Errorf("release webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// webhookEvent is the body of every delivery
+type webhookEvent struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// enqueueWebhook queues the event for every active subscription to it. It runs
+// in the transaction making the change, so events are sent exactly for the
+// changes that commit. data is only built when someone is listening.
+func enqueueWebhook(ctx context.Context, tx pgx.Tx, event string, data func() (any, error)) error {
+	var wanted bool
+	err := tx.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM webhook_subscriptions WHERE active AND $1 = ANY(event_types))`, event,
+	).Scan(&wanted)
+	if err != nil {
+		return fmt.Errorf("check webhook subscriptions: %w", err)
+	}
+	if !wanted {
+		return nil
+	}
+
+	d, err := data()
+	if err != nil {
+		return err
+	}
+	id, err := randomHex(16)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(webhookEvent{ID: id, Type: event, CreatedAt: time.Now().UTC(), Data: d})
+	if err != nil {
+		return fmt.Errorf("encode webhook event: %w", err)
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO webhook_deliveries (subscription_id, event_type, payload)
+		 SELECT id, $1, $2 FROM webhook_subscriptions WHERE active AND $1 = ANY(event_types)`,
+		event, payload,
+	)
+	if err != nil {
+		return fmt.Errorf("queue webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// orderEventData is what order events carry
+type orderEventData struct {
+	Order          models.Order       `json:"order"`
+	Items          []models.OrderItem `json:"items"`
+	PreviousStatus *string            `json:"previous_status,omitempty"`
+}
+
+// enqueueOrderEvent queues an order event carrying the order as it stands in tx
+func enqueueOrderEvent(ctx context.Context, tx pgx.Tx, event string, orderID int, previousStatus *string) error {
+	return enqueueWebhook(ctx, tx, event, func() (any, error) {
+		data := orderEventData{Items: []models.OrderItem{}, PreviousStatus: previousStatus}
+		err := scanOrder(tx.QueryRow(ctx, `SELECT `+orderColumns+` FROM orders WHERE id = $1`, orderID), &data.Order)
+		if err != nil {
+			return nil, fmt.Errorf("get order: %w", err)
+		}
+
+		rows, err := tx.Query(ctx,
+			`SELECT id, order_id, product_id, variant_id, quantity, price FROM order_items
+			 WHERE order_id = $1 ORDER BY id`, orderID)
+		if err != nil {
+			return nil, fmt.Errorf("list order items: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var item models.OrderItem
+			if err := rows.Scan(&item.ID, &item.OrderID, &item.ProductID, &item.VariantID, &item.Quantity, &item.Price); err != nil {
+				return nil, err
+			}
+			data.Items = append(data.Items, item)
+		}
+		return data, rows.Err()
+	})
+}
+
+// productEventData is what product events carry. Variant options are left out.
+type productEventData struct {
+	Product  models.Product          `json:"product"`
+	Variants []models.ProductVariant `json:"variants"`
+}
+
+// enqueueProductEvent queues a product event carrying the product and its
+// variants as they stand in tx
+func enqueueProductEvent(ctx context.Context, tx pgx.Tx, event string, productID int) error {
+	return enqueueWebhook(ctx, tx, event, func() (any, error) {
+		data := productEventData{Variants: []models.ProductVariant{}}
+		err := scanProduct(tx.QueryRow(ctx, `SELECT `+productColumns+` FROM products WHERE id = $1`, productID), &data.Product)
+		if err != nil {
+			return nil, fmt.Errorf("get product: %w", err)
+		}
+
+		rows, err := tx.Query(ctx, variantSelect+` WHERE v.product_id = $1 ORDER BY v.id`, productID)
+		if err != nil {
+			return nil, fmt.Errorf("list variants: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var v models.ProductVariant
+			if err := scanVariant(rows, &v); err != nil {
+				return nil, err
+			}
+			data.Variants = append(data.Variants, v)
+		}
+		return data, rows.Err()
+	})
+}
+
+func randomHex(n int) (string, error) {
+	raw := make([]byte, n)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
@@ -0,0 +1,144 @@
+package repo_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func TestWebhookDeliveriesAreQueuedWithChanges(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	customerRepo := repo.NewCustomerRepo(db)
+	productRepo := repo.NewProductRepo(db)
+	orderRepo := repo.NewOrderRepo(db)
+	webhookRepo := repo.NewWebhookRepo(db)
+
+	subscription, err := webhookRepo.CreateSubscription(ctx, &models.WebhookSubscription{
+		URL:        "https://partner.example.com/hooks",
+		EventTypes: []string{models.WebhookOrderCreated, models.WebhookOrderStatusChanged},
+	})
+	if err != nil {
+		t.Fatalf("CreateSubscription failed: %v", err)
+	}
+	defer webhookRepo.DeleteSubscription(ctx, subscription.ID)
+	if len(subscription.Secret) != 64 || !subscription.Active {
+		t.Errorf("expected an active subscription with a generated secret, got %+v", subscription)
+	}
+
+	customer, err := customerRepo.CreateCustomer(ctx, &models.Customer{
+		AuthID:    "auth0|webhook-test-" + RandString(8),
+		FirstName: "Hook",
+		LastName:  "Tester",
+		Email:     "webhook_tester_" + RandString(8) + "@example.com",
+		Phone:     "+1111111111",
+	})
+	if err != nil {
+		t.Fatalf("failed to create customer: %v", err)
+	}
+	// product events aren't subscribed to, so this queues nothing
+	product, err := productRepo.CreateProduct(ctx, "Lamp", nil, 25, nil)
+	if err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+	order, err := orderRepo.CreateOrder(ctx, customer.ID, []models.OrderItemInput{
+		{ProductID: product.ID, Quantity: 2, Price: 25},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+	if err := orderRepo.UpdateOrderStatus(ctx, order.ID, models.OrderStatusPaid, nil); err != nil {
+		t.Fatalf("UpdateOrderStatus failed: %v", err)
+	}
+
+	deliveries, err := webhookRepo.ListDeliveries(ctx, &subscription.ID, nil, 10)
+	if err != nil {
+		t.Fatalf("ListDeliveries failed: %v", err)
+	}
+	if len(deliveries) != 2 {
+		t.Fatalf("expected 2 deliveries, got %d", len(deliveries))
+	}
+	changed, created := deliveries[0], deliveries[1]
+	if created.EventType != models.WebhookOrderCreated || changed.EventType != models.WebhookOrderStatusChanged {
+		t.Errorf("unexpected events %q and %q", created.EventType, changed.EventType)
+	}
+
+	var event struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Order          models.Order       `json:"order"`
+			Items          []models.OrderItem `json:"items"`
+			PreviousStatus *string            `json:"previous_status"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(changed.Payload, &event); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	if event.Data.Order.ID != order.ID || event.Data.Order.Status != models.OrderStatusPaid || len(event.Data.Items) != 1 {
+		t.Errorf("unexpected order in payload: %+v", event.Data)
+	}
+	if event.Data.PreviousStatus == nil || *event.Data.PreviousStatus != models.OrderStatusPending {
+		t.Errorf("expected the previous status to be pending, got %v", event.Data.PreviousStatus)
+	}
+
+	// only the subscription's own deliveries are claimed here, others may be due too
+	var claimed []repo.DueDelivery
+	for {
+		due, err := webhookRepo.ClaimDue(ctx, 100, time.Minute)
+		if err != nil {
+			t.Fatalf("ClaimDue failed: %v", err)
+		}
+		if len(due) == 0 {
+			break
+		}
+		for _, d := range due {
+			if d.SubscriptionID == subscription.ID {
+				claimed = append(claimed, d)
+			}
+		}
+	}
+	if len(claimed) != 2 || claimed[0].URL != subscription.URL || claimed[0].Secret != subscription.Secret {
+		t.Fatalf("expected both deliveries to be claimed with their endpoint, got %+v", claimed)
+	}
+
+	statusCode := 200
+	if err := webhookRepo.RecordAttempt(ctx, created.ID, &statusCode, nil, nil); err != nil {
+		t.Fatalf("RecordAttempt failed: %v", err)
+	}
+	statusCode = 500
+	if err := webhookRepo.RecordAttempt(ctx, changed.ID, &statusCode, errors.New("endpoint responded 500"), nil); err != nil {
+		t.Fatalf("RecordAttempt failed: %v", err)
+	}
+
+	failed := models.WebhookDeliveryFailed
+	deliveries, err = webhookRepo.ListDeliveries(ctx, &subscription.ID, &failed, 10)
+	if err != nil {
+		t.Fatalf("ListDeliveries failed: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].ID != changed.ID || deliveries[0].Attempts != 1 {
+		t.Fatalf("expected the status change delivery to have failed, got %+v", deliveries)
+	}
+
+	replayed, err := webhookRepo.ReplayDelivery(ctx, changed.ID)
+	if err != nil {
+		t.Fatalf("ReplayDelivery failed: %v", err)
+	}
+	var replayedEvent struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(replayed.Payload, &replayedEvent); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	if replayed.Status != models.WebhookDeliveryPending || replayed.Attempts != 0 || replayedEvent.ID != event.ID {
+		t.Errorf("expected a fresh delivery of the same event, got %+v", replayed)
+	}
+}
@@ -0,0 +1,281 @@
+package repo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WishlistRepo struct {
+	DB *pgxpool.Pool
+}
+
+func NewWishlistRepo(db *pgxpool.Pool) *WishlistRepo {
+	return &WishlistRepo{DB: db}
+}
+
+const wishlistColumns = `id, customer_id, name, share_token, created_at`
+
+func scanWishlist(row pgx.Row, w *models.Wishlist) error {
+	return row.Scan(&w.ID, &w.CustomerID, &w.Name, &w.ShareToken, &w.CreatedAt)
+}
+
+// the price and availability of a wishlist item, given its product p and its
+// variant v, which is all NULLs when no variant was picked. Products without
+// variants are always in stock, others while any variant is.
+const (
+	wishlistItemPrice   = `COALESCE(v.price, p.price)`
+	wishlistItemInStock = `CASE WHEN v.id IS NOT NULL THEN v.stock > 0
+		ELSE COALESCE((SELECT bool_or(pv.stock > 0) FROM product_variants pv WHERE pv.product_id = p.id), TRUE) END`
+)
+
+// creates a named wishlist for a customer
+func (r *WishlistRepo) CreateWishlist(ctx context.Context, customerID int, name string) (*models.Wishlist, error) {
+	var w models.Wishlist
+	err := scanWishlist(r.DB.QueryRow(ctx,
+		`INSERT INTO wishlists (customer_id, name) VALUES ($1, $2)
+		 ON CONFLICT (customer_id, name) DO NOTHING
+		 RETURNING `+wishlistColumns,
+		customerID, name,
+	), &w)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, Conflict("you already have a wishlist named %q", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create wishlist: %w", err)
+	}
+	w.Items = []models.WishlistItem{}
+	return &w, nil
+}
+
+// DefaultWishlist returns the customer's "Saved for later" list, creating it
+// the first time
+func (r *WishlistRepo) DefaultWishlist(ctx context.Context, customerID int) (*models.Wishlist, error) {
+	var id int
+	// the no-op update makes RETURNING hand back the existing row too
+	err := r.DB.QueryRow(ctx,
+		`INSERT INTO wishlists (customer_id, name) VALUES ($1, $2)
+		 ON CONFLICT (customer_id, name) DO UPDATE SET name = EXCLUDED.name
+		 RETURNING id`,
+		customerID, models.DefaultWishlistName,
+	).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("get default wishlist: %w", err)
+	}
+	return r.GetWishlist(ctx, id)
+}
+
+// deletes a wishlist and its items
+func (r *WishlistRepo) DeleteWishlist(ctx context.Context, id int) error {
+	cmdTag, err := r.DB.Exec(ctx, `DELETE FROM wishlists WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete wishlist: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return NotFound("wishlist %d not found", id)
+	}
+	return nil
+}
+
+// AddItem puts a product, or one of its variants, on a wishlist. Adding it
+// again only updates whether the customer wants to be notified.
+func (r *WishlistRepo) AddItem(ctx context.Context, wishlistID int, item models.WishlistItem) (*models.Wishlist, error) {
+	cmdTag, err := r.DB.Exec(ctx,
+		`INSERT INTO wishlist_items (wishlist_id, product_id, variant_id, notify, alert_price, alert_in_stock)
+		 SELECT $1, p.id, v.id, $4, `+wishlistItemPrice+`, `+wishlistItemInStock+`
+		 FROM products p
+		 LEFT JOIN product_variants v ON v.id = $3 AND v.product_id = p.id
+		 WHERE p.id = $2 AND ($3::int IS NULL OR v.id IS NOT NULL)
+		 ON CONFLICT (wishlist_id, product_id, COALESCE(variant_id, 0)) DO UPDATE
+		 SET notify = EXCLUDED.notify,
+		     alert_price = EXCLUDED.alert_price,
+		     alert_in_stock = EXCLUDED.alert_in_stock`,
+		wishlistID, item.ProductID, item.VariantID, item.Notify,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("add wishlist item: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		if item.VariantID != nil {
+			return nil, NotFound("variant %d of product %d not found", *item.VariantID, item.ProductID)
+		}
+		return nil, NotFound("product %d not found", item.ProductID)
+	}
+	return r.GetWishlist(ctx, wishlistID)
+}
+
+// takes a product, or one of its variants, off a wishlist
+func (r *WishlistRepo) RemoveItem(ctx context.Context, wishlistID, productID int, variantID *int) (*models.Wishlist, error) {
+	cmdTag, err := r.DB.Exec(ctx,
+		`DELETE FROM wishlist_items
+		 WHERE wishlist_id = $1 AND product_id = $2 AND variant_id IS NOT DISTINCT FROM $3`,
+		wishlistID, productID, variantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("remove wishlist item: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return nil, NotFound("product %d is not on wishlist %d", productID, wishlistID)
+	}
+	return r.GetWishlist(ctx, wishlistID)
+}
+
+// Share gives a wishlist a random share token, keeping the one it has if it
+// is already shared
+func (r *WishlistRepo) Share(ctx context.Context, id int) (*models.Wishlist, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	return r.setShareToken(ctx, id, `COALESCE(share_token, $2)`, hex.EncodeToString(raw))
+}
+
+// Unshare turns off a wishlist's link, so the old token stops working
+func (r *WishlistRepo) Unshare(ctx context.Context, id int) (*models.Wishlist, error) {
+	return r.setShareToken(ctx, id, `NULL`)
+}
+
+func (r *WishlistRepo) setShareToken(ctx context.Context, id int, value string, args ...any) (*models.Wishlist, error) {
+	cmdTag, err := r.DB.Exec(ctx,
+		`UPDATE wishlists SET share_token = `+value+` WHERE id = $1`, append([]any{id}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("update wishlist share token: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return nil, NotFound("wishlist %d not found", id)
+	}
+	return r.GetWishlist(ctx, id)
+}
+
+// get a wishlist with its items
+func (r *WishlistRepo) GetWishlist(ctx context.Context, id int) (*models.Wishlist, error) {
+	return r.get(ctx, `SELECT `+wishlistColumns+` FROM wishlists WHERE id = $1`, id)
+}
+
+// get a shared wishlist by the token in its link
+func (r *WishlistRepo) GetWishlistByShareToken(ctx context.Context, token string) (*models.Wishlist, error) {
+	return r.get(ctx, `SELECT `+wishlistColumns+` FROM wishlists WHERE share_token = $1`, token)
+}
+
+// get a customer's wishlists, oldest first
+func (r *WishlistRepo) ListWishlistsByCustomer(ctx context.Context, customerID int) ([]models.Wishlist, error) {
+	return r.list(ctx, `SELECT `+wishlistColumns+` FROM wishlists WHERE customer_id = $1 ORDER BY id`, customerID)
+}
+
+func (r *WishlistRepo) get(ctx context.Context, query string, args ...any) (*models.Wishlist, error) {
+	wishlists, err := r.list(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(wishlists) == 0 {
+		return nil, NotFound("wishlist not found")
+	}
+	return &wishlists[0], nil
+}
+
+func (r *WishlistRepo) list(ctx context.Context, query string, args ...any) ([]models.Wishlist, error) {
+	rows, err := r.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list wishlists: %w", err)
+	}
+	defer rows.Close()
+
+	var wishlists []models.Wishlist
+	for rows.Next() {
+		var w models.Wishlist
+		if err := scanWishlist(rows, &w); err != nil {
+			return nil, err
+		}
+		wishlists = append(wishlists, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadItems(ctx, wishlists); err != nil {
+		return nil, err
+	}
+	return wishlists, nil
+}
+
+// loadItems fills in the items of the wishlists, oldest first
+func (r *WishlistRepo) loadItems(ctx context.Context, wishlists []models.Wishlist) error {
+	if len(wishlists) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(wishlists))
+	byID := make(map[int]*models.Wishlist, len(wishlists))
+	for i := range wishlists {
+		ids[i] = wishlists[i].ID
+		byID[wishlists[i].ID] = &wishlists[i]
+		wishlists[i].Items = []models.WishlistItem{}
+	}
+
+	rows, err := r.DB.Query(ctx,
+		`SELECT wishlist_id, product_id, variant_id, notify, added_at FROM wishlist_items
+		 WHERE wishlist_id = ANY($1) ORDER BY id`, ids)
+	if err != nil {
+		return fmt.Errorf("load wishlist items: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var wishlistID int
+		var item models.WishlistItem
+		if err := rows.Scan(&wishlistID, &item.ProductID, &item.VariantID, &item.Notify, &item.AddedAt); err != nil {
+			return err
+		}
+		byID[wishlistID].Items = append(byID[wishlistID].Items, item)
+	}
+	return rows.Err()
+}
+
+// TakeAlerts finds the wishlisted items with notifications on that came back
+// in stock or got cheaper since the customer last heard about them, and
+// remembers their current state so each change is only reported once. Only
+// items of the given products are checked, or all of them when productIDs is nil.
+func (r *WishlistRepo) TakeAlerts(ctx context.Context, productIDs []int) ([]models.WishlistAlert, error) {
+	rows, err := r.DB.Query(ctx, `
+		WITH current AS (
+			SELECT wi.id, wi.alert_price AS old_price, wi.alert_in_stock AS was_in_stock,
+			       `+wishlistItemPrice+` AS price, `+wishlistItemInStock+` AS in_stock,
+			       TRIM(c.first_name || ' ' || c.last_name) AS customer_name, c.phone, p.name AS product_name
+			FROM wishlist_items wi
+			JOIN wishlists w ON w.id = wi.wishlist_id
+			JOIN customers c ON c.id = w.customer_id
+			JOIN products p ON p.id = wi.product_id
+			LEFT JOIN product_variants v ON v.id = wi.variant_id
+			WHERE wi.notify AND ($1::int[] IS NULL OR wi.product_id = ANY($1))
+		)
+		UPDATE wishlist_items wi
+		SET alert_price = c.price, alert_in_stock = c.in_stock
+		FROM current c
+		WHERE wi.id = c.id AND (c.price <> c.old_price OR c.in_stock <> c.was_in_stock)
+		RETURNING c.customer_name, c.phone, c.product_name, c.old_price, c.price,
+		          c.in_stock AND NOT c.was_in_stock`,
+		productIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("take wishlist alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []models.WishlistAlert
+	for rows.Next() {
+		var a models.WishlistAlert
+		if err := rows.Scan(&a.CustomerName, &a.Phone, &a.ProductName, &a.OldPrice, &a.Price, &a.BackInStock); err != nil {
+			return nil, err
+		}
+		// price rises and items selling out are remembered but not worth a text
+		if a.BackInStock || a.PriceDropped() {
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts, rows.Err()
+}
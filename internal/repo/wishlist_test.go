@@ -0,0 +1,113 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func TestWishlistAlertsAndSharing(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	customerRepo := repo.NewCustomerRepo(db)
+	productRepo := repo.NewProductRepo(db)
+	variantRepo := repo.NewVariantRepo(db)
+	wishlistRepo := repo.NewWishlistRepo(db)
+
+	customer, err := customerRepo.CreateCustomer(ctx, &models.Customer{
+		AuthID:    "auth0|wishlist-test-" + RandString(8),
+		FirstName: "Wish",
+		LastName:  "Tester",
+		Email:     "wishlist_tester_" + RandString(8) + "@example.com",
+		Phone:     "+1111111111",
+	})
+	if err != nil {
+		t.Fatalf("failed to create customer: %v", err)
+	}
+	product, err := productRepo.CreateProduct(ctx, "Raincoat", nil, 80, nil)
+	if err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+	variant, err := variantRepo.CreateVariant(ctx, &models.ProductVariant{
+		ProductID: product.ID,
+		SKU:       "COAT-M-" + RandString(6),
+		Stock:     0,
+		Options:   []models.VariantOption{{Name: "size", Value: "M"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateVariant failed: %v", err)
+	}
+
+	saved, err := wishlistRepo.DefaultWishlist(ctx, customer.ID)
+	if err != nil {
+		t.Fatalf("DefaultWishlist failed: %v", err)
+	}
+	again, err := wishlistRepo.DefaultWishlist(ctx, customer.ID)
+	if err != nil || again.ID != saved.ID {
+		t.Fatalf("expected the same default wishlist, got %+v (%v)", again, err)
+	}
+
+	saved, err = wishlistRepo.AddItem(ctx, saved.ID, models.WishlistItem{ProductID: product.ID, VariantID: &variant.ID, Notify: true})
+	if err != nil {
+		t.Fatalf("AddItem failed: %v", err)
+	}
+	if len(saved.Items) != 1 || !saved.Items[0].Notify {
+		t.Fatalf("expected one item to notify about, got %+v", saved.Items)
+	}
+
+	alerts, err := wishlistRepo.TakeAlerts(ctx, []int{product.ID})
+	if err != nil {
+		t.Fatalf("TakeAlerts failed: %v", err)
+	}
+	if len(alerts) != 0 {
+		t.Errorf("expected no alerts before anything changed, got %+v", alerts)
+	}
+
+	if err := variantRepo.UpdateVariantStock(ctx, variant.ID, 5); err != nil {
+		t.Fatalf("UpdateVariantStock failed: %v", err)
+	}
+	alerts, err = wishlistRepo.TakeAlerts(ctx, []int{product.ID})
+	if err != nil {
+		t.Fatalf("TakeAlerts failed: %v", err)
+	}
+	if len(alerts) != 1 || !alerts[0].BackInStock || alerts[0].ProductName != "Raincoat" {
+		t.Fatalf("expected a back in stock alert, got %+v", alerts)
+	}
+	alerts, err = wishlistRepo.TakeAlerts(ctx, nil)
+	if err != nil {
+		t.Fatalf("TakeAlerts failed: %v", err)
+	}
+	for _, a := range alerts {
+		if a.Phone == customer.Phone && a.ProductName == "Raincoat" {
+			t.Errorf("expected the restock to be reported once, got %+v", a)
+		}
+	}
+
+	shared, err := wishlistRepo.Share(ctx, saved.ID)
+	if err != nil || shared.ShareToken == nil {
+		t.Fatalf("Share failed: %+v (%v)", shared, err)
+	}
+	found, err := wishlistRepo.GetWishlistByShareToken(ctx, *shared.ShareToken)
+	if err != nil || found.ID != saved.ID {
+		t.Errorf("expected to find the wishlist by its token, got %+v (%v)", found, err)
+	}
+	if _, err := wishlistRepo.Unshare(ctx, saved.ID); err != nil {
+		t.Fatalf("Unshare failed: %v", err)
+	}
+	if _, err := wishlistRepo.GetWishlistByShareToken(ctx, *shared.ShareToken); err == nil {
+		t.Error("expected the old token to stop working")
+	}
+
+	saved, err = wishlistRepo.RemoveItem(ctx, saved.ID, product.ID, &variant.ID)
+	if err != nil {
+		t.Fatalf("RemoveItem failed: %v", err)
+	}
+	if len(saved.Items) != 0 {
+		t.Errorf("expected an empty wishlist, got %+v", saved.Items)
+	}
+}
@@ -0,0 +1,133 @@
+// Package server runs the HTTP server and the background workers beside it,
+// and stops both cleanly when the process is asked to. Kubernetes sends
+// SIGTERM before replacing a pod, so requests in flight, such as an order
+// being placed, are given time to finish rather than cut off.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/config"
+)
+
+// Server is an HTTP server plus the workers that stop with it
+type Server struct {
+	http            *http.Server
+	shutdownDelay   time.Duration
+	shutdownTimeout time.Duration
+
+	// ctx is the base of every request and worker, cancelled once the
+	// server has drained
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+
+	onShutdown []func()
+}
+
+func New(addr string, cfg config.Server) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		shutdownDelay:   cfg.ShutdownDelay,
+		shutdownTimeout: cfg.ShutdownTimeout,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+	s.http = &http.Server{
+		Addr:              addr,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	return s
+}
+
+// Context is done once the server has stopped taking requests and the ones
+// in flight have finished. Background workers and long-lived connections,
+// such as GraphQL subscriptions, should stop with it.
+func (s *Server) Context() context.Context {
+	return s.ctx
+}
+
+// Go runs fn in the background. fn should return soon after its context is
+// done, shutting down waits for it.
+func (s *Server) Go(fn func(ctx context.Context)) {
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		fn(s.ctx)
+	}()
+}
+
+// OnShutdown registers f to run as soon as shutting down starts, while
+// requests are still being taken. It must be called before Run.
+func (s *Server) OnShutdown(f func()) {
+	s.onShutdown = append(s.onShutdown, f)
+}
+
+// Run serves handler until ctx is done, then shuts down: it runs the
+// OnShutdown hooks, keeps serving for the shutdown delay, stops accepting
+// connections, waits for requests in flight, then stops the workers and waits
+// for them, all within the shutdown timeout. Whatever is still running at the
+// deadline is cut off.
+func (s *Server) Run(ctx context.Context, handler http.Handler) error {
+	ln, err := net.Listen("tcp", s.http.Addr)
+	if err != nil {
+		s.cancel()
+		return fmt.Errorf("listen on %s: %w", s.http.Addr, err)
+	}
+	return s.serve(ctx, ln, handler)
+}
+
+func (s *Server) serve(ctx context.Context, ln net.Listener, handler http.Handler) error {
+	s.http.Handler = handler
+	served := make(chan error, 1)
+	go func() { served <- s.http.Serve(ln) }()
+
+	select {
+	case err := <-served:
+		s.cancel()
+		return fmt.Errorf("serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	for _, f := range s.onShutdown {
+		f()
+	}
+	if s.shutdownDelay > 0 {
+		log.Printf("shutting down in %s, still serving meanwhile", s.shutdownDelay)
+		time.Sleep(s.shutdownDelay)
+	}
+
+	log.Printf("shutting down, waiting up to %s for requests and workers to finish", s.shutdownTimeout)
+	deadline, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	if err := s.http.Shutdown(deadline); err != nil {
+		errs = append(errs, fmt.Errorf("drain requests: %w", err))
+		_ = s.http.Close()
+	}
+	s.cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-deadline.Done():
+		errs = append(errs, errors.New("background workers still running at the shutdown deadline"))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/config"
+)
+
+func testServer(t *testing.T, shutdownTimeout time.Duration) (*Server, net.Listener) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	cfg := config.Default().Server
+	cfg.ShutdownTimeout = shutdownTimeout
+	return New(ln.Addr().String(), cfg), ln
+}
+
+func TestShutdownFinishesRequestsInFlightThenStopsWorkers(t *testing.T) {
+	s, ln := testServer(t, 5*time.Second)
+
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		if r.Context().Err() != nil {
+			t.Error("request context was cancelled before the request finished")
+		}
+		_, _ = io.WriteString(w, "placed")
+	})
+
+	workerStopped := make(chan time.Time, 1)
+	s.Go(func(ctx context.Context) {
+		<-ctx.Done()
+		workerStopped <- time.Now()
+	})
+
+	ctx, stop := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- s.serve(ctx, ln, handler) }()
+
+	type result struct {
+		body     string
+		err      error
+		finished time.Time
+	}
+	responses := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- result{body: string(body), err: err, finished: time.Now()}
+	}()
+
+	<-started
+	stop()
+
+	res := <-responses
+	if res.err != nil {
+		t.Fatalf("request in flight failed: %v", res.err)
+	}
+	if res.body != "placed" {
+		t.Errorf("expected the request to finish, got %q", res.body)
+	}
+	if err := <-served; err != nil {
+		t.Fatalf("expected a clean shutdown, got %v", err)
+	}
+	select {
+	case at := <-workerStopped:
+		if at.Before(res.finished.Add(-50 * time.Millisecond)) {
+			t.Error("worker was stopped before the request in flight finished")
+		}
+	default:
+		t.Fatal("expected the worker to have stopped by the time Run returned")
+	}
+
+	if _, err := http.Get("http://" + ln.Addr().String()); err == nil {
+		t.Error("expected new connections to be refused after shutdown")
+	}
+}
+
+func TestShutdownKeepsServingThroughTheDelay(t *testing.T) {
+	s, ln := testServer(t, time.Second)
+	s.shutdownDelay = 300 * time.Millisecond
+
+	hooked := make(chan struct{})
+	s.OnShutdown(func() { close(hooked) })
+
+	ctx, stop := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- s.serve(ctx, ln, http.NotFoundHandler()) }()
+	stop()
+
+	<-hooked
+	resp, err := http.Get("http://" + ln.Addr().String())
+	if err != nil {
+		t.Fatalf("expected requests to be served during the delay: %v", err)
+	}
+	resp.Body.Close()
+	if err := <-served; err != nil {
+		t.Fatalf("expected a clean shutdown, got %v", err)
+	}
+}
+
+func TestShutdownGivesUpAtTheDeadline(t *testing.T) {
+	s, ln := testServer(t, 100*time.Millisecond)
+
+	release := make(chan struct{})
+	defer close(release)
+	s.Go(func(ctx context.Context) {
+		<-release
+	})
+
+	ctx, stop := context.WithCancel(context.Background())
+	stop()
+	begin := time.Now()
+	err := s.serve(ctx, ln, http.NotFoundHandler())
+	if err == nil {
+		t.Fatal("expected an error for a worker that didn't stop")
+	}
+	if elapsed := time.Since(begin); elapsed > 2*time.Second {
+		t.Errorf("expected Run to return at the deadline, took %s", elapsed)
+	}
+}
+
+func TestWorkersStopWhenServingFails(t *testing.T) {
+	s, ln := testServer(t, time.Second)
+	ln.Close()
+
+	if err := s.serve(context.Background(), ln, http.NotFoundHandler()); err == nil {
+		t.Fatal("expected an error from a closed listener")
+	}
+	if s.Context().Err() == nil {
+		t.Error("expected the worker context to be cancelled")
+	}
+}
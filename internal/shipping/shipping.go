@@ -0,0 +1,138 @@
+// Package shipping prices delivery of a parcel to an address. It holds no
+// state; callers load methods, zones and rates from the shipping repo.
+package shipping
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+// VolumetricDivisor turns a parcel's volume in cm³ into the weight in kg
+// couriers charge for bulky but light items
+const VolumetricDivisor = 5000
+
+var ErrUnavailable = errors.New("shipping method is not available for this address")
+
+// Parcel is one order line as seen by the rate calculator
+type Parcel struct {
+	Product  models.Product
+	Quantity int
+}
+
+// Table is the full shipping configuration
+type Table struct {
+	Methods []models.ShippingMethod
+	Zones   []models.ShippingZone
+	Rates   []models.ShippingRate
+}
+
+// ChargeableWeight is the weight couriers bill for: per unit, the larger of
+// its actual weight and its volumetric weight. Products without a weight or
+// dimensions count as weightless.
+func ChargeableWeight(parcels []Parcel) float64 {
+	var total float64
+	for _, p := range parcels {
+		var actual, volumetric float64
+		if p.Product.WeightKg != nil {
+			actual = *p.Product.WeightKg
+		}
+		if p.Product.LengthCm != nil && p.Product.WidthCm != nil && p.Product.HeightCm != nil {
+			volumetric = *p.Product.LengthCm * *p.Product.WidthCm * *p.Product.HeightCm / VolumetricDivisor
+		}
+		total += float64(p.Quantity) * math.Max(actual, volumetric)
+	}
+	return math.Round(total*1000) / 1000
+}
+
+// Matches reports whether the address lies in the zone
+func Matches(z models.ShippingZone, a models.Address) bool {
+	if !containsFold(z.Countries, a.Country) {
+		return false
+	}
+	if len(z.Regions) == 0 {
+		return true
+	}
+	if containsFold(z.Regions, a.City) {
+		return true
+	}
+	return a.Region != nil && containsFold(z.Regions, *a.Region)
+}
+
+// Quotes prices every active method that can deliver weightKg to the address,
+// cheapest first. For each method the most specific matching zone with a rate
+// for the weight wins: a zone listing regions beats a country-wide one.
+func Quotes(t Table, a models.Address, weightKg float64) []models.ShippingQuote {
+	zones := matchingZones(t.Zones, a)
+
+	var quotes []models.ShippingQuote
+	for _, m := range t.Methods {
+		if !m.Active {
+			continue
+		}
+		for _, z := range zones {
+			if rate, ok := findRate(t.Rates, m.ID, z.ID, weightKg); ok {
+				quotes = append(quotes, models.ShippingQuote{
+					Method:   m,
+					Zone:     z,
+					WeightKg: weightKg,
+					Cost:     rate.Price,
+				})
+				break
+			}
+		}
+	}
+
+	sort.SliceStable(quotes, func(i, j int) bool { return quotes[i].Cost < quotes[j].Cost })
+	return quotes
+}
+
+// Quote prices a single method, failing with ErrUnavailable when it can't
+// deliver to the address
+func Quote(t Table, methodID int, a models.Address, weightKg float64) (*models.ShippingQuote, error) {
+	for _, q := range Quotes(t, a, weightKg) {
+		if q.Method.ID == methodID {
+			return &q, nil
+		}
+	}
+	return nil, ErrUnavailable
+}
+
+// matchingZones returns the zones covering the address, most specific first
+func matchingZones(zones []models.ShippingZone, a models.Address) []models.ShippingZone {
+	var matched []models.ShippingZone
+	for _, z := range zones {
+		if Matches(z, a) {
+			matched = append(matched, z)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return len(matched[i].Regions) > 0 && len(matched[j].Regions) == 0
+	})
+	return matched
+}
+
+func findRate(rates []models.ShippingRate, methodID, zoneID int, weightKg float64) (models.ShippingRate, bool) {
+	for _, r := range rates {
+		if r.MethodID != methodID || r.ZoneID != zoneID {
+			continue
+		}
+		if weightKg >= r.MinWeightKg && (r.MaxWeightKg == nil || weightKg < *r.MaxWeightKg) {
+			return r, true
+		}
+	}
+	return models.ShippingRate{}, false
+}
+
+func containsFold(values []string, s string) bool {
+	s = strings.TrimSpace(s)
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package shipping_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/shipping"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+// the default configuration seeded by the shipping migration
+var table = shipping.Table{
+	Methods: []models.ShippingMethod{
+		{ID: 1, Code: "pickup_station", Active: true},
+		{ID: 2, Code: "nairobi_same_day", Active: true},
+		{ID: 3, Code: "countrywide_courier", Active: true},
+		{ID: 4, Code: "retired", Active: false},
+	},
+	Zones: []models.ShippingZone{
+		{ID: 2, Name: "Kenya", Countries: []string{"KE"}},
+		{ID: 1, Name: "Nairobi", Countries: []string{"KE"}, Regions: []string{"nairobi"}},
+	},
+	Rates: []models.ShippingRate{
+		{MethodID: 1, ZoneID: 2, Price: 150},
+		{MethodID: 2, ZoneID: 1, MaxWeightKg: ptr(5), Price: 350},
+		{MethodID: 2, ZoneID: 1, MinWeightKg: 5, MaxWeightKg: ptr(20), Price: 600},
+		{MethodID: 3, ZoneID: 1, MaxWeightKg: ptr(5), Price: 250},
+		{MethodID: 3, ZoneID: 1, MinWeightKg: 5, Price: 450},
+		{MethodID: 3, ZoneID: 2, MaxWeightKg: ptr(5), Price: 450},
+		{MethodID: 3, ZoneID: 2, MinWeightKg: 5, Price: 800},
+		{MethodID: 4, ZoneID: 2, Price: 1},
+	},
+}
+
+func costs(quotes []models.ShippingQuote) map[string]float64 {
+	out := map[string]float64{}
+	for _, q := range quotes {
+		out[q.Method.Code] = q.Cost
+	}
+	return out
+}
+
+func TestQuotes(t *testing.T) {
+	nairobi := models.Address{City: "Nairobi", Country: "KE"}
+	mombasa := models.Address{City: "Mombasa", Country: "ke"}
+	kampala := models.Address{City: "Kampala", Country: "UG"}
+
+	got := costs(shipping.Quotes(table, nairobi, 2))
+	want := map[string]float64{"pickup_station": 150, "nairobi_same_day": 350, "countrywide_courier": 250}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for code, cost := range want {
+		if got[code] != cost {
+			t.Errorf("Nairobi %s: expected %.2f, got %.2f", code, cost, got[code])
+		}
+	}
+
+	// same-day only covers Nairobi and only up to 20kg
+	got = costs(shipping.Quotes(table, mombasa, 7))
+	if _, ok := got["nairobi_same_day"]; ok || got["countrywide_courier"] != 800 || got["pickup_station"] != 150 {
+		t.Errorf("unexpected Mombasa quotes: %v", got)
+	}
+	if _, ok := costs(shipping.Quotes(table, nairobi, 25))["nairobi_same_day"]; ok {
+		t.Error("expected same-day to be unavailable above 20kg")
+	}
+
+	if quotes := shipping.Quotes(table, kampala, 1); len(quotes) != 0 {
+		t.Errorf("expected no quotes outside Kenya, got %v", quotes)
+	}
+
+	quotes := shipping.Quotes(table, nairobi, 1)
+	for i := 1; i < len(quotes); i++ {
+		if quotes[i].Cost < quotes[i-1].Cost {
+			t.Errorf("expected quotes cheapest first, got %v", costs(quotes))
+		}
+	}
+}
+
+func TestQuoteUnavailable(t *testing.T) {
+	_, err := shipping.Quote(table, 2, models.Address{City: "Kisumu", Country: "KE"}, 1)
+	if !errors.Is(err, shipping.ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable, got %v", err)
+	}
+	if _, err := shipping.Quote(table, 4, models.Address{City: "Kisumu", Country: "KE"}, 1); err == nil {
+		t.Error("expected inactive methods to be unavailable")
+	}
+}
+
+func TestMatchesRegion(t *testing.T) {
+	zone := models.ShippingZone{Countries: []string{"KE"}, Regions: []string{"Nairobi"}}
+	region := "nairobi"
+	if !shipping.Matches(zone, models.Address{City: "Westlands", Region: &region, Country: "KE"}) {
+		t.Error("expected the region to match the zone")
+	}
+	if shipping.Matches(zone, models.Address{City: "Nairobi", Country: "UG"}) {
+		t.Error("expected a different country not to match")
+	}
+}
+
+func TestChargeableWeight(t *testing.T) {
+	heavy := models.Product{WeightKg: ptr(2)}
+	// 60x40x50cm is 24kg volumetric
+	bulky := models.Product{WeightKg: ptr(3), LengthCm: ptr(60), WidthCm: ptr(40), HeightCm: ptr(50)}
+	unknown := models.Product{}
+
+	got := shipping.ChargeableWeight([]shipping.Parcel{
+		{Product: heavy, Quantity: 3},
+		{Product: bulky, Quantity: 1},
+		{Product: unknown, Quantity: 5},
+	})
+	if got != 30 {
+		t.Errorf("expected 30kg, got %v", got)
+	}
+}
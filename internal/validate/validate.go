@@ -0,0 +1,191 @@
+// Package validate checks inputs against declarative rules and reports every
+// violation at once, each with the path of the field at fault, instead of
+// stopping at the first problem or leaving it to a database constraint.
+//
+//	v := validate.New("input")
+//	validate.Field(v, "name", input.Name, validate.Required(), validate.MaxLength(200))
+//	validate.Optional(v, "weightKg", input.WeightKg, validate.Min(0.0))
+//	if err := v.Err(); err != nil { ... }
+package validate
+
+import (
+	"cmp"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Violation is one problem with one field. Field is the path of the field
+// from the argument it is in, like "input.items.0.quantity".
+type Violation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error lists every violation found in an input
+type Error struct {
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Rule checks a value and describes what is wrong with it, like "is required",
+// or returns "" when the value is fine
+type Rule[T any] func(T) string
+
+// Validator collects violations for the fields under one path. Validators
+// made with At and Index share their parent's violations.
+type Validator struct {
+	path       string
+	violations *[]Violation
+}
+
+// New starts validating the fields under path, usually the argument name
+func New(path string) *Validator {
+	return &Validator{path: path, violations: &[]Violation{}}
+}
+
+// At validates the fields of a nested input
+func (v *Validator) At(name string) *Validator {
+	return &Validator{path: v.join(name), violations: v.violations}
+}
+
+// Index validates the fields of the i-th item of a list
+func (v *Validator) Index(i int) *Validator {
+	return v.At(strconv.Itoa(i))
+}
+
+// Check records message against the named field unless ok holds. The message
+// should read on from the field name, like "must have at least one item".
+func (v *Validator) Check(ok bool, name, message string) {
+	if !ok {
+		*v.violations = append(*v.violations, Violation{Field: v.join(name), Message: name + " " + message})
+	}
+}
+
+// Err returns an *Error with every violation recorded so far, or nil
+func (v *Validator) Err() error {
+	if len(*v.violations) == 0 {
+		return nil
+	}
+	return &Error{Violations: *v.violations}
+}
+
+func (v *Validator) join(name string) string {
+	if v.path == "" {
+		return name
+	}
+	return v.path + "." + name
+}
+
+// Field checks value against the rules in order, recording the first one it
+// breaks
+func Field[T any](v *Validator, name string, value T, rules ...Rule[T]) {
+	for _, rule := range rules {
+		if message := rule(value); message != "" {
+			v.Check(false, name, message)
+			return
+		}
+	}
+}
+
+// Optional checks value like Field when it is given and accepts nil
+func Optional[T any](v *Validator, name string, value *T, rules ...Rule[T]) {
+	if value != nil {
+		Field(v, name, *value, rules...)
+	}
+}
+
+// Required rejects strings that are empty or only whitespace
+func Required() Rule[string] {
+	return func(s string) string {
+		if strings.TrimSpace(s) == "" {
+			return "is required"
+		}
+		return ""
+	}
+}
+
+// MinLength rejects strings shorter than n characters
+func MinLength(n int) Rule[string] {
+	return func(s string) string {
+		if utf8.RuneCountInString(s) < n {
+			return fmt.Sprintf("must be at least %d characters", n)
+		}
+		return ""
+	}
+}
+
+// MaxLength rejects strings longer than n characters
+func MaxLength(n int) Rule[string] {
+	return func(s string) string {
+		if utf8.RuneCountInString(s) > n {
+			return fmt.Sprintf("must be at most %d characters", n)
+		}
+		return ""
+	}
+}
+
+// Email accepts a bare address like jane@example.com
+func Email() Rule[string] {
+	return func(s string) string {
+		addr, err := mail.ParseAddress(s)
+		if err != nil || addr.Address != s || !strings.Contains(s[strings.LastIndex(s, "@")+1:], ".") {
+			return "must be a valid email address"
+		}
+		return ""
+	}
+}
+
+// phonePattern is E.164: a plus, then up to 15 digits not starting with 0
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// Phone accepts international numbers like +254712345678, which is what SMS
+// notifications need
+func Phone() Rule[string] {
+	return func(s string) string {
+		if !phonePattern.MatchString(s) {
+			return "must be an international phone number like +254712345678"
+		}
+		return ""
+	}
+}
+
+// ID accepts the string form of a positive integer ID
+func ID() Rule[string] {
+	return func(s string) string {
+		if id, err := strconv.Atoi(s); err != nil || id <= 0 {
+			return "must be a valid ID"
+		}
+		return ""
+	}
+}
+
+// Min rejects values below n
+func Min[T cmp.Ordered](n T) Rule[T] {
+	return func(value T) string {
+		if value < n {
+			return fmt.Sprintf("must be at least %v", n)
+		}
+		return ""
+	}
+}
+
+// Max rejects values above n
+func Max[T cmp.Ordered](n T) Rule[T] {
+	return func(value T) string {
+		if value > n {
+			return fmt.Sprintf("must be at most %v", n)
+		}
+		return ""
+	}
+}
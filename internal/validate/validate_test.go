@@ -0,0 +1,75 @@
+package validate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidatorReportsEveryViolation(t *testing.T) {
+	name := ""
+	price := -5.0
+	weight := -1.0
+	quantities := []int{2, 0}
+
+	v := New("input")
+	Field(v, "name", name, Required(), MaxLength(10))
+	Field(v, "price", price, Min(0.0))
+	Optional(v, "weightKg", &weight, Min(0.0))
+	Optional[float64](v, "lengthCm", nil, Min(0.0))
+	items := v.At("items")
+	for i, q := range quantities {
+		Field(items.Index(i), "quantity", q, Min(1))
+	}
+
+	var verr *Error
+	if !errors.As(v.Err(), &verr) {
+		t.Fatalf("expected a validation error, got %v", v.Err())
+	}
+	want := []Violation{
+		{Field: "input.name", Message: "name is required"},
+		{Field: "input.price", Message: "price must be at least 0"},
+		{Field: "input.weightKg", Message: "weightKg must be at least 0"},
+		{Field: "input.items.1.quantity", Message: "quantity must be at least 1"},
+	}
+	if !reflect.DeepEqual(verr.Violations, want) {
+		t.Errorf("violations = %+v, want %+v", verr.Violations, want)
+	}
+}
+
+func TestValidatorWithoutViolations(t *testing.T) {
+	v := New("input")
+	Field(v, "email", "jane@example.com", Required(), Email())
+	Field(v, "phone", "+254712345678", Phone())
+	Field(v, "productID", "42", ID())
+	if err := v.Err(); err != nil {
+		t.Errorf("expected no violations, got %v", err)
+	}
+}
+
+func TestRules(t *testing.T) {
+	cases := []struct {
+		rule  Rule[string]
+		value string
+		ok    bool
+	}{
+		{Required(), "  ", false},
+		{MinLength(3), "ab", false},
+		{MaxLength(3), "abcd", false},
+		{MaxLength(3), "äöü", true},
+		{Email(), "jane@example.com", true},
+		{Email(), "Jane <jane@example.com>", false},
+		{Email(), "jane@localhost", false},
+		{Email(), "not an email", false},
+		{Phone(), "+254712345678", true},
+		{Phone(), "0712345678", false},
+		{ID(), "7", true},
+		{ID(), "0", false},
+		{ID(), "abc", false},
+	}
+	for _, tc := range cases {
+		if got := tc.rule(tc.value) == ""; got != tc.ok {
+			t.Errorf("rule on %q passed = %v, want %v", tc.value, got, tc.ok)
+		}
+	}
+}
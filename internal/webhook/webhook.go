@@ -0,0 +1,186 @@
+// Package webhook sends queued webhook deliveries to partner endpoints.
+// Deliveries are queued by the repo in the same transaction as the change they
+// describe; a Dispatcher claims the due ones, posts them signed with the
+// subscription's secret and retries failures with exponential backoff.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+)
+
+// headers sent with every delivery
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// MaxAttempts is how many times a delivery is tried before it is marked failed
+const MaxAttempts = 8
+
+// releaseTimeout bounds recording attempts and releasing claims once the
+// dispatcher is shutting down
+const releaseTimeout = 5 * time.Second
+
+// backoff limits, the wait doubles after every failed attempt
+const (
+	firstRetry = 30 * time.Second
+	maxRetry   = 6 * time.Hour
+)
+
+// Sign returns the signature header value for body sent at t:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">". Signing
+// the timestamp lets receivers reject old deliveries replayed by someone else.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff is how long to wait before the next try after the given number of
+// failed attempts
+func Backoff(attempts int) time.Duration {
+	wait := firstRetry
+	for i := 1; i < attempts && wait < maxRetry; i++ {
+		wait *= 2
+	}
+	return min(wait, maxRetry)
+}
+
+// Store is where a Dispatcher finds deliveries and records how they went
+type Store interface {
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]repo.DueDelivery, error)
+	RecordAttempt(ctx context.Context, id int, statusCode *int, attemptErr error, retryAt *time.Time) error
+	ReleaseClaims(ctx context.Context, ids []int) error
+}
+
+// Dispatcher sends due deliveries until its context is done. Several may run
+// against the same database, each delivery is claimed by one at a time.
+type Dispatcher struct {
+	Store  Store
+	Client *http.Client
+	// PollInterval is how often to look for due deliveries
+	PollInterval time.Duration
+	// BatchSize is how many deliveries are claimed at once
+	BatchSize int
+}
+
+func NewDispatcher(store Store) *Dispatcher {
+	return &Dispatcher{
+		Store:        store,
+		Client:       &http.Client{Timeout: 10 * time.Second},
+		PollInterval: 5 * time.Second,
+		BatchSize:    20,
+	}
+}
+
+// Run polls for due deliveries until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+	for {
+		// keep going while full batches come back, there may be more waiting
+		for ctx.Err() == nil {
+			n, err := d.RunOnce(ctx)
+			if err != nil {
+				log.Printf("webhook: %v", err)
+			}
+			if err != nil || n < d.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends one batch of due deliveries and reports how many it claimed.
+// When ctx is done partway, the request in flight is cut off and the
+// deliveries not yet attempted are released rather than left waiting out
+// their lease, so a batch never holds up shutting down.
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	// a claim outlives the longest a whole batch can take to send
+	lease := time.Duration(d.BatchSize)*d.Client.Timeout + time.Minute
+	due, err := d.Store.ClaimDue(ctx, d.BatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, delivery := range due {
+		if ctx.Err() != nil {
+			return len(due), d.release(ctx, due[i:])
+		}
+		statusCode, sendErr := d.send(ctx, delivery)
+		if sendErr != nil && ctx.Err() != nil {
+			// cut off by shutting down, the endpoint isn't to blame
+			return len(due), d.release(ctx, due[i:])
+		}
+
+		var retryAt *time.Time
+		if sendErr != nil && delivery.Attempts+1 < MaxAttempts {
+			next := time.Now().Add(Backoff(delivery.Attempts + 1))
+			retryAt = &next
+		}
+		recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+		err := d.Store.RecordAttempt(recordCtx, delivery.ID, statusCode, sendErr, retryAt)
+		cancel()
+		if err != nil {
+			return len(due), err
+		}
+	}
+	return len(due), nil
+}
+
+// release hands back claimed deliveries that weren't attempted
+func (d *Dispatcher) release(ctx context.Context, unsent []repo.DueDelivery) error {
+	ids := make([]int, len(unsent))
+	for i, delivery := range unsent {
+		ids[i] = delivery.ID
+	}
+	releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+	defer cancel()
+	return d.Store.ReleaseClaims(releaseCtx, ids)
+}
+
+// send posts the delivery once. Any 2xx response counts as delivered.
+func (d *Dispatcher) send(ctx context.Context, delivery repo.DueDelivery) (*int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "simple-ecomm-webhooks/1")
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, time.Now(), delivery.Payload))
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(delivery.ID))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &resp.StatusCode, fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+	return &resp.StatusCode, nil
+}
@@ -0,0 +1,165 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+)
+
+type attempt struct {
+	id         int
+	statusCode *int
+	err        error
+	retryAt    *time.Time
+}
+
+type fakeStore struct {
+	due      []repo.DueDelivery
+	attempts []attempt
+	released []int
+}
+
+func (s *fakeStore) ClaimDue(context.Context, int, time.Duration) ([]repo.DueDelivery, error) {
+	due := s.due
+	s.due = nil
+	return due, nil
+}
+
+func (s *fakeStore) RecordAttempt(_ context.Context, id int, statusCode *int, err error, retryAt *time.Time) error {
+	s.attempts = append(s.attempts, attempt{id, statusCode, err, retryAt})
+	return nil
+}
+
+func (s *fakeStore) ReleaseClaims(_ context.Context, ids []int) error {
+	s.released = append(s.released, ids...)
+	return nil
+}
+
+func delivery(id, attempts int, url string) repo.DueDelivery {
+	return repo.DueDelivery{
+		WebhookDelivery: models.WebhookDelivery{
+			ID:        id,
+			EventType: models.WebhookOrderCreated,
+			Payload:   []byte(`{"type":"order.created"}`),
+			Attempts:  attempts,
+		},
+		URL:    url,
+		Secret: "s3cret",
+	}
+}
+
+func TestSign(t *testing.T) {
+	got := Sign("s3cret", time.Unix(1700000000, 0), []byte(`{"a":1}`))
+	want := "t=1700000000,v1=1698a50bc74d1ff1db85c4e0a5297c2ad9fdba245d5737cdb789e4cc6e098940"
+	if got != want {
+		t.Errorf("Sign() = %q, want %q", got, want)
+	}
+	if Sign("s3cret", time.Unix(1700000000, 0), []byte(`{"a":2}`)) == got {
+		t.Error("expected a different body to change the signature")
+	}
+	if Sign("other", time.Unix(1700000000, 0), []byte(`{"a":1}`)) == got {
+		t.Error("expected a different secret to change the signature")
+	}
+}
+
+func TestBackoffDoublesUpToTheCap(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		12: 6 * time.Hour,
+	}
+	for attempts, want := range cases {
+		if got := Backoff(attempts); got != want {
+			t.Errorf("Backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestDispatcherSignsAndRecordsDeliveries(t *testing.T) {
+	var gotSignature, gotEvent, gotBody string
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get(SignatureHeader)
+		gotEvent = r.Header.Get(EventHeader)
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+	}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	store := &fakeStore{due: []repo.DueDelivery{
+		delivery(1, 0, ok.URL),
+		delivery(2, 0, failing.URL),
+		delivery(3, MaxAttempts-1, failing.URL),
+	}}
+	d := NewDispatcher(store)
+
+	n, err := d.RunOnce(context.Background())
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 deliveries to be sent, got %d (%v)", n, err)
+	}
+
+	if gotEvent != models.WebhookOrderCreated || gotBody != `{"type":"order.created"}` {
+		t.Errorf("unexpected request: event %q body %q", gotEvent, gotBody)
+	}
+	if gotSignature == "" {
+		t.Error("expected the delivery to be signed")
+	}
+
+	if len(store.attempts) != 3 {
+		t.Fatalf("expected 3 recorded attempts, got %d", len(store.attempts))
+	}
+	if a := store.attempts[0]; a.err != nil || a.statusCode == nil || *a.statusCode != http.StatusOK {
+		t.Errorf("expected the first delivery to succeed, got %+v", a)
+	}
+	if a := store.attempts[1]; a.err == nil || a.retryAt == nil || *a.statusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected the second delivery to be retried, got %+v", a)
+	}
+	if a := store.attempts[2]; a.err == nil || a.retryAt != nil {
+		t.Errorf("expected the last attempt to give up, got %+v", a)
+	}
+}
+
+func TestDispatcherReleasesClaimsWhenShuttingDown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the endpoint hangs, shutting down starts while it does
+	done := make(chan struct{})
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		<-done
+	}))
+	defer hanging.Close()
+	defer close(done)
+
+	store := &fakeStore{due: []repo.DueDelivery{
+		delivery(1, 0, hanging.URL),
+		delivery(2, 0, hanging.URL),
+	}}
+	d := NewDispatcher(store)
+
+	start := time.Now()
+	n, err := d.RunOnce(ctx)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 claimed deliveries, got %d (%v)", n, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the batch to stop when shutting down, it took %s", elapsed)
+	}
+	if len(store.attempts) != 0 {
+		t.Errorf("expected no attempts recorded for cut off deliveries, got %+v", store.attempts)
+	}
+	if len(store.released) != 2 || store.released[0] != 1 || store.released[1] != 2 {
+		t.Errorf("expected both claims to be released, got %v", store.released)
+	}
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/extension"
@@ -13,24 +17,38 @@ import (
 	"github.com/godfreyowidi/simple-ecomm-demo/db"
 	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/graph"
 	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/resolvers"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/config"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/health"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/invoice"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/pubsub"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/querylimit"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/ratelimit"
 	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/server"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/webhook"
+	"github.com/godfreyowidi/simple-ecomm-demo/migrations"
 	"github.com/godfreyowidi/simple-ecomm-demo/pkg"
-	"github.com/joho/godotenv"
+	"github.com/godfreyowidi/simple-ecomm-demo/pkg/storage"
+	"github.com/gorilla/websocket"
 	"github.com/vektah/gqlparser/v2/ast"
 )
 
 func main() {
-	// Load env
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using system environment variables")
+	// Load and check every setting before starting anything
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
 	}
+	log.Printf("Starting version %s with the %s profile", health.Build().Version, cfg.Env)
 
 	// Init DB
-	database, err := db.NewPostgresDB()
+	database, err := db.NewPostgresDB(context.Background(), cfg.DatabaseURL)
 	if err != nil {
 		log.Fatalf("failed to connect to database: %v", err)
 	}
-	defer database.Close()
+
+	// Background work stops with the server once it has drained its requests
+	app := server.New("0.0.0.0:"+cfg.Port, cfg.Server)
 
 	// Initialize repositories
 	productRepo := repo.NewProductRepo(database.Pool)
@@ -39,47 +57,209 @@ func main() {
 	orderItemRepo := repo.NewOrderItemRepo(database.Pool)
 	createCategoryRepo := repo.NewCategoryRepo(database.Pool)
 	catalogRepo := repo.NewCatalogRepo(database.Pool)
+	promotionRepo := repo.NewPromotionRepo(database.Pool)
+	variantRepo := repo.NewVariantRepo(database.Pool)
+	mediaRepo := repo.NewMediaRepo(database.Pool)
+	addressRepo := repo.NewAddressRepo(database.Pool)
+	shippingRepo := repo.NewShippingRepo(database.Pool)
+	shipmentRepo := repo.NewShipmentRepo(database.Pool)
+	returnRepo := repo.NewReturnRepo(database.Pool)
+	refundRepo := repo.NewRefundRepo(database.Pool)
+	idempotencyRepo := repo.NewIdempotencyRepo(database.Pool)
+	invoiceRepo := repo.NewInvoiceRepo(database.Pool)
+	reviewRepo := repo.NewReviewRepo(database.Pool)
+	wishlistRepo := repo.NewWishlistRepo(database.Pool)
+	webhookRepo := repo.NewWebhookRepo(database.Pool)
+
+	// Init media storage (local directory or S3-compatible bucket)
+	mediaStorage, err := storage.New(context.Background(), cfg.Storage)
+	if err != nil {
+		log.Fatalf("failed to initialize media storage: %v", err)
+	}
+
+	// Tokens are checked against the Auth0 tenant's signing keys
+	authenticator, err := pkg.NewAuthenticator(context.Background(), cfg.Auth0)
+	if err != nil {
+		log.Fatalf("failed to initialize authentication: %v", err)
+	}
+	auth0Client := pkg.NewAuth0Client(cfg.Auth0)
+
+	// Customers are texted about their orders when an SMS account is set
+	var smsService *pkg.SMSService
+	if cfg.SMS.Enabled() {
+		smsService, err = pkg.NewSMSService(cfg.SMS)
+		if err != nil {
+			log.Fatalf("failed to initialize SMS service: %v", err)
+		}
+	} else {
+		log.Println("AT_API_KEY is not set, SMS notifications are off")
+	}
+
+	// Order updates reach subscribers on every replica through Postgres LISTEN/NOTIFY
+	events := pubsub.NewPostgres(app.Context(), database.Pool, pubsub.DefaultChannel)
+
+	// Send queued webhook deliveries, every replica helps and each delivery is claimed once
+	app.Go(webhook.NewDispatcher(webhookRepo).Run)
 
 	// Initialize RegisterHandler
 	registerHandler := &pkg.RegisterHandler{
 		CustomerRepo: customerRepo,
+		Auth0:        auth0Client,
 	}
 
 	// Construct the resolver with all dependencies
 	resolver := &resolvers.Resolver{
-		ProductRepo:     productRepo,
-		CustomerRepo:    customerRepo,
-		OrderRepo:       orderRepo,
-		OrderItemRepo:   orderItemRepo,
-		CategoryRepo:    createCategoryRepo,
-		RegisterHandler: registerHandler,
-		CatalogRepo:     catalogRepo,
+		ProductRepo:      productRepo,
+		CustomerRepo:     customerRepo,
+		OrderRepo:        orderRepo,
+		OrderItemRepo:    orderItemRepo,
+		CategoryRepo:     createCategoryRepo,
+		RegisterHandler:  registerHandler,
+		Auth0:            auth0Client,
+		CatalogRepo:      catalogRepo,
+		PromotionRepo:    promotionRepo,
+		VariantRepo:      variantRepo,
+		MediaRepo:        mediaRepo,
+		AddressRepo:      addressRepo,
+		ShippingRepo:     shippingRepo,
+		ShipmentRepo:     shipmentRepo,
+		ReturnRepo:       returnRepo,
+		RefundRepo:       refundRepo,
+		IdempotencyRepo:  idempotencyRepo,
+		ReviewRepo:       reviewRepo,
+		WishlistRepo:     wishlistRepo,
+		WebhookRepo:      webhookRepo,
+		Storage:          mediaStorage,
+		SMS:              smsService,
+		Events:           events,
+		ReturnWindowDays: cfg.ReturnWindowDays,
+	}
+
+	mediaUploadHandler := &pkg.MediaUploadHandler{
+		MediaRepo:   mediaRepo,
+		ProductRepo: productRepo,
+		Storage:     mediaStorage,
+	}
+	invoiceHandler := &pkg.InvoiceHandler{
+		OrderRepo:     orderRepo,
+		OrderItemRepo: orderItemRepo,
+		CustomerRepo:  customerRepo,
+		ProductRepo:   productRepo,
+		VariantRepo:   variantRepo,
+		InvoiceRepo:   invoiceRepo,
+		Storage:       mediaStorage,
+		Seller: invoice.Seller{
+			Name:    cfg.Invoice.SellerName,
+			Address: cfg.Invoice.SellerAddress,
+			TaxID:   cfg.Invoice.SellerTaxID,
+		},
+		TaxRate:  cfg.Invoice.TaxRate,
+		Currency: cfg.Invoice.Currency,
+	}
+	productImportHandler := &pkg.ProductImportHandler{
+		ProductRepo:  productRepo,
+		WishlistRepo: wishlistRepo,
+		SMS:          smsService,
+		MaxBytes:     cfg.Server.MaxImportBytes,
+	}
+	productExportHandler := &pkg.ProductExportHandler{ProductRepo: productRepo}
+
+	// Queries registered in the persisted query manifest run by hash, and in
+	// strict mode they are the only queries that run
+	var persistedQueries map[string]string
+	if path := cfg.GraphQL.PersistedQueriesManifest; path != "" {
+		persistedQueries, err = querylimit.LoadManifest(path)
+		if err != nil {
+			log.Fatalf("failed to load persisted queries: %v", err)
+		}
+	}
+	strictPersistedQueries := cfg.GraphQL.PersistedQueriesStrict
+
+	// With the postgres store rate limits hold across every replica
+	var rateLimitStore ratelimit.Store = ratelimit.NewMemory()
+	if cfg.RateLimit.Store == "postgres" {
+		rateLimitStore = ratelimit.NewPostgres(app.Context(), database.Pool)
 	}
 
 	// GraphQL server setup
-	srv := handler.New(graph.NewExecutableSchema(graph.Config{Resolvers: resolver}))
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{
+		Resolvers:  resolver,
+		Complexity: resolvers.Complexity(),
+	}))
 
+	// websocket goes first, upgrade requests are GETs too
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		InitFunc:              authenticator.WebsocketInit(),
+		Upgrader: websocket.Upgrader{
+			// connections authenticate with a token, not cookies, so any origin may connect
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	})
 	srv.AddTransport(transport.Options{})
 	srv.AddTransport(transport.GET{})
 	srv.AddTransport(transport.POST{})
 
 	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	srv.SetErrorPresenter(resolvers.ErrorPresenter)
 
-	srv.Use(extension.Introspection{})
-	srv.Use(extension.AutomaticPersistedQuery{
-		Cache: lru.New[string](100),
+	// the schema isn't published in production
+	if cfg.GraphQL.Introspection {
+		srv.Use(extension.Introspection{})
+	}
+	if persistedQueries != nil {
+		srv.Use(querylimit.PersistedQueries{Queries: persistedQueries, Strict: strictPersistedQueries})
+	}
+	// automatic persisted queries would let clients register any query
+	if !strictPersistedQueries {
+		srv.Use(extension.AutomaticPersistedQuery{
+			Cache: lru.New[string](100),
+		})
+	}
+	srv.Use(extension.FixedComplexityLimit(cfg.GraphQL.MaxComplexity))
+	srv.Use(querylimit.DepthLimit{Max: cfg.GraphQL.MaxDepth})
+	srv.Use(pkg.RateLimit{Store: rateLimitStore, Default: cfg.RateLimit.Default, Fields: cfg.RateLimit.Fields})
+
+	// Kubernetes only sends traffic while the database, its schema and the
+	// Auth0 signing keys are usable, and stops once shutting down starts
+	checker := health.New()
+	checker.Add("database", database.Pool.Ping)
+	checker.Add("migrations", func(ctx context.Context) error {
+		return migrations.CheckVersion(ctx, database.Pool)
 	})
+	checker.Add("auth0", authenticator.CheckKeys)
+	app.OnShutdown(checker.ShutDown)
 
+	// uploads and imports check their own, larger, size limits: the upload
+	// handler's MaxUploadBytes and SERVER_MAX_IMPORT_BYTES
+	graphqlHandler := http.MaxBytesHandler(srv, cfg.Server.MaxBodyBytes)
+
+	trustProxy := cfg.RateLimit.TrustProxy
 	mux := http.NewServeMux()
-	mux.Handle("/public-query", srv)
-	mux.Handle("/query", pkg.AuthMiddleware(srv))
+	mux.HandleFunc("GET /healthz", checker.Live)
+	mux.HandleFunc("GET /readyz", checker.Ready)
+	mux.HandleFunc("GET /version", health.VersionHandler)
+	mux.Handle("/public-query", pkg.RateLimitMiddleware(trustProxy, pkg.IdempotencyMiddleware(graphqlHandler)))
+	mux.Handle("/query", pkg.RateLimitMiddleware(trustProxy, authenticator.AuthMiddleware(pkg.IdempotencyMiddleware(graphqlHandler))))
+	mux.Handle("POST /products/{id}/media", authenticator.AuthMiddleware(mediaUploadHandler))
+	mux.Handle("POST /admin/products/import", authenticator.AuthMiddleware(productImportHandler))
+	mux.Handle("GET /admin/products/export", authenticator.AuthMiddleware(productExportHandler))
+	mux.Handle("GET /orders/{id}/invoice", authenticator.AuthMiddleware(invoiceHandler))
+	if local, ok := mediaStorage.(*storage.Local); ok {
+		// invoices share the storage but are only served through /orders/{id}/invoice
+		mux.Handle("GET /media/", local.Handler("/media/", pkg.InvoiceKeyPrefix))
+	}
 	mux.Handle("/", playground.Handler("GraphQL Playground", "/public-query"))
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+	// SIGTERM is how Kubernetes asks a pod to stop during a rollout
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	log.Printf("🚀 Server running at http://0.0.0.0:%s/", port)
-	log.Fatal(http.ListenAndServe("0.0.0.0:"+port, mux))
+	log.Printf("🚀 Server running at http://0.0.0.0:%s/", cfg.Port)
+	err = app.Run(ctx, mux)
+	database.Close()
+	if err != nil {
+		log.Fatalf("server stopped: %v", err)
+	}
+	log.Println("Server stopped")
 }
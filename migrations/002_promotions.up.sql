@@ -0,0 +1,46 @@
+-- Create promotions (coupons have a code, automatic discounts don't)
+CREATE TABLE promotions (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    code TEXT UNIQUE,
+    kind TEXT NOT NULL CHECK (kind IN ('percentage', 'fixed_amount', 'buy_x_get_y')),
+    value NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (value >= 0),
+    category_id INTEGER REFERENCES categories(id) ON DELETE CASCADE,
+    buy_quantity INTEGER CHECK (buy_quantity > 0),
+    get_quantity INTEGER CHECK (get_quantity > 0),
+    min_subtotal NUMERIC(10, 2),
+    usage_limit INTEGER CHECK (usage_limit > 0),
+    usage_limit_per_customer INTEGER CHECK (usage_limit_per_customer > 0),
+    starts_at TIMESTAMP,
+    ends_at TIMESTAMP,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create order_discounts (one line per promotion applied to an order)
+CREATE TABLE order_discounts (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    promotion_id INTEGER REFERENCES promotions(id) ON DELETE SET NULL,
+    code TEXT,
+    description TEXT NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL CHECK (amount >= 0)
+);
+
+CREATE INDEX order_discounts_promotion_id_idx ON order_discounts (promotion_id);
+
+-- Order totals
+ALTER TABLE orders
+    ADD COLUMN subtotal NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN discount_total NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN total NUMERIC(10, 2) NOT NULL DEFAULT 0;
+
+-- Backfill totals for existing orders
+UPDATE orders AS o
+SET subtotal = s.amount, total = s.amount
+FROM (
+    SELECT order_id, SUM(quantity * price) AS amount
+    FROM order_items
+    GROUP BY order_id
+) AS s
+WHERE s.order_id = o.id;
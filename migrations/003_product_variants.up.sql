@@ -0,0 +1,31 @@
+-- Create option_types (e.g. size, colour)
+CREATE TABLE option_types (
+    id SERIAL PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL
+);
+
+INSERT INTO option_types (name) VALUES ('size'), ('colour');
+
+-- Create product_variants (a sellable SKU of a product)
+CREATE TABLE product_variants (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku TEXT UNIQUE NOT NULL,
+    price NUMERIC(10, 2) CHECK (price >= 0),
+    stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX product_variants_product_id_idx ON product_variants (product_id);
+
+-- Create variant_option_values (the size/colour/... of each variant)
+CREATE TABLE variant_option_values (
+    variant_id INTEGER NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+    option_type_id INTEGER NOT NULL REFERENCES option_types(id) ON DELETE RESTRICT,
+    value TEXT NOT NULL,
+    PRIMARY KEY (variant_id, option_type_id)
+);
+
+-- Order items of products with variants point at the variant that was sold
+ALTER TABLE order_items
+    ADD COLUMN variant_id INTEGER REFERENCES product_variants(id) ON DELETE SET NULL;
@@ -0,0 +1,20 @@
+-- Create product_media (images of a product with their resized renditions)
+CREATE TABLE product_media (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    original_key TEXT NOT NULL,
+    medium_key TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    alt_text TEXT,
+    position INTEGER NOT NULL DEFAULT 0,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX product_media_product_id_idx ON product_media (product_id, position);
+
+-- At most one primary image per product
+CREATE UNIQUE INDEX product_media_primary_idx ON product_media (product_id) WHERE is_primary;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS categories_parent_id_name_idx;
+
+ALTER TABLE products DROP COLUMN IF EXISTS sku;
@@ -0,0 +1,5 @@
+-- Products get a SKU so bulk imports can upsert them
+ALTER TABLE products ADD COLUMN sku TEXT UNIQUE;
+
+-- Category paths are resolved one level at a time
+CREATE INDEX categories_parent_id_name_idx ON categories (parent_id, name);
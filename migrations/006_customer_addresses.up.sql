@@ -0,0 +1,27 @@
+-- Create customer_addresses (a customer's address book)
+CREATE TABLE customer_addresses (
+    id SERIAL PRIMARY KEY,
+    customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    label TEXT,
+    full_name TEXT NOT NULL,
+    line1 TEXT NOT NULL,
+    line2 TEXT,
+    city TEXT NOT NULL,
+    region TEXT,
+    postal_code TEXT,
+    country TEXT NOT NULL,
+    phone TEXT,
+    is_default_shipping BOOLEAN NOT NULL DEFAULT FALSE,
+    is_default_billing BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX customer_addresses_customer_id_idx ON customer_addresses (customer_id);
+
+-- At most one default shipping and one default billing address per customer
+CREATE UNIQUE INDEX customer_addresses_default_shipping_idx ON customer_addresses (customer_id) WHERE is_default_shipping;
+CREATE UNIQUE INDEX customer_addresses_default_billing_idx ON customer_addresses (customer_id) WHERE is_default_billing;
+
+-- Orders keep a copy of the address they ship to, so editing or deleting
+-- an address book entry doesn't rewrite past orders
+ALTER TABLE orders ADD COLUMN shipping_address JSONB;
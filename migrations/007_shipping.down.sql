@@ -0,0 +1,16 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS shipping_cost,
+    DROP COLUMN IF EXISTS shipping_method_name,
+    DROP COLUMN IF EXISTS shipping_method_id;
+
+DROP TABLE IF EXISTS shipping_rates;
+
+DROP TABLE IF EXISTS shipping_zones;
+
+DROP TABLE IF EXISTS shipping_methods;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS height_cm,
+    DROP COLUMN IF EXISTS width_cm,
+    DROP COLUMN IF EXISTS length_cm,
+    DROP COLUMN IF EXISTS weight_kg;
@@ -0,0 +1,69 @@
+-- Product weight and dimensions, used to price delivery
+ALTER TABLE products
+    ADD COLUMN weight_kg NUMERIC(10, 3) CHECK (weight_kg >= 0),
+    ADD COLUMN length_cm NUMERIC(10, 2) CHECK (length_cm >= 0),
+    ADD COLUMN width_cm NUMERIC(10, 2) CHECK (width_cm >= 0),
+    ADD COLUMN height_cm NUMERIC(10, 2) CHECK (height_cm >= 0);
+
+-- Create shipping_methods (how an order gets to the customer)
+CREATE TABLE shipping_methods (
+    id SERIAL PRIMARY KEY,
+    code TEXT UNIQUE NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create shipping_zones (where an address is). A zone covers its countries,
+-- narrowed to the listed regions/cities when regions is not empty.
+CREATE TABLE shipping_zones (
+    id SERIAL PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL,
+    countries TEXT[] NOT NULL,
+    regions TEXT[] NOT NULL DEFAULT '{}'
+);
+
+-- Create shipping_rates (the price of a method in a zone for a weight bracket)
+CREATE TABLE shipping_rates (
+    id SERIAL PRIMARY KEY,
+    method_id INTEGER NOT NULL REFERENCES shipping_methods(id) ON DELETE CASCADE,
+    zone_id INTEGER NOT NULL REFERENCES shipping_zones(id) ON DELETE CASCADE,
+    min_weight_kg NUMERIC(10, 3) NOT NULL DEFAULT 0 CHECK (min_weight_kg >= 0),
+    max_weight_kg NUMERIC(10, 3) CHECK (max_weight_kg > min_weight_kg),
+    price NUMERIC(10, 2) NOT NULL CHECK (price >= 0)
+);
+
+CREATE INDEX shipping_rates_method_zone_idx ON shipping_rates (method_id, zone_id);
+
+-- Orders record the chosen method and what it cost, the name is kept in case
+-- the method is renamed or removed later
+ALTER TABLE orders
+    ADD COLUMN shipping_method_id INTEGER REFERENCES shipping_methods(id) ON DELETE SET NULL,
+    ADD COLUMN shipping_method_name TEXT,
+    ADD COLUMN shipping_cost NUMERIC(10, 2) NOT NULL DEFAULT 0;
+
+-- Default methods, zones and rates
+INSERT INTO shipping_methods (code, name, description) VALUES
+    ('pickup_station', 'Pickup station', 'Collect from a pickup station near you'),
+    ('nairobi_same_day', 'Nairobi same-day', 'Delivered the same day within Nairobi'),
+    ('countrywide_courier', 'Countrywide courier', 'Delivered to your door anywhere in Kenya');
+
+INSERT INTO shipping_zones (name, countries, regions) VALUES
+    ('Nairobi', '{KE}', '{nairobi}'),
+    ('Kenya', '{KE}', '{}');
+
+INSERT INTO shipping_rates (method_id, zone_id, min_weight_kg, max_weight_kg, price)
+SELECT m.id, z.id, r.min_weight_kg, r.max_weight_kg, r.price
+FROM (VALUES
+    ('pickup_station', 'Kenya', 0, NULL, 150),
+    ('nairobi_same_day', 'Nairobi', 0, 5, 350),
+    ('nairobi_same_day', 'Nairobi', 5, 20, 600),
+    ('countrywide_courier', 'Nairobi', 0, 5, 250),
+    ('countrywide_courier', 'Nairobi', 5, NULL, 450),
+    ('countrywide_courier', 'Kenya', 0, 5, 450),
+    ('countrywide_courier', 'Kenya', 5, 20, 800),
+    ('countrywide_courier', 'Kenya', 20, NULL, 1500)
+) AS r (method_code, zone_name, min_weight_kg, max_weight_kg, price)
+JOIN shipping_methods m ON m.code = r.method_code
+JOIN shipping_zones z ON z.name = r.zone_name;
@@ -0,0 +1,21 @@
+-- Create shipments (a parcel sent out for an order, orders may ship in several)
+CREATE TABLE shipments (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    carrier TEXT NOT NULL,
+    tracking_number TEXT,
+    shipped_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP
+);
+
+CREATE INDEX shipments_order_id_idx ON shipments (order_id);
+
+-- Create shipment_items (how many of each order item went in a shipment)
+CREATE TABLE shipment_items (
+    shipment_id INTEGER NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+    order_item_id INTEGER NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (shipment_id, order_item_id)
+);
+
+CREATE INDEX shipment_items_order_item_id_idx ON shipment_items (order_item_id);
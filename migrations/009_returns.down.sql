@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS refunds;
+
+DROP TABLE IF EXISTS return_status_history;
+
+DROP TABLE IF EXISTS return_items;
+
+DROP TABLE IF EXISTS returns;
@@ -0,0 +1,15 @@
+-- Create order_status_history (every status an order has been through)
+CREATE TABLE order_status_history (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    note TEXT,
+    changed_by TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX order_status_history_order_id_idx ON order_status_history (order_id);
+
+-- Start the history of existing orders at their current status
+INSERT INTO order_status_history (order_id, status, created_at)
+SELECT id, COALESCE(status, 'pending'), order_date FROM orders;
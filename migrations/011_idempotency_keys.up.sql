@@ -0,0 +1,13 @@
+-- Create idempotency_keys (the first result of a mutation sent with an
+-- idempotency key, replayed when a client retries with the same key)
+CREATE TABLE idempotency_keys (
+    owner TEXT NOT NULL,
+    operation TEXT NOT NULL,
+    key TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    response JSONB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (owner, operation, key)
+);
+
+CREATE INDEX idempotency_keys_created_at_idx ON idempotency_keys (created_at);
@@ -0,0 +1,42 @@
+-- Add order numbers, the reference customers see instead of the serial id
+ALTER TABLE orders ADD COLUMN order_number TEXT;
+
+-- Give existing orders a number in the same ORD-YYMMDD-XXXXXXXC format the
+-- application generates: seven random Crockford base32 characters and a
+-- Luhn mod 32 check character over the date and the random part
+DO $$
+DECLARE
+    alphabet CONSTANT TEXT := '0123456789ABCDEFGHJKMNPQRSTVWXYZ';
+    o RECORD;
+    payload TEXT;
+    candidate TEXT;
+    factor INTEGER;
+    total INTEGER;
+    addend INTEGER;
+BEGIN
+    FOR o IN SELECT id, order_date FROM orders LOOP
+        LOOP
+            payload := to_char(COALESCE(o.order_date, CURRENT_TIMESTAMP), 'YYMMDD');
+            FOR i IN 1..7 LOOP
+                payload := payload || substr(alphabet, floor(random() * 32)::INTEGER + 1, 1);
+            END LOOP;
+
+            factor := 2;
+            total := 0;
+            FOR i IN REVERSE length(payload)..1 LOOP
+                addend := (strpos(alphabet, substr(payload, i, 1)) - 1) * factor;
+                total := total + addend / 32 + addend % 32;
+                factor := 3 - factor;
+            END LOOP;
+
+            candidate := 'ORD-' || substr(payload, 1, 6) || '-' || substr(payload, 7)
+                || substr(alphabet, (32 - total % 32) % 32 + 1, 1);
+            EXIT WHEN NOT EXISTS (SELECT 1 FROM orders WHERE order_number = candidate);
+        END LOOP;
+
+        UPDATE orders SET order_number = candidate WHERE id = o.id;
+    END LOOP;
+END $$;
+
+ALTER TABLE orders ALTER COLUMN order_number SET NOT NULL;
+ALTER TABLE orders ADD CONSTRAINT orders_order_number_key UNIQUE (order_number);
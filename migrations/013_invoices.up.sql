@@ -0,0 +1,20 @@
+-- Create invoice_counters (the last invoice number handed out each year,
+-- locked while issuing so numbers run without gaps)
+CREATE TABLE invoice_counters (
+    year INTEGER PRIMARY KEY,
+    last_number INTEGER NOT NULL
+);
+
+-- Create invoices (the invoice or receipt issued for an order, stored as PDF)
+CREATE TABLE invoices (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+    number TEXT NOT NULL UNIQUE,
+    tax_rate NUMERIC(5, 4) NOT NULL DEFAULT 0 CHECK (tax_rate >= 0),
+    tax_amount NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    storage_key TEXT NOT NULL,
+    -- what the stored document shows, it is rendered again when these change
+    title TEXT NOT NULL,
+    refunded_total NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    issued_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
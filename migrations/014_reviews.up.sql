@@ -0,0 +1,22 @@
+-- Create reviews (a customer's star rating and comments on a product they
+-- received; only approved reviews are shown and counted)
+CREATE TABLE reviews (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    rating INTEGER NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    body TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'approved', 'rejected')),
+    moderated_by TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (product_id, customer_id)
+);
+
+CREATE INDEX reviews_product_status_idx ON reviews (product_id, status, id);
+
+-- running totals of the approved ratings, kept up to date as reviews are
+-- moderated so listing products never has to aggregate reviews
+ALTER TABLE products
+    ADD COLUMN rating_count INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN rating_total INTEGER NOT NULL DEFAULT 0;
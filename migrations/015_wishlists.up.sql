@@ -0,0 +1,29 @@
+-- Create wishlists (named lists of products a customer wants to keep an eye
+-- on; share_token is set while the list is shared by link)
+CREATE TABLE wishlists (
+    id SERIAL PRIMARY KEY,
+    customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    share_token TEXT UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (customer_id, name)
+);
+
+-- Create wishlist_items (a product, or one variant of it, on a wishlist).
+-- alert_price and alert_in_stock are what the customer was last told about,
+-- so a price drop or restock is only texted once.
+CREATE TABLE wishlist_items (
+    id SERIAL PRIMARY KEY,
+    wishlist_id INTEGER NOT NULL REFERENCES wishlists(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE,
+    notify BOOLEAN NOT NULL DEFAULT FALSE,
+    alert_price NUMERIC(10, 2) NOT NULL,
+    alert_in_stock BOOLEAN NOT NULL,
+    added_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX wishlist_items_unique_idx
+    ON wishlist_items (wishlist_id, product_id, COALESCE(variant_id, 0));
+
+CREATE INDEX wishlist_items_product_id_idx ON wishlist_items (product_id) WHERE notify;
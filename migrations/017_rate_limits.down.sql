@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS rate_limit_buckets_full_at_idx;
+
+DROP TABLE IF EXISTS rate_limit_buckets;
@@ -0,0 +1,73 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Latest is the version of the newest migration built into the binary, the
+// one a database should be at for this build to run against it
+func Latest() (uint, error) {
+	d, err := iofs.New(migrationFiles, ".")
+	if err != nil {
+		return 0, fmt.Errorf("migration source: %w", err)
+	}
+	defer d.Close()
+
+	version, err := d.First()
+	if err != nil {
+		return 0, fmt.Errorf("first migration: %w", err)
+	}
+	for {
+		next, err := d.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("next migration after %d: %w", version, err)
+		}
+		version = next
+	}
+}
+
+// Version is the version the database was last migrated to. Dirty means that
+// migration failed partway and needs fixing by hand.
+func Version(ctx context.Context, pool *pgxpool.Pool) (version uint, dirty bool, err error) {
+	// golang-migrate's default table, also used by the migrate CLI
+	err = pool.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("read migration version: %w", err)
+	}
+	return version, dirty, nil
+}
+
+// CheckVersion reports an error unless the database has been migrated,
+// cleanly, to at least the version this build expects. A database ahead of
+// the build is fine: during a rolling deploy the new release migrates it
+// while the old pods keep serving.
+func CheckVersion(ctx context.Context, pool *pgxpool.Pool) error {
+	want, err := Latest()
+	if err != nil {
+		return err
+	}
+	got, dirty, err := Version(ctx, pool)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("migration %d failed partway and needs fixing", got)
+	}
+	if got < want {
+		return fmt.Errorf("database is at migration %d, want at least %d", got, want)
+	}
+	return nil
+}
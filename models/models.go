@@ -18,33 +18,453 @@ type Customer struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// Address is a postal address
+type Address struct {
+	FullName   string  `json:"full_name"`
+	Line1      string  `json:"line1"`
+	Line2      *string `json:"line2,omitempty"`
+	City       string  `json:"city"`
+	Region     *string `json:"region,omitempty"`
+	PostalCode *string `json:"postal_code,omitempty"`
+	Country    string  `json:"country"`
+	Phone      *string `json:"phone,omitempty"`
+}
+
+// CustomerAddress is an entry in a customer's address book
+type CustomerAddress struct {
+	ID         int     `json:"id"`
+	CustomerID int     `json:"customer_id"`
+	Label      *string `json:"label,omitempty"`
+	Address
+	IsDefaultShipping bool      `json:"is_default_shipping"`
+	IsDefaultBilling  bool      `json:"is_default_billing"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
 type OrderItemInput struct {
 	ProductID int
+	VariantID *int
 	Quantity  int
-	Price     float64
+	// Price is the unit price. PlaceOrder sets it from the catalogue, a
+	// price given by the caller is never charged.
+	Price float64
 }
 
 type OrderItem struct {
 	ID        int     `json:"id"`
 	OrderID   int     `json:"order_id"`
 	ProductID int     `json:"product_id"`
+	VariantID *int    `json:"variant_id,omitempty"`
 	Quantity  int     `json:"quantity"`
 	Price     float64 `json:"price"`
 }
 
+// order statuses, the shipping ones are set as shipments go out and arrive
+const (
+	OrderStatusPending          = "pending"
+	OrderStatusPaid             = "paid"
+	OrderStatusPartiallyShipped = "partially_shipped"
+	OrderStatusShipped          = "shipped"
+	OrderStatusDelivered        = "delivered"
+	OrderStatusCancelled        = "cancelled"
+)
+
 type Order struct {
+	ID            int       `json:"id"`
+	CustomerID    int       `json:"customer_id"`
+	OrderDate     time.Time `json:"order_date"`
+	Status        string    `json:"status"`
+	Subtotal      float64   `json:"subtotal"`
+	DiscountTotal float64   `json:"discount_total"`
+	Total         float64   `json:"total"`
+	// Number is the order number customers see, e.g. ORD-241019-7K3QZ9MX
+	Number string `json:"order_number"`
+	// ShippingMethodName is kept in case the method is renamed or removed later
+	ShippingMethodID   *int    `json:"shipping_method_id,omitempty"`
+	ShippingMethodName *string `json:"shipping_method_name,omitempty"`
+	ShippingCost       float64 `json:"shipping_cost"`
+	// ShippingAddress is a copy of the address taken when the order was placed
+	ShippingAddress *Address `json:"shipping_address,omitempty"`
+}
+
+// OrderFilter narrows down a customer's orders. From is inclusive and To
+// exclusive; AfterID continues a listing after that order, newest first.
+type OrderFilter struct {
+	CustomerID int
+	Status     *string
+	From       *time.Time
+	To         *time.Time
+	AfterID    *int
+	Limit      int
+}
+
+// OrderStatusChange is one step in the life of an order. ChangedBy is the
+// Auth0 subject of whoever made the change.
+type OrderStatusChange struct {
+	Status    string    `json:"status"`
+	Note      *string   `json:"note,omitempty"`
+	ChangedBy *string   `json:"changed_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OrderDraft is everything needed to place an order in one transaction
+type OrderDraft struct {
+	CustomerID      int
+	Items           []OrderItemInput
+	Discounts       []OrderDiscount
+	ShippingAddress *Address
+	Shipping        *ShippingQuote
+}
+
+// Shipment is a parcel sent out for an order. Orders can ship in several parts.
+type Shipment struct {
+	ID             int            `json:"id"`
+	OrderID        int            `json:"order_id"`
+	Carrier        string         `json:"carrier"`
+	TrackingNumber *string        `json:"tracking_number,omitempty"`
+	ShippedAt      time.Time      `json:"shipped_at"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty"`
+	Items          []ShipmentItem `json:"items"`
+}
+
+// ShipmentItem is how many units of an order item went in a shipment
+type ShipmentItem struct {
+	OrderItemID int `json:"order_item_id"`
+	Quantity    int `json:"quantity"`
+}
+
+// return statuses
+const (
+	ReturnRequested = "requested"
+	ReturnApproved  = "approved"
+	ReturnRejected  = "rejected"
+	ReturnReceived  = "received"
+)
+
+// Return is a customer's request to send items of an order back
+type Return struct {
+	ID        int                  `json:"id"`
+	OrderID   int                  `json:"order_id"`
+	Reason    string               `json:"reason"`
+	Status    string               `json:"status"`
+	Items     []ReturnItem         `json:"items"`
+	History   []ReturnStatusChange `json:"history"`
+	CreatedAt time.Time            `json:"created_at"`
+}
+
+// ReturnItem is how many units of an order item are being returned
+type ReturnItem struct {
+	OrderItemID int `json:"order_item_id"`
+	Quantity    int `json:"quantity"`
+}
+
+// ReturnStatusChange is one step in the life of a return. ChangedBy is the
+// Auth0 subject of whoever made the change.
+type ReturnStatusChange struct {
+	Status    string    `json:"status"`
+	Note      *string   `json:"note,omitempty"`
+	ChangedBy *string   `json:"changed_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// refund statuses
+const (
+	RefundPending   = "pending"
+	RefundProcessed = "processed"
+)
+
+// Refund is money owed back to a customer, e.g. for a received return
+type Refund struct {
+	ID          int        `json:"id"`
+	OrderID     int        `json:"order_id"`
+	ReturnID    *int       `json:"return_id,omitempty"`
+	Amount      float64    `json:"amount"`
+	Reason      *string    `json:"reason,omitempty"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ProcessedAt *time.Time `json:"processed_at,omitempty"`
+}
+
+// Invoice is the invoice or receipt issued for an order. Numbers run
+// sequentially per year, separately from order IDs, e.g. INV-2024-000001.
+type Invoice struct {
+	ID         int     `json:"id"`
+	OrderID    int     `json:"order_id"`
+	Number     string  `json:"number"`
+	TaxRate    float64 `json:"tax_rate"`
+	TaxAmount  float64 `json:"tax_amount"`
+	StorageKey string  `json:"storage_key"`
+	// Title and RefundedTotal are what the stored document shows
+	Title         string    `json:"title"`
+	RefundedTotal float64   `json:"refunded_total"`
+	IssuedAt      time.Time `json:"issued_at"`
+}
+
+// review statuses
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// Review is a customer's 1 to 5 star rating of a product they received.
+// CustomerName is how the reviewer is shown, e.g. "Jane D.".
+type Review struct {
+	ID           int       `json:"id"`
+	ProductID    int       `json:"product_id"`
+	CustomerID   int       `json:"customer_id"`
+	CustomerName string    `json:"customer_name"`
+	Rating       int       `json:"rating"`
+	Body         string    `json:"body"`
+	Status       string    `json:"status"`
+	ModeratedBy  *string   `json:"moderated_by,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// DefaultWishlistName is the list products are saved to when the customer
+// doesn't pick one
+const DefaultWishlistName = "Saved for later"
+
+// Wishlist is a named list of products a customer saved. ShareToken is set
+// while the list can be viewed by anyone with the link.
+type Wishlist struct {
+	ID         int            `json:"id"`
+	CustomerID int            `json:"customer_id"`
+	Name       string         `json:"name"`
+	ShareToken *string        `json:"share_token,omitempty"`
+	Items      []WishlistItem `json:"items"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// WishlistItem is a product, or one variant of it, on a wishlist. Notify asks
+// for an SMS when it comes back in stock or gets cheaper.
+type WishlistItem struct {
+	ProductID int       `json:"product_id"`
+	VariantID *int      `json:"variant_id,omitempty"`
+	Notify    bool      `json:"notify"`
+	AddedAt   time.Time `json:"added_at"`
+}
+
+// WishlistAlert is a change to a wishlisted item worth texting its owner about
+type WishlistAlert struct {
+	CustomerName string  `json:"customer_name"`
+	Phone        string  `json:"phone"`
+	ProductName  string  `json:"product_name"`
+	OldPrice     float64 `json:"old_price"`
+	Price        float64 `json:"price"`
+	BackInStock  bool    `json:"back_in_stock"`
+}
+
+// PriceDropped tells whether the item got cheaper since the last alert
+func (a WishlistAlert) PriceDropped() bool {
+	return a.Price < a.OldPrice
+}
+
+// webhook event types
+const (
+	WebhookOrderCreated       = "order.created"
+	WebhookOrderStatusChanged = "order.status_changed"
+	WebhookProductCreated     = "product.created"
+	WebhookProductUpdated     = "product.updated"
+)
+
+// WebhookEventTypes lists every event a webhook can subscribe to
+var WebhookEventTypes = []string{
+	WebhookOrderCreated,
+	WebhookOrderStatusChanged,
+	WebhookProductCreated,
+	WebhookProductUpdated,
+}
+
+// WebhookSubscription is a partner endpoint told about the listed events.
+// Deliveries are signed with Secret.
+type WebhookSubscription struct {
 	ID         int       `json:"id"`
-	CustomerID int       `json:"customer_id"`
-	OrderDate  time.Time `json:"order_date"`
-	Status     string    `json:"status"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
-type Product struct {
+// webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event sent, or still to be sent, to one subscription
+type WebhookDelivery struct {
+	ID             int        `json:"id"`
+	SubscriptionID int        `json:"subscription_id"`
+	EventType      string     `json:"event_type"`
+	Payload        []byte     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastStatusCode *int       `json:"last_status_code,omitempty"`
+	LastError      *string    `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// OrderDiscount is a discount line persisted with an order
+type OrderDiscount struct {
 	ID          int     `json:"id"`
-	Name        string  `json:"name"`
-	Description *string `json:"description,omitempty"`
-	Price       float64 `json:"price"`
-	CategoryID  *int    `json:"category_id,omitempty"`
+	OrderID     int     `json:"order_id"`
+	PromotionID *int    `json:"promotion_id,omitempty"`
+	Code        *string `json:"code,omitempty"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+}
+
+// promotion kinds
+const (
+	PromotionPercentage  = "percentage"
+	PromotionFixedAmount = "fixed_amount"
+	PromotionBuyXGetY    = "buy_x_get_y"
+)
+
+// Promotion is a coupon (when Code is set) or an automatic discount
+type Promotion struct {
+	ID                    int        `json:"id"`
+	Name                  string     `json:"name"`
+	Code                  *string    `json:"code,omitempty"`
+	Kind                  string     `json:"kind"`
+	Value                 float64    `json:"value"`
+	CategoryID            *int       `json:"category_id,omitempty"`
+	BuyQuantity           *int       `json:"buy_quantity,omitempty"`
+	GetQuantity           *int       `json:"get_quantity,omitempty"`
+	MinSubtotal           *float64   `json:"min_subtotal,omitempty"`
+	UsageLimit            *int       `json:"usage_limit,omitempty"`
+	UsageLimitPerCustomer *int       `json:"usage_limit_per_customer,omitempty"`
+	StartsAt              *time.Time `json:"starts_at,omitempty"`
+	EndsAt                *time.Time `json:"ends_at,omitempty"`
+	Active                bool       `json:"active"`
+	CreatedAt             time.Time  `json:"created_at"`
+}
+
+type Product struct {
+	ID          int      `json:"id"`
+	SKU         *string  `json:"sku,omitempty"`
+	Name        string   `json:"name"`
+	Description *string  `json:"description,omitempty"`
+	Price       float64  `json:"price"`
+	CategoryID  *int     `json:"category_id,omitempty"`
+	WeightKg    *float64 `json:"weight_kg,omitempty"`
+	LengthCm    *float64 `json:"length_cm,omitempty"`
+	WidthCm     *float64 `json:"width_cm,omitempty"`
+	HeightCm    *float64 `json:"height_cm,omitempty"`
+	// totals of the approved review ratings
+	RatingCount int `json:"rating_count"`
+	RatingTotal int `json:"rating_total"`
+}
+
+// AverageRating is the mean approved star rating, nil before the first one
+func (p Product) AverageRating() *float64 {
+	if p.RatingCount == 0 {
+		return nil
+	}
+	avg := float64(p.RatingTotal) / float64(p.RatingCount)
+	return &avg
+}
+
+// OptionType is a dimension products vary in, like size or colour
+type OptionType struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// VariantOption is the value a variant has for one option type
+type VariantOption struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ProductVariant is a sellable SKU of a product. Price overrides the product
+// price when set and UnitPrice is whichever of the two applies.
+type ProductVariant struct {
+	ID        int             `json:"id"`
+	ProductID int             `json:"product_id"`
+	SKU       string          `json:"sku"`
+	Price     *float64        `json:"price,omitempty"`
+	UnitPrice float64         `json:"unit_price"`
+	Stock     int             `json:"stock"`
+	Options   []VariantOption `json:"options"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// ProductMedia is an uploaded product image. The keys point into the media
+// storage backend, one per rendition.
+type ProductMedia struct {
+	ID           int       `json:"id"`
+	ProductID    int       `json:"product_id"`
+	OriginalKey  string    `json:"original_key"`
+	MediumKey    string    `json:"medium_key"`
+	ThumbnailKey string    `json:"thumbnail_key"`
+	ContentType  string    `json:"content_type"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	AltText      *string   `json:"alt_text,omitempty"`
+	Position     int       `json:"position"`
+	IsPrimary    bool      `json:"is_primary"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ProductRecord is a product as it appears in bulk import and export files.
+// CategoryPath names the category from the top of the tree, e.g. "Electronics/Phones".
+type ProductRecord struct {
+	// ID matches rows to products that have no SKU, it is ignored when the
+	// row has a SKU
+	ID           int      `json:"id,omitempty"`
+	SKU          string   `json:"sku"`
+	Name         string   `json:"name"`
+	Description  *string  `json:"description,omitempty"`
+	Price        float64  `json:"price"`
+	CategoryPath string   `json:"category,omitempty"`
+	WeightKg     *float64 `json:"weight_kg,omitempty"`
+	LengthCm     *float64 `json:"length_cm,omitempty"`
+	WidthCm      *float64 `json:"width_cm,omitempty"`
+	HeightCm     *float64 `json:"height_cm,omitempty"`
+}
+
+// ShippingMethod is a way of delivering an order, e.g. a pickup station or a courier
+type ShippingMethod struct {
+	ID          int       `json:"id"`
+	Code        string    `json:"code"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description,omitempty"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ShippingZone is an area shipping is priced for. It covers its countries,
+// narrowed to the listed regions or cities when Regions is not empty.
+type ShippingZone struct {
+	ID        int      `json:"id"`
+	Name      string   `json:"name"`
+	Countries []string `json:"countries"`
+	Regions   []string `json:"regions"`
+}
+
+// ShippingRate is the price of a method in a zone for parcels weighing at
+// least MinWeightKg and less than MaxWeightKg (no upper bound when nil)
+type ShippingRate struct {
+	ID          int      `json:"id"`
+	MethodID    int      `json:"method_id"`
+	ZoneID      int      `json:"zone_id"`
+	MinWeightKg float64  `json:"min_weight_kg"`
+	MaxWeightKg *float64 `json:"max_weight_kg,omitempty"`
+	Price       float64  `json:"price"`
+}
+
+// ShippingQuote is what delivering a parcel with a method costs
+type ShippingQuote struct {
+	Method   ShippingMethod `json:"method"`
+	Zone     ShippingZone   `json:"zone"`
+	WeightKg float64        `json:"weight_kg"`
+	Cost     float64        `json:"cost"`
 }
 
 type ProductCatalog struct {
@@ -56,3 +476,13 @@ type ProductSubCategory struct {
 	Name     string
 	Products []Product
 }
+
+// IdempotencyKey identifies one client request to a mutation. Owner is whoever
+// sent it, so clients can't collide on each other's keys, and RequestHash is a
+// digest of the payload so a key can't be reused for a different request.
+type IdempotencyKey struct {
+	Owner       string
+	Operation   string
+	Key         string
+	RequestHash string
+}
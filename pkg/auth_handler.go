@@ -17,15 +17,16 @@ type RegisterInput struct {
 
 type RegisterHandler struct {
 	CustomerRepo *repo.CustomerRepo
+	Auth0        *Auth0Client
 }
 
 func (h *RegisterHandler) Register(ctx context.Context, input RegisterInput) (*models.Customer, error) {
-	token, err := GetManagementToken(ctx)
+	token, err := h.Auth0.GetManagementToken(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := CreateUser(ctx, token, CreateUserPayload{
+	userID, err := h.Auth0.CreateUser(ctx, token, CreateUserPayload{
 		Email:      input.Email,
 		Password:   input.Password,
 		Connection: "Username-Password-Authentication",
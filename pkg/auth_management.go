@@ -7,19 +7,31 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
+	"strings"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/config"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
 )
 
+// Auth0Client signs users up and in with the Auth0 tenant
+type Auth0Client struct {
+	Config config.Auth0
+}
+
+func NewAuth0Client(cfg config.Auth0) *Auth0Client {
+	return &Auth0Client{Config: cfg}
+}
+
 type Auth0TokenResponse struct {
 	AccessToken string `json:"access_token"`
 	TokenType   string `json:"token_type"`
 }
 
-func GetManagementToken(ctx context.Context) (string, error) {
-	domain := os.Getenv("AUTH0_DOMAIN")
-	clientID := os.Getenv("AUTH0_CLIENT_ID")
-	clientSecret := os.Getenv("AUTH0_CLIENT_SECRET")
-	audience := os.Getenv("AUTH0_MANAGEMENT_API_AUDIENCE")
+func (c *Auth0Client) GetManagementToken(ctx context.Context) (string, error) {
+	domain := c.Config.Domain
+	clientID := c.Config.ClientID
+	clientSecret := c.Config.ClientSecret
+	audience := c.Config.ManagementAudience
 
 	fmt.Println("[Auth0] Requesting management token with:")
 	fmt.Println("  Domain   :", domain)
@@ -27,7 +39,7 @@ func GetManagementToken(ctx context.Context) (string, error) {
 	fmt.Println("  Audience :", audience)
 
 	if domain == "" || clientID == "" || clientSecret == "" || audience == "" {
-		return "", errors.New("missing Auth0 configuration environment variables")
+		return "", errors.New("missing Auth0 management application configuration")
 	}
 
 	data := map[string]string{
@@ -82,8 +94,8 @@ type Auth0UserResponse struct {
 	UserID string `json:"user_id"`
 }
 
-func CreateUser(ctx context.Context, token string, payload CreateUserPayload) (string, error) {
-	domain := os.Getenv("AUTH0_DOMAIN")
+func (c *Auth0Client) CreateUser(ctx context.Context, token string, payload CreateUserPayload) (string, error) {
+	domain := c.Config.Domain
 	url := fmt.Sprintf("https://%s/api/v2/users", domain)
 
 	fmt.Println("[Auth0] Creating user at:", url)
@@ -108,6 +120,15 @@ func CreateUser(ctx context.Context, token string, payload CreateUserPayload) (s
 		var msg map[string]any
 		_ = json.NewDecoder(resp.Body).Decode(&msg)
 		fmt.Printf("[Auth0] User creation failed (%d): %+v\n", resp.StatusCode, msg)
+		switch resp.StatusCode {
+		case http.StatusConflict:
+			return "", repo.Conflict("an account with this email already exists")
+		case http.StatusBadRequest:
+			// Auth0 answers 400 for passwords that break its policy
+			if m, ok := msg["message"].(string); ok && strings.Contains(strings.ToLower(m), "password") {
+				return "", repo.Validation("input.password", "password is too weak")
+			}
+		}
 		return "", fmt.Errorf("auth0 user creation failed: %v", msg)
 	}
 
@@ -0,0 +1,125 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/bulk"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+)
+
+// DefaultMaxImportBytes caps the size of a product import file
+const DefaultMaxImportBytes = 50 << 20
+
+// ProductImportHandler takes a CSV or JSON Lines file of products on
+// POST /admin/products/import and upserts them by SKU. The format comes from
+// the "format" query parameter or the Content-Type header, and "dry_run=true"
+// validates the file without saving anything. It must sit behind
+// AuthMiddleware and only lets staff through.
+type ProductImportHandler struct {
+	ProductRepo *repo.ProductRepo
+	// WishlistRepo, when set, is used to text customers about price drops
+	WishlistRepo *repo.WishlistRepo
+	SMS          *SMSService
+	// MaxBytes caps the import file, DefaultMaxImportBytes when unset
+	MaxBytes int64
+}
+
+func (h *ProductImportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, err := RequireStaff(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	format, err := requestFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	maxBytes := h.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxImportBytes
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+	report, err := bulk.Import(r.Context(), h.ProductRepo, r.Body, format, dryRun)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("import file exceeds %d bytes", maxBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		log.Printf("product import failed: %v", err)
+		http.Error(w, fmt.Sprintf("import failed: %v", err), http.StatusBadRequest)
+		return
+	}
+	if report.Committed && report.Updated > 0 && h.WishlistRepo != nil {
+		SendWishlistAlerts(r.Context(), h.WishlistRepo, h.SMS, nil)
+	}
+
+	status := http.StatusOK
+	if report.Failed > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// ProductExportHandler streams every product on GET /admin/products/export in
+// the format named by the "format" query parameter (csv by default), in the
+// same shape the import accepts. It must sit behind AuthMiddleware and only
+// lets staff through.
+type ProductExportHandler struct {
+	ProductRepo *repo.ProductRepo
+}
+
+func (h *ProductExportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, err := RequireStaff(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	format := bulk.FormatCSV
+	if name := r.URL.Query().Get("format"); name != "" {
+		var err error
+		if format, err = bulk.ParseFormat(name); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	contentType := "text/csv"
+	if format == bulk.FormatJSONL {
+		contentType = "application/x-ndjson"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="products-%s.%s"`,
+		time.Now().UTC().Format("20060102"), format))
+
+	// headers are already sent once rows start streaming, so failures can only be logged
+	if err := bulk.Export(r.Context(), h.ProductRepo, w, format); err != nil {
+		log.Printf("product export failed: %v", err)
+	}
+}
+
+// requestFormat picks the import format from the query string, falling back to the Content-Type
+func requestFormat(r *http.Request) (string, error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		return bulk.ParseFormat(name)
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil {
+		if format, err := bulk.ParseFormat(mediaType); err == nil {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf(`set format=csv or format=jsonl, or send a text/csv or application/x-ndjson body`)
+}
@@ -0,0 +1,28 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// IdempotencyKeyHeader lets clients retry a mutation without running it twice
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const idempotencyKeyContextKey contextKey = "idempotency_key"
+
+// IdempotencyMiddleware passes the request's Idempotency-Key header on to the resolvers
+func IdempotencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := strings.TrimSpace(r.Header.Get(IdempotencyKeyHeader)); key != "" {
+			r = r.WithContext(context.WithValue(r.Context(), idempotencyKeyContextKey, key))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IdempotencyKeyFromContext returns the Idempotency-Key header of the request, if any
+func IdempotencyKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKeyContextKey).(string)
+	return key, ok
+}
@@ -0,0 +1,190 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/invoice"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/godfreyowidi/simple-ecomm-demo/pkg/storage"
+)
+
+// InvoiceKeyPrefix is where invoices are kept in storage. Media servers must
+// not expose it, invoices are only downloaded through InvoiceHandler.
+const InvoiceKeyPrefix = "invoices/"
+
+// InvoicePath is where an order's invoice is downloaded from
+func InvoicePath(orderID int) string {
+	return fmt.Sprintf("/orders/%d/invoice", orderID)
+}
+
+// InvoiceHandler serves the PDF invoice of an order on GET /orders/{id}/invoice.
+// The first download issues the invoice and takes the next invoice number.
+// Later downloads return the stored document, rendered again under the same
+// number when it is out of date: it is titled a receipt once the order has
+// been paid and lists the refunds made. It must sit behind AuthMiddleware and
+// lets staff and the customer who placed the order through.
+type InvoiceHandler struct {
+	OrderRepo     *repo.OrderRepo
+	OrderItemRepo *repo.OrderItemRepo
+	CustomerRepo  *repo.CustomerRepo
+	ProductRepo   *repo.ProductRepo
+	VariantRepo   *repo.VariantRepo
+	InvoiceRepo   *repo.InvoiceRepo
+	Storage       storage.Storage
+	Seller        invoice.Seller
+	// TaxRate is the tax included in prices, e.g. 0.16 for 16% VAT
+	TaxRate  float64
+	Currency string
+}
+
+func (h *InvoiceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	orderID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid order ID", http.StatusBadRequest)
+		return
+	}
+	order, err := h.OrderRepo.GetOrder(ctx, orderID)
+	if err != nil {
+		http.Error(w, "order not found", http.StatusNotFound)
+		return
+	}
+	if err := h.authorize(ctx, order.CustomerID); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if order.Status == models.OrderStatusCancelled {
+		http.Error(w, "cancelled orders have no invoice", http.StatusConflict)
+		return
+	}
+
+	issued, err := h.InvoiceRepo.IssueInvoice(ctx, orderID, h.TaxRate, func(inv models.Invoice, o models.Order) (string, error) {
+		return h.store(ctx, inv, o)
+	})
+	if err != nil {
+		log.Printf("failed to issue invoice for order %d: %v", orderID, err)
+		http.Error(w, "failed to issue invoice", http.StatusInternalServerError)
+		return
+	}
+
+	doc, err := h.Storage.Get(ctx, issued.StorageKey)
+	if err != nil {
+		log.Printf("failed to load invoice %s: %v", issued.Number, err)
+		http.Error(w, "failed to load invoice", http.StatusInternalServerError)
+		return
+	}
+	defer doc.Close()
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s.pdf"`, issued.Number))
+	if _, err := io.Copy(w, doc); err != nil {
+		log.Printf("failed to send invoice %s: %v", issued.Number, err)
+	}
+}
+
+// authorize lets staff through and otherwise checks that the caller placed the order
+func (h *InvoiceHandler) authorize(ctx context.Context, customerID int) error {
+	user, ok := UserFromContext(ctx)
+	if !ok {
+		return errors.New("unauthorized: missing or invalid token")
+	}
+	if user.IsStaff() {
+		return nil
+	}
+
+	callerID, err := h.CustomerRepo.FindCustomerIDByAuth0Sub(ctx, user.Sub)
+	if err != nil || callerID != customerID {
+		return errors.New("forbidden: order belongs to another customer")
+	}
+	return nil
+}
+
+// store renders the invoice and saves it under InvoiceKeyPrefix, replacing
+// the invoice's document when it has one. The random part keeps keys
+// unguessable on backends whose bucket may be public.
+func (h *InvoiceHandler) store(ctx context.Context, inv models.Invoice, order models.Order) (string, error) {
+	data, err := h.invoiceData(ctx, inv, order)
+	if err != nil {
+		return "", err
+	}
+	doc := invoice.Render(*data)
+
+	key := inv.StorageKey
+	if key == "" {
+		token := make([]byte, 16)
+		if _, err := rand.Read(token); err != nil {
+			return "", err
+		}
+		key = fmt.Sprintf("%s%d/%s/%s.pdf", InvoiceKeyPrefix, inv.IssuedAt.Year(), hex.EncodeToString(token), inv.Number)
+	}
+	if err := h.Storage.Put(ctx, key, bytes.NewReader(doc), int64(len(doc)), "application/pdf"); err != nil {
+		return "", fmt.Errorf("store invoice: %w", err)
+	}
+	return key, nil
+}
+
+// invoiceData gathers the customer and the order's lines, naming each line
+// after its product and variant SKU
+func (h *InvoiceHandler) invoiceData(ctx context.Context, inv models.Invoice, order models.Order) (*invoice.Data, error) {
+	customer, err := h.CustomerRepo.GetCustomerById(ctx, order.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := h.OrderItemRepo.GetItemsByOrder(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var variantIDs []int
+	for _, item := range items {
+		if item.VariantID != nil {
+			variantIDs = append(variantIDs, *item.VariantID)
+		}
+	}
+	skus := map[int]string{}
+	if len(variantIDs) > 0 {
+		variants, err := h.VariantRepo.GetVariantsByIDs(ctx, variantIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range variants {
+			skus[v.ID] = v.SKU
+		}
+	}
+
+	names := map[int]string{}
+	data := &invoice.Data{
+		Seller:   h.Seller,
+		Invoice:  inv,
+		Order:    order,
+		Customer: *customer,
+		Currency: h.Currency,
+	}
+	for _, item := range items {
+		name, ok := names[item.ProductID]
+		if !ok {
+			product, err := h.ProductRepo.GetProduct(ctx, item.ProductID)
+			if err != nil {
+				return nil, err
+			}
+			name = product.Name
+			names[item.ProductID] = name
+		}
+		if item.VariantID != nil && skus[*item.VariantID] != "" {
+			name += " (" + skus[*item.VariantID] + ")"
+		}
+		data.Lines = append(data.Lines, invoice.Line{Description: name, Quantity: item.Quantity, UnitPrice: item.Price})
+	}
+	return data, nil
+}
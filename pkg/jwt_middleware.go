@@ -2,36 +2,118 @@ package pkg
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
-	"os"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/coreos/go-oidc"
-)
-
-var (
-	auth0Domain   = os.Getenv("AUTH0_DOMAIN")
-	auth0Audience = os.Getenv("AUTH0_AUDIENCE")
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/config"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
 )
 
 type AuthClaims struct {
-	Email string `json:"email"`
-	Sub   string `json:"sub"`
+	Email       string   `json:"email"`
+	Sub         string   `json:"sub"`
+	Permissions []string `json:"permissions"`
 }
 
+// StaffPermission is the Auth0 RBAC permission granted to shop staff
+const StaffPermission = "manage:store"
+
 type contextKey string
 
 const userContextKey contextKey = "user"
 
-func AuthMiddleware(next http.Handler) http.Handler {
-	ctx := context.Background()
-	provider, err := oidc.NewProvider(ctx, "https://"+auth0Domain+"/")
+// Authenticator checks access tokens issued by the Auth0 tenant
+type Authenticator struct {
+	verifier *oidc.IDTokenVerifier
+	jwksURL  string
+
+	mu          sync.Mutex
+	keysFetched time.Time
+	keysChecked time.Time
+}
+
+// keysCheckInterval is how often CheckKeys asks the tenant again once it has
+// answered
+const keysCheckInterval = 5 * time.Minute
+
+// NewAuthenticator looks up the tenant's signing keys, so it fails when the
+// tenant can't be reached
+func NewAuthenticator(ctx context.Context, cfg config.Auth0) (*Authenticator, error) {
+	provider, err := oidc.NewProvider(ctx, "https://"+cfg.Domain+"/")
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("discover auth0 tenant %s: %w", cfg.Domain, err)
+	}
+	var discovery struct {
+		JWKSURL string `json:"jwks_uri"`
+	}
+	if err := provider.Claims(&discovery); err != nil {
+		return nil, fmt.Errorf("read auth0 tenant %s discovery: %w", cfg.Domain, err)
+	}
+	return &Authenticator{
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.Audience}),
+		jwksURL:  discovery.JWKSURL,
+	}, nil
+}
+
+// CheckKeys reports whether tokens can be checked: the tenant's signing keys
+// are reachable, or were fetched before and so are cached. Once the keys have
+// been fetched the tenant is asked again at most every keysCheckInterval.
+func (a *Authenticator) CheckKeys(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if !a.keysFetched.IsZero() && now.Sub(a.keysChecked) < keysCheckInterval {
+		return nil
+	}
+	a.keysChecked = now
+	if err := fetchKeys(ctx, a.jwksURL); err != nil {
+		if !a.keysFetched.IsZero() {
+			log.Printf("auth0 signing keys unreachable, using keys fetched %s: %v", a.keysFetched.Format(time.RFC3339), err)
+			return nil
+		}
+		return err
 	}
+	a.keysFetched = now
+	return nil
+}
 
-	verifier := provider.Verifier(&oidc.Config{ClientID: auth0Audience})
+// fetchKeys downloads the JSON web key set and checks it has keys in it
+func fetchKeys(ctx context.Context, jwksURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURL, nil)
+	if err != nil {
+		return fmt.Errorf("fetch signing keys: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch signing keys: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch signing keys: %s responded %s", jwksURL, resp.Status)
+	}
+	var keySet struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&keySet); err != nil {
+		return fmt.Errorf("decode signing keys: %w", err)
+	}
+	if len(keySet.Keys) == 0 {
+		return fmt.Errorf("%s has no signing keys", jwksURL)
+	}
+	return nil
+}
 
+// AuthMiddleware lets through only requests with a valid access token
+func (a *Authenticator) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
@@ -39,30 +121,54 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-
-		// Verify the token
-		idToken, err := verifier.Verify(r.Context(), tokenString)
+		claims, err := verifyToken(r.Context(), a.verifier, authHeader)
 		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
-
-		// Extract custom claims or pass token down
-		var claims struct {
-			Email string `json:"email"`
-			Sub   string `json:"sub"`
-		}
-		if err := idToken.Claims(&claims); err != nil {
-			http.Error(w, "Failed to parse claims", http.StatusUnauthorized)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
 		// Add claims to context
-		ctx := context.WithValue(r.Context(), contextKey("user"), claims)
+		ctx := ContextWithUser(r.Context(), *claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// WebsocketInit authenticates GraphQL websocket connections. Browsers can't
+// set headers on websocket requests, so the access token comes in the
+// connection_init payload as {"Authorization": "Bearer <token>"}. Connections
+// without one stay anonymous, like requests to /public-query.
+func (a *Authenticator) WebsocketInit() transport.WebsocketInitFunc {
+	return func(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		authHeader := payload.Authorization()
+		if authHeader == "" {
+			return ctx, &payload, nil
+		}
+
+		claims, err := verifyToken(ctx, a.verifier, authHeader)
+		if err != nil {
+			return ctx, nil, err
+		}
+		return ContextWithUser(ctx, *claims), &payload, nil
+	}
+}
+
+// verifyToken checks a "Bearer <token>" value and returns its claims
+func verifyToken(ctx context.Context, verifier *oidc.IDTokenVerifier, authHeader string) (*AuthClaims, error) {
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+
+	// Verify the token
+	idToken, err := verifier.Verify(ctx, tokenString)
+	if err != nil {
+		return nil, errors.New("Invalid token")
+	}
+
+	// Extract custom claims or pass token down
+	var claims AuthClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, errors.New("Failed to parse claims")
+	}
+	return &claims, nil
+}
+
 // UserFromContext extracts AuthClaims from the context
 func UserFromContext(ctx context.Context) (*AuthClaims, bool) {
 	claims, ok := ctx.Value(userContextKey).(AuthClaims)
@@ -71,3 +177,31 @@ func UserFromContext(ctx context.Context) (*AuthClaims, bool) {
 	}
 	return &claims, true
 }
+
+// ContextWithUser returns a copy of ctx carrying the given claims, as
+// AuthMiddleware does for a verified token
+func ContextWithUser(ctx context.Context, claims AuthClaims) context.Context {
+	return context.WithValue(ctx, userContextKey, claims)
+}
+
+// IsStaff reports whether the token carries the staff permission
+func (c *AuthClaims) IsStaff() bool {
+	for _, p := range c.Permissions {
+		if p == StaffPermission {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireStaff returns the caller's claims or an error when the caller is not staff
+func RequireStaff(ctx context.Context) (*AuthClaims, error) {
+	user, ok := UserFromContext(ctx)
+	if !ok {
+		return nil, repo.Unauthorized("unauthorized: missing or invalid token")
+	}
+	if !user.IsStaff() {
+		return nil, repo.Unauthorized("forbidden: staff permission required")
+	}
+	return user, nil
+}
@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 )
 
 type Auth0LoginResponse struct {
@@ -18,11 +17,11 @@ type Auth0LoginResponse struct {
 	Scope        string `json:"scope,omitempty"`
 }
 
-func CustomerLogin(ctx context.Context, email, password string) (*Auth0LoginResponse, error) {
-	domain := os.Getenv("AUTH0_DOMAIN")
-	clientID := os.Getenv("AUTH0_LOGIN_CLIENT_ID")
-	clientSecret := os.Getenv("AUTH0_LOGIN_CLIENT_SECRET")
-	audience := os.Getenv("AUTH0_API_AUDIENCE")
+func (c *Auth0Client) CustomerLogin(ctx context.Context, email, password string) (*Auth0LoginResponse, error) {
+	domain := c.Config.Domain
+	clientID := c.Config.LoginClientID
+	clientSecret := c.Config.LoginClientSecret
+	audience := c.Config.LoginAudience
 	grantType := "password"
 	scope := "openid profile email offline_access" // ✅ include offline_access
 
@@ -58,10 +57,10 @@ func CustomerLogin(ctx context.Context, email, password string) (*Auth0LoginResp
 	return &loginResp, nil
 }
 
-func RefreshCustomerToken(ctx context.Context, refreshToken string) (*Auth0LoginResponse, error) {
-	domain := os.Getenv("AUTH0_DOMAIN")
-	clientID := os.Getenv("AUTH0_LOGIN_CLIENT_ID")
-	clientSecret := os.Getenv("AUTH0_LOGIN_CLIENT_SECRET")
+func (c *Auth0Client) RefreshCustomerToken(ctx context.Context, refreshToken string) (*Auth0LoginResponse, error) {
+	domain := c.Config.Domain
+	clientID := c.Config.LoginClientID
+	clientSecret := c.Config.LoginClientSecret
 
 	refreshData := map[string]string{
 		"grant_type":    "refresh_token",
@@ -0,0 +1,194 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/media"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+	"github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/godfreyowidi/simple-ecomm-demo/pkg/storage"
+)
+
+// DefaultMaxUploadBytes caps the size of a single image upload
+const DefaultMaxUploadBytes = 10 << 20
+
+// MediaUploadHandler accepts multipart image uploads for a product on
+// POST /products/{id}/media. The image goes in the "file" field, "alt" sets
+// its alt text and "primary=true" makes it the product's primary image.
+// It must sit behind AuthMiddleware and only lets staff through.
+type MediaUploadHandler struct {
+	MediaRepo      *repo.MediaRepo
+	ProductRepo    *repo.ProductRepo
+	Storage        storage.Storage
+	MaxUploadBytes int64
+}
+
+type mediaUploadResponse struct {
+	ID           int     `json:"id"`
+	ProductID    int     `json:"productID"`
+	URL          string  `json:"url"`
+	MediumURL    string  `json:"mediumUrl"`
+	ThumbnailURL string  `json:"thumbnailUrl"`
+	AltText      *string `json:"altText,omitempty"`
+	Width        int     `json:"width"`
+	Height       int     `json:"height"`
+	Position     int     `json:"position"`
+	IsPrimary    bool    `json:"isPrimary"`
+}
+
+func (h *MediaUploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if _, err := RequireStaff(ctx); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	productID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid product ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.ProductRepo.GetProduct(ctx, productID); err != nil {
+		http.Error(w, "product not found", http.StatusNotFound)
+		return
+	}
+
+	maxBytes := h.MaxUploadBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxUploadBytes
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes+1<<20) // leave room for the other form fields
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("upload exceeds %d bytes", maxBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, `multipart form with a "file" field is required`, http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		http.Error(w, "failed to read upload", http.StatusBadRequest)
+		return
+	}
+	if int64(len(data)) > maxBytes {
+		http.Error(w, fmt.Sprintf("upload exceeds %d bytes", maxBytes), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	processed, err := media.Process(data)
+	if errors.Is(err, media.ErrTooManyPixels) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	record, err := h.store(r, productID, processed)
+	if err != nil {
+		log.Printf("media upload for product %d failed: %v", productID, err)
+		http.Error(w, "failed to store image", http.StatusInternalServerError)
+		return
+	}
+
+	if r.FormValue("primary") == "true" && !record.IsPrimary {
+		if err := h.MediaRepo.SetPrimaryMedia(ctx, record.ID); err != nil {
+			log.Printf("failed to make image %d primary: %v", record.ID, err)
+		} else {
+			record.IsPrimary = true
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(mediaUploadResponse{
+		ID:           record.ID,
+		ProductID:    record.ProductID,
+		URL:          h.Storage.URL(record.OriginalKey),
+		MediumURL:    h.Storage.URL(record.MediumKey),
+		ThumbnailURL: h.Storage.URL(record.ThumbnailKey),
+		AltText:      record.AltText,
+		Width:        record.Width,
+		Height:       record.Height,
+		Position:     record.Position,
+		IsPrimary:    record.IsPrimary,
+	})
+}
+
+// store uploads the original and its renditions and records them. Uploaded
+// objects are removed again if anything fails along the way.
+func (h *MediaUploadHandler) store(r *http.Request, productID int, processed *media.Processed) (*models.ProductMedia, error) {
+	ctx := r.Context()
+
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	prefix := fmt.Sprintf("products/%d/%s/", productID, hex.EncodeToString(token))
+
+	var uploaded []string
+	put := func(name string, enc media.Encoded) (string, error) {
+		key := prefix + name + enc.Ext
+		if err := h.Storage.Put(ctx, key, bytes.NewReader(enc.Data), int64(len(enc.Data)), enc.ContentType); err != nil {
+			return "", err
+		}
+		uploaded = append(uploaded, key)
+		return key, nil
+	}
+	cleanup := func() {
+		for _, key := range uploaded {
+			if err := h.Storage.Delete(ctx, key); err != nil {
+				log.Printf("failed to clean up %s: %v", key, err)
+			}
+		}
+	}
+
+	record := &models.ProductMedia{
+		ProductID:   productID,
+		ContentType: processed.Original.ContentType,
+		Width:       processed.Original.Width,
+		Height:      processed.Original.Height,
+	}
+	if alt := strings.TrimSpace(r.FormValue("alt")); alt != "" {
+		record.AltText = &alt
+	}
+
+	var err error
+	if record.OriginalKey, err = put("original", processed.Original); err != nil {
+		cleanup()
+		return nil, err
+	}
+	if record.MediumKey, err = put(media.Medium.Name, processed.Renditions[media.Medium.Name]); err != nil {
+		cleanup()
+		return nil, err
+	}
+	if record.ThumbnailKey, err = put(media.Thumbnail.Name, processed.Renditions[media.Thumbnail.Name]); err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	created, err := h.MediaRepo.CreateMedia(ctx, record)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	return created, nil
+}
@@ -0,0 +1,184 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/ratelimit"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// CodeRateLimited is the extensions.code of requests turned away by RateLimit
+const CodeRateLimited = "RATE_LIMITED"
+
+const rateLimitContextKey contextKey = "rate_limit"
+
+// rateLimitState carries the client's address to RateLimit and, when it turns
+// the request away, how long to wait back to the response
+type rateLimitState struct {
+	clientIP   string
+	retryAfter time.Duration
+}
+
+// RateLimitMiddleware prepares requests for the RateLimit extension. Requests
+// it turns away get a 429 response with a Retry-After header. With
+// trustProxy the client's address is taken from the X-Forwarded-For header
+// set by the load balancer in front of the app.
+func RateLimitMiddleware(trustProxy bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := &rateLimitState{clientIP: clientIP(r, trustProxy)}
+		r = r.WithContext(context.WithValue(r.Context(), rateLimitContextKey, state))
+		// websocket upgrades need the original writer to hijack the connection
+		if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			w = &rateLimitWriter{ResponseWriter: w, state: state}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP is the address the request came from. The last X-Forwarded-For
+// entry is the one added by the proxy, earlier ones are up to the client.
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			hops := strings.Split(forwarded, ",")
+			return strings.TrimSpace(hops[len(hops)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+type rateLimitWriter struct {
+	http.ResponseWriter
+	state       *rateLimitState
+	wroteHeader bool
+}
+
+func (w *rateLimitWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if w.state.retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(w.state.retryAfter)))
+		code = http.StatusTooManyRequests
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *rateLimitWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *rateLimitWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// retryAfterSeconds rounds up, so clients don't come back a moment too early
+func retryAfterSeconds(d time.Duration) int {
+	return max(int(math.Ceil(d.Seconds())), 1)
+}
+
+// RateLimit is a gqlgen extension that throttles each client: signed in
+// customers by their token's subject, anyone else by IP address. Every
+// operation takes a token from the client's Default bucket, and each
+// top-level field listed in Fields, such as customerLogin, also takes one
+// from a bucket of its own. Fields are counted rather than operation names,
+// which clients choose freely, and each alias counts separately.
+//
+// When the store fails, requests are let through rather than turned away.
+type RateLimit struct {
+	Store   ratelimit.Store
+	Default ratelimit.Limit
+	Fields  map[string]ratelimit.Limit
+}
+
+var _ interface {
+	graphql.OperationContextMutator
+	graphql.HandlerExtension
+} = RateLimit{}
+
+func (l RateLimit) ExtensionName() string {
+	return "RateLimit"
+}
+
+func (l RateLimit) Validate(schema graphql.ExecutableSchema) error {
+	if l.Store == nil {
+		return fmt.Errorf("RateLimit.Store can not be nil")
+	}
+	return nil
+}
+
+func (l RateLimit) MutateOperationContext(ctx context.Context, opCtx *graphql.OperationContext) *gqlerror.Error {
+	if opCtx.Operation == nil {
+		return nil
+	}
+	client := rateLimitClient(ctx)
+
+	buckets := []rateLimitBucket{{key: "graphql:" + client, limit: l.Default}}
+	for _, field := range graphql.CollectFields(opCtx, opCtx.Operation.SelectionSet, rootTypes(opCtx.Operation)) {
+		if limit, ok := l.Fields[field.Name]; ok {
+			buckets = append(buckets, rateLimitBucket{key: "graphql:" + field.Name + ":" + client, limit: limit})
+		}
+	}
+
+	for _, b := range buckets {
+		ok, retryAfter, err := l.Store.Take(ctx, b.key, b.limit)
+		if err != nil {
+			log.Printf("rate limit: letting %s through: %v", b.key, err)
+			continue
+		}
+		if !ok {
+			if state, found := ctx.Value(rateLimitContextKey).(*rateLimitState); found {
+				state.retryAfter = retryAfter
+			}
+			err := gqlerror.Errorf("too many requests, try again in %d seconds", retryAfterSeconds(retryAfter))
+			errcode.Set(err, CodeRateLimited)
+			err.Extensions["retryAfter"] = retryAfterSeconds(retryAfter)
+			return err
+		}
+	}
+	return nil
+}
+
+type rateLimitBucket struct {
+	key   string
+	limit ratelimit.Limit
+}
+
+// rateLimitClient names who is calling, for bucket keys
+func rateLimitClient(ctx context.Context) string {
+	if user, ok := UserFromContext(ctx); ok && user.Sub != "" {
+		return "sub:" + user.Sub
+	}
+	if state, ok := ctx.Value(rateLimitContextKey).(*rateLimitState); ok {
+		return "ip:" + state.clientIP
+	}
+	return "ip:unknown"
+}
+
+// rootTypes lets CollectFields match fragments on the operation's root type
+func rootTypes(op *ast.OperationDefinition) []string {
+	switch op.Operation {
+	case ast.Mutation:
+		return []string{"Mutation"}
+	case ast.Subscription:
+		return []string{"Subscription"}
+	}
+	return []string{"Query"}
+}
@@ -3,22 +3,19 @@ package pkg
 import (
 	"fmt"
 	"log"
-	"os"
 
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/config"
 	africastalking "github.com/tech-kenya/africastalkingsms"
 )
 
+// SMSService texts customers. A nil *SMSService sends nothing, which is how
+// notifications are turned off when no account is configured.
 type SMSService struct {
 	client *africastalking.SMSClient
 }
 
-func NewSMSService() (*SMSService, error) {
-	apiKey := os.Getenv("AT_API_KEY")
-	username := os.Getenv("AT_USERNAME")
-	shortCode := os.Getenv("AT_SHORTCODE")
-	sandbox := os.Getenv("AT_SANDBOX")
-
-	client, err := africastalking.NewSMSClient(apiKey, username, shortCode, sandbox)
+func NewSMSService(cfg config.SMS) (*SMSService, error) {
+	client, err := africastalking.NewSMSClient(cfg.APIKey, cfg.Username, cfg.ShortCode, cfg.Sandbox)
 	if err != nil {
 		return nil, err
 	}
@@ -26,9 +23,50 @@ func NewSMSService() (*SMSService, error) {
 	return &SMSService{client: client}, nil
 }
 
-func (s *SMSService) SendOrderConfirmationSMS(toPhone, customerName string) error {
-	message := fmt.Sprintf("Hi %s, your order has been received and is being processed. Thank you!", customerName)
+func (s *SMSService) SendOrderConfirmationSMS(toPhone, customerName, orderNumber string) error {
+	message := fmt.Sprintf("Hi %s, your order %s has been received and is being processed. Thank you!", customerName, orderNumber)
+	return s.send(toPhone, message)
+}
+
+// SendShipmentDispatchedSMS tells the customer part or all of their order is on its way
+func (s *SMSService) SendShipmentDispatchedSMS(toPhone, customerName, orderNumber, carrier string, trackingNumber *string) error {
+	message := fmt.Sprintf("Hi %s, items from your order %s have been dispatched with %s.", customerName, orderNumber, carrier)
+	if trackingNumber != nil {
+		message += fmt.Sprintf(" Tracking number: %s.", *trackingNumber)
+	}
+	return s.send(toPhone, message)
+}
+
+// SendOrderCancelledSMS confirms a cancellation, mentioning the refund when one is due
+func (s *SMSService) SendOrderCancelledSMS(toPhone, customerName, orderNumber string, refundAmount *float64) error {
+	message := fmt.Sprintf("Hi %s, your order %s has been cancelled.", customerName, orderNumber)
+	if refundAmount != nil {
+		message += fmt.Sprintf(" A refund of %.2f is on its way.", *refundAmount)
+	}
+	return s.send(toPhone, message)
+}
+
+// SendWishlistAlertSMS tells the customer a product on their wishlist is back
+// in stock, cheaper at newPrice, or both
+func (s *SMSService) SendWishlistAlertSMS(toPhone, customerName, productName string, backInStock bool, newPrice *float64) error {
+	var message string
+	switch {
+	case backInStock && newPrice != nil:
+		message = fmt.Sprintf("Hi %s, %s from your wishlist is back in stock and now costs %.2f.", customerName, productName, *newPrice)
+	case backInStock:
+		message = fmt.Sprintf("Hi %s, %s from your wishlist is back in stock.", customerName, productName)
+	case newPrice != nil:
+		message = fmt.Sprintf("Hi %s, %s from your wishlist has dropped to %.2f.", customerName, productName, *newPrice)
+	default:
+		return nil
+	}
+	return s.send(toPhone, message)
+}
 
+func (s *SMSService) send(toPhone, message string) error {
+	if s == nil {
+		return nil
+	}
 	resp, err := s.client.SendSMS(toPhone, message)
 	if err != nil {
 		return fmt.Errorf("failed to send SMS: %w", err)
@@ -0,0 +1,95 @@
+package storage_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/pkg/storage"
+)
+
+func TestLocalPutGetDelete(t *testing.T) {
+	ctx := context.Background()
+
+	local, err := storage.NewLocal(t.TempDir(), "/media/")
+	if err != nil {
+		t.Fatalf("NewLocal failed: %v", err)
+	}
+
+	key := "products/1/abc/original.jpg"
+	if err := local.Put(ctx, key, strings.NewReader("image bytes"), 11, "image/jpeg"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	rc, err := local.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "image bytes" {
+		t.Errorf("unexpected content %q", data)
+	}
+
+	if url := local.URL(key); url != "/media/products/1/abc/original.jpg" {
+		t.Errorf("unexpected URL %q", url)
+	}
+
+	if err := local.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := local.Get(ctx, key); err != storage.ErrNotFound {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestLocalKeysStayInsideDir(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	local, err := storage.NewLocal(dir+"/media", "/media")
+	if err != nil {
+		t.Fatalf("NewLocal failed: %v", err)
+	}
+
+	if err := local.Put(ctx, "../../escape.txt", strings.NewReader("x"), 1, "text/plain"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if _, err := local.Get(ctx, "escape.txt"); err != nil {
+		t.Errorf("expected traversal key to be stored inside the media dir: %v", err)
+	}
+}
+
+func TestLocalHandlerHidesDirectoriesAndPrivateKeys(t *testing.T) {
+	ctx := context.Background()
+
+	local, err := storage.NewLocal(t.TempDir(), "/media/")
+	if err != nil {
+		t.Fatalf("NewLocal failed: %v", err)
+	}
+	for _, key := range []string{"products/1/abc/original.jpg", "invoices/2026/xyz/INV-1.pdf"} {
+		if err := local.Put(ctx, key, strings.NewReader("data"), 4, "application/octet-stream"); err != nil {
+			t.Fatalf("Put %s failed: %v", key, err)
+		}
+	}
+	handler := local.Handler("/media/", "invoices/")
+
+	cases := map[string]int{
+		"/media/products/1/abc/original.jpg":             http.StatusOK,
+		"/media/products/":                               http.StatusNotFound,
+		"/media/":                                        http.StatusNotFound,
+		"/media/invoices/":                               http.StatusNotFound,
+		"/media/invoices/2026/xyz/INV-1.pdf":             http.StatusNotFound,
+		"/media/products/../invoices/2026/xyz/INV-1.pdf": http.StatusNotFound,
+	}
+	for target, want := range cases {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != want {
+			t.Errorf("GET %s: expected %d, got %d", target, want, rec.Code)
+		}
+	}
+}
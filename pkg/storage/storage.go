@@ -0,0 +1,44 @@
+// Package storage keeps uploaded files (product images, invoices, ...) in a
+// local directory or an S3-compatible bucket behind one interface.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/config"
+)
+
+// ErrNotFound is returned by Get when no object exists under the key
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage stores objects under slash separated keys like "products/12/abc/thumb.jpg"
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// URL is the address clients fetch the object from
+	URL(key string) string
+}
+
+// New builds the backend selected by cfg.Driver, "local" or "s3"
+func New(ctx context.Context, cfg config.Storage) (Storage, error) {
+	switch cfg.Driver {
+	case "local":
+		return NewLocal(cfg.MediaDir, cfg.MediaBaseURL)
+	case "s3":
+		return NewS3(ctx, S3Config{
+			Endpoint:  cfg.S3.Endpoint,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+			Bucket:    cfg.S3.Bucket,
+			Region:    cfg.S3.Region,
+			UseSSL:    cfg.S3.UseSSL,
+			PublicURL: cfg.S3.PublicURL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
@@ -0,0 +1,33 @@
+package pkg
+
+import (
+	"context"
+	"log"
+
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
+)
+
+// SendWishlistAlerts texts customers who asked to be notified about wishlisted
+// items of the given products (all products when productIDs is nil) that are
+// back in stock or cheaper. Failures are only logged, the change that caused
+// the alerts has already been saved.
+func SendWishlistAlerts(ctx context.Context, wishlists *repo.WishlistRepo, sms *SMSService, productIDs []int) {
+	alerts, err := wishlists.TakeAlerts(ctx, productIDs)
+	if err != nil {
+		log.Printf("failed to check wishlist alerts: %v", err)
+		return
+	}
+	if len(alerts) == 0 {
+		return
+	}
+
+	for _, a := range alerts {
+		var newPrice *float64
+		if a.PriceDropped() {
+			newPrice = &a.Price
+		}
+		if err := sms.SendWishlistAlertSMS(a.Phone, a.CustomerName, a.ProductName, a.BackInStock, newPrice); err != nil {
+			log.Printf("failed to send SMS: %v", err)
+		}
+	}
+}
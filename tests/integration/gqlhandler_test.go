@@ -5,6 +5,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
@@ -13,21 +15,38 @@ import (
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/graph"
 	"github.com/godfreyowidi/simple-ecomm-demo/gql-gateway/resolvers"
+	"github.com/godfreyowidi/simple-ecomm-demo/internal/pubsub"
 	"github.com/godfreyowidi/simple-ecomm-demo/internal/repo"
 	"github.com/godfreyowidi/simple-ecomm-demo/migrations"
 	"github.com/godfreyowidi/simple-ecomm-demo/models"
+	"github.com/godfreyowidi/simple-ecomm-demo/pkg"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func newGraphQLServer(pool *pgxpool.Pool) *handler.Server {
 	res := &resolvers.Resolver{
-		ProductRepo:   repo.NewProductRepo(pool),
-		CustomerRepo:  repo.NewCustomerRepo(pool),
-		OrderRepo:     repo.NewOrderRepo(pool),
-		OrderItemRepo: repo.NewOrderItemRepo(pool),
-		CategoryRepo:  repo.NewCategoryRepo(pool),
+		ProductRepo:     repo.NewProductRepo(pool),
+		CustomerRepo:    repo.NewCustomerRepo(pool),
+		OrderRepo:       repo.NewOrderRepo(pool),
+		OrderItemRepo:   repo.NewOrderItemRepo(pool),
+		CategoryRepo:    repo.NewCategoryRepo(pool),
+		PromotionRepo:   repo.NewPromotionRepo(pool),
+		VariantRepo:     repo.NewVariantRepo(pool),
+		MediaRepo:       repo.NewMediaRepo(pool),
+		AddressRepo:     repo.NewAddressRepo(pool),
+		ShippingRepo:    repo.NewShippingRepo(pool),
+		ShipmentRepo:    repo.NewShipmentRepo(pool),
+		ReturnRepo:      repo.NewReturnRepo(pool),
+		RefundRepo:      repo.NewRefundRepo(pool),
+		IdempotencyRepo: repo.NewIdempotencyRepo(pool),
+		ReviewRepo:      repo.NewReviewRepo(pool),
+		WishlistRepo:    repo.NewWishlistRepo(pool),
+		WebhookRepo:     repo.NewWebhookRepo(pool),
+		Events:          pubsub.NewMemory(),
 	}
-	return handler.NewDefaultServer(graph.NewExecutableSchema(graph.Config{Resolvers: res}))
+	srv := handler.NewDefaultServer(graph.NewExecutableSchema(graph.Config{Resolvers: res}))
+	srv.SetErrorPresenter(resolvers.ErrorPresenter)
+	return srv
 }
 
 func setupPostgres(t *testing.T) (*pgxpool.Pool, func()) {
@@ -76,8 +95,9 @@ func TestCreateOrderMutation(t *testing.T) {
 	}
 
 	// Create customer with full details
+	authID := "auth0|" + uniqueEmail()
 	customer := &models.Customer{
-		AuthID:    "auth0|testid",
+		AuthID:    authID,
 		FirstName: "Bob",
 		LastName:  "Smith",
 		Email:     uniqueEmail(),
@@ -88,22 +108,46 @@ func TestCreateOrderMutation(t *testing.T) {
 		t.Fatalf("create customer: %v", err)
 	}
 
+	// Seeded by the shipping migration
+	methods, err := repo.NewShippingRepo(pool).ListMethods(ctx)
+	if err != nil {
+		t.Fatalf("list shipping methods: %v", err)
+	}
+	var pickupID int
+	for _, m := range methods {
+		if m.Code == "pickup_station" {
+			pickupID = m.ID
+		}
+	}
+
 	// Start GraphQL server
+	// orders can only be placed by the customer themselves or by staff
 	srv := newGraphQLServer(pool)
-	ts := httptest.NewServer(srv)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := pkg.ContextWithUser(r.Context(), pkg.AuthClaims{Sub: authID})
+		srv.ServeHTTP(w, r.WithContext(ctx))
+	}))
 	defer ts.Close()
 
 	query := `
-	mutation ($cid: ID!, $pid: ID!) {
+	mutation ($cid: ID!, $pid: ID!, $sid: ID!) {
 		createOrder(input: {
 			customerID: $cid,
-			items: [{ productID: $pid, quantity: 2, price: 699.99 }]
+			items: [{ productID: $pid, quantity: 2 }],
+			shippingAddress: { fullName: "Bob Smith", line1: "1 Moi Avenue", city: "Nairobi", country: "KE" },
+			shippingMethodID: $sid
 		}) {
 			id
 			status
 			items {
 				quantity
 			}
+			shippingAddress {
+				city
+				country
+			}
+			shippingCost
+			total
 		}
 	}`
 
@@ -112,6 +156,7 @@ func TestCreateOrderMutation(t *testing.T) {
 		"variables": map[string]any{
 			"cid": fmt.Sprintf("%d", createdCustomer.ID), // send as string
 			"pid": fmt.Sprintf("%d", prod.ID),
+			"sid": fmt.Sprintf("%d", pickupID),
 		},
 	}
 	b, _ := json.Marshal(reqBody)
@@ -129,6 +174,12 @@ func TestCreateOrderMutation(t *testing.T) {
 				Items  []struct {
 					Quantity int
 				}
+				ShippingAddress *struct {
+					City    string
+					Country string
+				}
+				ShippingCost float64
+				Total        float64
 			}
 		}
 		Errors json.RawMessage
@@ -142,6 +193,12 @@ func TestCreateOrderMutation(t *testing.T) {
 	if out.Data.CreateOrder.Status != "pending" || out.Data.CreateOrder.Items[0].Quantity != 2 {
 		t.Errorf("unexpected result: %+v", out.Data.CreateOrder)
 	}
+	if a := out.Data.CreateOrder.ShippingAddress; a == nil || a.City != "Nairobi" || a.Country != "KE" {
+		t.Errorf("expected the shipping address to be copied onto the order, got %+v", a)
+	}
+	if out.Data.CreateOrder.ShippingCost != 150 || math.Round(out.Data.CreateOrder.Total*100) != 154998 {
+		t.Errorf("expected pickup station shipping in the total, got %+v", out.Data.CreateOrder)
+	}
 }
 
 func TestUploadProductsWithCategoriesForGQL(t *testing.T) {